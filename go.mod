@@ -3,7 +3,10 @@ module github.com/AccursedGalaxy/wallfetch
 go 1.24.3
 
 require (
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/image v0.25.0
+	golang.org/x/sys v0.31.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -0,0 +1,125 @@
+// Package analysis computes what wallfetch derives from a wallpaper's
+// pixels — its palette, brightness and perceptual hashes — and keeps the
+// results stored with the image records.
+package analysis
+
+import (
+	"image"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/imagehash"
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+	"github.com/AccursedGalaxy/wallfetch/internal/palette"
+)
+
+// thumbnailSize is the longest side images are scaled down to before
+// analysis; neither palettes nor hashes need more detail
+const thumbnailSize = 256
+
+// Result is the analysis of one image
+type Result struct {
+	Colors palette.Analysis
+	PHash  imagehash.Hash
+	DHash  imagehash.Hash
+}
+
+// Analyze computes the palette, brightness and hashes of an image
+func Analyze(img image.Image) Result {
+	thumb := imaging.Fit(img, thumbnailSize)
+	return Result{
+		Colors: palette.Analyze(thumb),
+		PHash:  imagehash.PHash(thumb),
+		DHash:  imagehash.DHash(thumb),
+	}
+}
+
+// AnalyzeFile decodes an image file and analyzes it
+func AnalyzeFile(path string) (Result, error) {
+	img, _, err := imaging.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	return Analyze(img), nil
+}
+
+// Apply stores the results on an image record
+func (r Result) Apply(img *database.Image) {
+	img.Palette = palette.Format(r.Colors.Palette)
+	img.Luminance = r.Colors.Luminance
+	img.Brightness = r.Colors.Brightness
+	img.PHash = r.PHash.String()
+	img.DHash = r.DHash.String()
+}
+
+// Needed reports whether an image is missing any analysis results, e.g.
+// because it was added before they were computed
+func Needed(img *database.Image) bool {
+	return img.Palette == "" || img.Brightness == "" || img.PHash == "" || img.DHash == ""
+}
+
+// Backfill analyzes the images that need it, several at a time, and stores
+// the results both in the database and in the images slice. Files that are
+// missing or cannot be decoded are left alone. It returns the number of
+// images analyzed.
+func Backfill(db *database.DB, images []database.Image) (int, error) {
+	missing := missingIndexes(images)
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	results := make([]*Result, len(missing))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if r, err := AnalyzeFile(images[missing[j]].LocalPath); err == nil {
+					results[j] = &r
+				}
+			}
+		}()
+	}
+	for j := range missing {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	analyzed := 0
+	for j, i := range missing {
+		if results[j] == nil {
+			continue
+		}
+		img := &images[i]
+		results[j].Apply(img)
+		if err := db.SetAnalysis(img); err != nil {
+			return analyzed, err
+		}
+		analyzed++
+	}
+	return analyzed, nil
+}
+
+// CountMissing returns how many images Backfill would analyze
+func CountMissing(images []database.Image) int {
+	return len(missingIndexes(images))
+}
+
+// missingIndexes returns the indexes of images that need analysis and
+// whose files exist
+func missingIndexes(images []database.Image) []int {
+	var missing []int
+	for i := range images {
+		if Needed(&images[i]) {
+			if _, err := os.Stat(images[i].LocalPath); err == nil {
+				missing = append(missing, i)
+			}
+		}
+	}
+	return missing
+}
@@ -0,0 +1,132 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/imagehash"
+)
+
+// DefaultThreshold is the largest perceptual hash distance, in bits, at
+// which two images count as near-duplicates
+const DefaultThreshold = 8
+
+// SimilarGroups groups images that are near-duplicates of each other:
+// their perceptual hashes, and their difference hashes where both have
+// one, are within threshold bits. Every member of a group is close to
+// every other member, so whichever copy is kept, the others are
+// near-duplicates of it; a chain of images that each differ a little is
+// not one group. Groups are built greedily in download order. Only groups
+// of two or more are returned, each ordered by download time and the
+// groups by their first image. Images without a hash are skipped.
+func SimilarGroups(images []database.Image, threshold int) [][]database.Image {
+	var tree imagehash.BKTree
+	phashes := make(map[int]imagehash.Hash)
+	dhashes := make(map[int]imagehash.Hash)
+	for i, img := range images {
+		h, err := imagehash.Parse(img.PHash)
+		if err != nil {
+			continue
+		}
+		phashes[i] = h
+		tree.Add(h, i)
+		if d, err := imagehash.Parse(img.DHash); err == nil {
+			dhashes[i] = d
+		}
+	}
+
+	// near reports whether two images are within threshold on both hashes
+	near := func(a, b int) bool {
+		if imagehash.Distance(phashes[a], phashes[b]) > threshold {
+			return false
+		}
+		da, okA := dhashes[a]
+		db, okB := dhashes[b]
+		return !okA || !okB || imagehash.Distance(da, db) <= threshold
+	}
+
+	order := make([]int, 0, len(phashes))
+	for i := range phashes {
+		order = append(order, i)
+	}
+	earlier := func(i, j int) bool {
+		if !images[i].DownloadedAt.Equal(images[j].DownloadedAt) {
+			return images[i].DownloadedAt.Before(images[j].DownloadedAt)
+		}
+		return images[i].ID < images[j].ID
+	}
+	sort.Slice(order, func(a, b int) bool { return earlier(order[a], order[b]) })
+
+	grouped := make(map[int]bool)
+	var members [][]database.Image
+	for _, i := range order {
+		if grouped[i] {
+			continue
+		}
+
+		var candidates []int
+		for _, m := range tree.Search(phashes[i], threshold) {
+			if m.ID != i && !grouped[m.ID] {
+				candidates = append(candidates, m.ID)
+			}
+		}
+		sort.Slice(candidates, func(a, b int) bool { return earlier(candidates[a], candidates[b]) })
+
+		group := []int{i}
+		for _, c := range candidates {
+			closeToAll := true
+			for _, m := range group {
+				if !near(m, c) {
+					closeToAll = false
+					break
+				}
+			}
+			if closeToAll {
+				group = append(group, c)
+			}
+		}
+		if len(group) < 2 {
+			continue
+		}
+
+		var imgs []database.Image
+		for _, m := range group {
+			grouped[m] = true
+			imgs = append(imgs, images[m])
+		}
+		members = append(members, imgs)
+	}
+
+	var groups [][]database.Image
+	for _, group := range members {
+		sort.SliceStable(group, func(i, j int) bool {
+			if !group[i].DownloadedAt.Equal(group[j].DownloadedAt) {
+				return group[i].DownloadedAt.Before(group[j].DownloadedAt)
+			}
+			return group[i].ID < group[j].ID
+		})
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i][0], groups[j][0]
+		if !a.DownloadedAt.Equal(b.DownloadedAt) {
+			return a.DownloadedAt.Before(b.DownloadedAt)
+		}
+		return a.ID < b.ID
+	})
+	return groups
+}
+
+// HashDistance returns the perceptual hash distance between two images, or
+// -1 if either has no hash
+func HashDistance(a, b *database.Image) int {
+	ha, err := imagehash.Parse(a.PHash)
+	if err != nil {
+		return -1
+	}
+	hb, err := imagehash.Parse(b.PHash)
+	if err != nil {
+		return -1
+	}
+	return imagehash.Distance(ha, hb)
+}
@@ -0,0 +1,47 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+)
+
+func hashedImage(id int, phash, dhash string) database.Image {
+	return database.Image{
+		ID:           id,
+		PHash:        phash,
+		DHash:        dhash,
+		DownloadedAt: time.Unix(int64(id), 0),
+	}
+}
+
+func TestSimilarGroupsDoesNotChain(t *testing.T) {
+	// B is 8 bits from A and from C, but C is 16 bits from A
+	images := []database.Image{
+		hashedImage(1, "0000000000000000", ""),
+		hashedImage(2, "00000000000000ff", ""),
+		hashedImage(3, "000000000000ffff", ""),
+	}
+
+	groups := SimilarGroups(images, 8)
+	if len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("got %d groups %v, want one group of two", len(groups), groups)
+	}
+	if groups[0][0].ID != 1 || groups[0][1].ID != 2 {
+		t.Errorf("grouped IDs %d and %d, want 1 and 2", groups[0][0].ID, groups[0][1].ID)
+	}
+}
+
+func TestSimilarGroupsChecksDHash(t *testing.T) {
+	images := []database.Image{
+		hashedImage(1, "0000000000000000", "0000000000000000"),
+		hashedImage(2, "0000000000000001", "ffffffffffffffff"),
+		hashedImage(3, "0000000000000003", "0000000000000001"),
+	}
+
+	groups := SimilarGroups(images, 8)
+	if len(groups) != 1 || len(groups[0]) != 2 || groups[0][1].ID != 3 {
+		t.Fatalf("got groups %v, want IDs 1 and 3 grouped", groups)
+	}
+}
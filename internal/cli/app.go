@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
 	"github.com/spf13/cobra"
 )
 
@@ -32,14 +33,28 @@ with intelligent duplicate detection and local library management.`,
 
 	// Add subcommands
 	app.rootCmd.AddCommand(app.newFetchCmd())
+	app.rootCmd.AddCommand(app.newHistoryCmd())
 	app.rootCmd.AddCommand(app.newListCmd())
 	app.rootCmd.AddCommand(app.newBrowseCmd())
 	app.rootCmd.AddCommand(app.newFavoritesCmd())
+	app.rootCmd.AddCommand(app.newRateCmd())
 	app.rootCmd.AddCommand(app.newImportCmd())
+	app.rootCmd.AddCommand(app.newWatchCmd())
 	app.rootCmd.AddCommand(app.newPruneCmd())
 	app.rootCmd.AddCommand(app.newDedupeCmd())
 	app.rootCmd.AddCommand(app.newDeleteCmd())
 	app.rootCmd.AddCommand(app.newCleanupCmd())
+	app.rootCmd.AddCommand(app.newRelocateCmd())
+	app.rootCmd.AddCommand(app.newVerifyCmd())
+	app.rootCmd.AddCommand(app.newSetCmd())
+	app.rootCmd.AddCommand(app.newMonitorsCmd())
+	app.rootCmd.AddCommand(app.newRotateCmd())
+	app.rootCmd.AddCommand(app.newDaemonCmd())
+	app.rootCmd.AddCommand(app.newControlCmds()...)
+	app.rootCmd.AddCommand(app.newThemeCmd())
+	app.rootCmd.AddCommand(app.newStatsCmd())
+	app.rootCmd.AddCommand(app.newBlockCmd())
+	app.rootCmd.AddCommand(app.newTrashCmd())
 	app.rootCmd.AddCommand(app.newConfigCmd())
 	app.rootCmd.AddCommand(app.newCompletionCmd())
 	app.rootCmd.AddCommand(app.newUpdateCmd())
@@ -47,6 +62,20 @@ with intelligent duplicate detection and local library management.`,
 	return app
 }
 
+// openDatabase opens the wallpaper database with the library roots of the
+// config, so paths under them are stored relative to the roots
+func (a *App) openDatabase() (*database.DB, error) {
+	db, err := database.Open(a.config.Database.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SetRoots(a.config.LibraryRoots()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set library roots: %w", err)
+	}
+	return db, nil
+}
+
 // Run executes the CLI application
 func (a *App) Run(args []string) error {
 	a.rootCmd.SetArgs(args[1:]) // Skip program name
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// newBlockCmd creates the block command
+func (a *App) newBlockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "block",
+		Short: "Manage the blocklist",
+		Long: `Manage wallpapers that should never be downloaded or imported again.
+
+Wallpapers are added to the blocklist with the --block flag of delete, prune
+and dedupe, or by answering 'block' when deleting in interactive browse mode.`,
+	}
+
+	// block ls
+	lsCmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List blocked wallpapers",
+		RunE:    a.runBlockList,
+	}
+
+	// block rm
+	rmCmd := &cobra.Command{
+		Use:     "rm <block-id>...",
+		Aliases: []string{"remove"},
+		Short:   "Remove wallpapers from the blocklist",
+		Args:    cobra.MinimumNArgs(1),
+		RunE:    a.runBlockRemove,
+	}
+
+	cmd.AddCommand(lsCmd)
+	cmd.AddCommand(rmCmd)
+
+	return cmd
+}
+
+// runBlockList handles the block ls command
+func (a *App) runBlockList(cmd *cobra.Command, args []string) error {
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	entries, err := db.ListBlocked()
+	if err != nil {
+		return fmt.Errorf("failed to list blocklist: %w", err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Blocklist is empty.")
+		return nil
+	}
+
+	fmt.Printf("%d blocked wallpapers:\n\n", len(entries))
+	for _, b := range entries {
+		sourceID := b.SourceID
+		if sourceID == "" {
+			sourceID = "-"
+		}
+		fmt.Printf("%-4d | %-10s | %-8s | %-10s | %s | %s\n",
+			b.ID,
+			b.Source,
+			sourceID,
+			b.Reason,
+			b.BlockedAt.Format("2006-01-02 15:04"),
+			b.Checksum[:16]+"...")
+	}
+
+	return nil
+}
+
+// runBlockRemove handles the block rm command
+func (a *App) runBlockRemove(cmd *cobra.Command, args []string) error {
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid blocklist ID: %s", arg)
+		}
+
+		if err := db.UnblockImage(id); err != nil {
+			return fmt.Errorf("failed to remove blocklist entry: %w", err)
+		}
+		fmt.Printf("Removed blocklist entry %d\n", id)
+	}
+
+	return nil
+}
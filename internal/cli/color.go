@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/analysis"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/palette"
+	"github.com/spf13/cobra"
+)
+
+// dominantColors is how many of the most common palette colors are
+// compared in color searches; rarer colors are mostly noise
+const dominantColors = 8
+
+// colorSearch holds the --color and --tolerance flags
+type colorSearch struct {
+	target    color.RGBA
+	tolerance float64
+}
+
+// addColorFlags adds the color search flags shared by list and browse
+func addColorFlags(cmd *cobra.Command) {
+	cmd.Flags().String("color", "", "Rank by similarity to a color (e.g., \"#1e1e2e\")")
+	cmd.Flags().Float64("tolerance", 20, "Maximum color difference (CIEDE2000) for --color")
+}
+
+// colorSearchFromFlags returns the requested color search, or nil without --color
+func colorSearchFromFlags(cmd *cobra.Command) (*colorSearch, error) {
+	hex, _ := cmd.Flags().GetString("color")
+	if hex == "" {
+		return nil, nil
+	}
+	tolerance, _ := cmd.Flags().GetFloat64("tolerance")
+	if tolerance < 0 {
+		return nil, fmt.Errorf("invalid tolerance: %g (must be at least 0)", tolerance)
+	}
+
+	target, err := palette.ParseHex(hex)
+	if err != nil {
+		return nil, err
+	}
+	return &colorSearch{target: target, tolerance: tolerance}, nil
+}
+
+// queryImages runs a library query. With a color search, the images are
+// ranked by color difference instead of the query's sort order, the limit
+// applies to the ranking, and their differences are returned by ID.
+func queryImages(db *database.DB, q database.ImageQuery, search *colorSearch) ([]database.Image, map[int]float64, error) {
+	limit := q.Limit
+	if search != nil {
+		q.Limit = 0
+	}
+
+	images, err := db.QueryImages(q)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	if search == nil {
+		return images, nil, nil
+	}
+
+	images, distances, err := search.rank(db, images)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit > 0 && len(images) > limit {
+		images = images[:limit]
+	}
+	return images, distances, nil
+}
+
+// rank returns the images whose dominant colors are within the tolerance,
+// closest first, together with their color differences. Images that have
+// not been analyzed yet are analyzed and stored first.
+func (s *colorSearch) rank(db *database.DB, images []database.Image) ([]database.Image, map[int]float64, error) {
+	if n := analysis.CountMissing(images); n > 0 {
+		fmt.Printf("🎨 Analyzing colors of %d wallpapers (stored for next time)...\n", n)
+		if _, err := analysis.Backfill(db, images); err != nil {
+			return nil, nil, fmt.Errorf("failed to store palette: %w", err)
+		}
+	}
+
+	distances := make(map[int]float64)
+	var matches []database.Image
+	for _, img := range images {
+		d := palette.Distance(s.target, imageColors(&img))
+		if d <= s.tolerance {
+			distances[img.ID] = d
+			matches = append(matches, img)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return distances[matches[i].ID] < distances[matches[j].ID] })
+	return matches, distances, nil
+}
+
+// imageColors returns the dominant colors of an image: the most common
+// colors of its computed palette plus the colors reported by the source
+func imageColors(img *database.Image) []color.RGBA {
+	var colors []color.RGBA
+	if computed, err := palette.Parse(img.Palette); err == nil {
+		colors = append(colors, computed[:min(len(computed), dominantColors)]...)
+	}
+	if source, err := palette.Parse(img.Colors); err == nil {
+		colors = append(colors, source...)
+	}
+	return colors
+}
@@ -2,8 +2,6 @@ package cli
 
 import (
 	"bufio"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,10 +12,12 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/AccursedGalaxy/wallfetch/internal/database"
 	"github.com/AccursedGalaxy/wallfetch/internal/downloader"
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+	"github.com/AccursedGalaxy/wallfetch/internal/palette"
+	"github.com/AccursedGalaxy/wallfetch/internal/verify"
 	"github.com/AccursedGalaxy/wallfetch/internal/wallhaven"
 	"github.com/spf13/cobra"
 )
@@ -41,6 +41,7 @@ func (a *App) newFetchCmd() *cobra.Command {
 	cmd.Flags().StringP("output", "o", "", "Output directory")
 	cmd.Flags().String("query", "", "Search query")
 	cmd.Flags().String("purity", "", "Content purity (sfw, sketchy, nsfw)")
+	cmd.Flags().String("on-full", "", "When the library limits are reached: stop or evict (default from library.on_full)")
 
 	return cmd
 }
@@ -57,6 +58,8 @@ func (a *App) newListCmd() *cobra.Command {
 	cmd.Flags().StringP("source", "s", "", "Filter by source")
 	cmd.Flags().IntP("limit", "l", 50, "Limit number of results")
 	cmd.Flags().BoolP("verbose", "v", false, "Show detailed information")
+	addImageFilterFlags(cmd)
+	addColorFlags(cmd)
 
 	return cmd
 }
@@ -76,37 +79,44 @@ func (a *App) newBrowseCmd() *cobra.Command {
 	cmd.Flags().BoolP("preview", "p", false, "Show image preview in terminal")
 	cmd.Flags().String("viewer", "", "External image viewer command (e.g., 'feh', 'eog', 'open')")
 	cmd.Flags().BoolP("interactive", "i", false, "Interactive browsing mode")
+	addImageFilterFlags(cmd)
+	addColorFlags(cmd)
 
 	return cmd
 }
 
-// newPruneCmd creates the prune command
-func (a *App) newPruneCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "prune",
-		Short: "Prune old wallpapers",
-		Long:  "Remove old wallpapers keeping only the most recent ones",
-		RunE:  a.runPrune,
-	}
-
-	cmd.Flags().IntP("keep", "k", 100, "Number of wallpapers to keep")
-	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be deleted without actually deleting")
-
-	return cmd
+// addImageFilterFlags adds the metadata filter and sort flags shared by list and browse
+func addImageFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("category", "", "Filter by category (general, anime, people)")
+	cmd.Flags().String("purity", "", "Filter by purity (sfw, sketchy, nsfw)")
+	cmd.Flags().Int("min-width", 0, "Minimum image width")
+	cmd.Flags().Int("min-height", 0, "Minimum image height")
+	cmd.Flags().String("brightness", "", "Filter by brightness (light, dark)")
+	cmd.Flags().String("sort", "downloaded_at", "Sort by "+strings.Join(database.ValidImageSorts(), ", "))
+	cmd.Flags().Bool("asc", false, "Sort in ascending order")
 }
 
-// newDedupeCmd creates the dedupe command
-func (a *App) newDedupeCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "dedupe",
-		Short: "Remove duplicate wallpapers",
-		Long:  "Remove duplicate wallpapers based on checksums",
-		RunE:  a.runDedupe,
+// imageQueryFromFlags builds a database query from the shared filter flags
+func imageQueryFromFlags(cmd *cobra.Command, source string, limit int) database.ImageQuery {
+	category, _ := cmd.Flags().GetString("category")
+	purity, _ := cmd.Flags().GetString("purity")
+	minWidth, _ := cmd.Flags().GetInt("min-width")
+	minHeight, _ := cmd.Flags().GetInt("min-height")
+	brightness, _ := cmd.Flags().GetString("brightness")
+	sortBy, _ := cmd.Flags().GetString("sort")
+	ascending, _ := cmd.Flags().GetBool("asc")
+
+	return database.ImageQuery{
+		Source:     source,
+		Category:   category,
+		Purity:     purity,
+		MinWidth:   minWidth,
+		MinHeight:  minHeight,
+		Brightness: brightness,
+		SortBy:     sortBy,
+		Ascending:  ascending,
+		Limit:      limit,
 	}
-
-	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be deleted without actually deleting")
-
-	return cmd
 }
 
 // runFetch handles the fetch command
@@ -125,7 +135,7 @@ func (a *App) runFetch(cmd *cobra.Command, args []string) error {
 }
 
 // runWallhavenFetch handles fetching from Wallhaven
-func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
+func (a *App) runWallhavenFetch(cmd *cobra.Command) (err error) {
 	client := wallhaven.NewClient(a.config.GetWallhavenAPIKey())
 
 	// Get flags
@@ -173,23 +183,51 @@ func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
 	fmt.Printf("  Page: %d\n", page)
 	fmt.Printf("  Output Directory: %s\n", outputDir)
 
-	results, err := client.Search(params)
+	// Open database
+	db, err := a.openDatabase()
 	if err != nil {
-		return fmt.Errorf("failed to search wallpapers: %w", err)
+		return fmt.Errorf("failed to open database: %w", err)
 	}
+	defer db.Close()
 
-	fmt.Printf("Found %d wallpapers on page %d (total available: %d)\n", len(results.Data), page, results.Meta.Total)
+	// Record this run in the fetch history
+	run, err := db.StartFetchRun("wallhaven", map[string]string{
+		"query":      query,
+		"categories": categories,
+		"purity":     purity,
+		"resolution": resolution,
+		"sort":       sort,
+		"limit":      strconv.Itoa(limit),
+		"page":       strconv.Itoa(page),
+		"output":     outputDir,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record fetch run: %w", err)
+	}
+	defer func() {
+		run.Status = database.RunStatusCompleted
+		if err != nil {
+			run.Status = database.RunStatusFailed
+			run.Errors = append(run.Errors, err.Error())
+		}
+		if finishErr := db.FinishFetchRun(run); finishErr != nil {
+			fmt.Printf("⚠️  Failed to save fetch history: %v\n", finishErr)
+		}
+	}()
 
-	// Open database
-	db, err := database.Open(a.config.Database.Path)
+	results, err := client.Search(params)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return fmt.Errorf("failed to search wallpapers: %w", err)
 	}
-	defer db.Close()
+
+	fmt.Printf("Found %d wallpapers on page %d (total available: %d)\n", len(results.Data), page, results.Meta.Total)
 
 	// Create downloader and filter
 	dl := downloader.NewDownloader(outputDir, a.config.MaxConcurrent, db)
 	filter := downloader.NewWallpaperFilter(&defaults)
+	if err := a.applyLibraryBudget(cmd, db, dl); err != nil {
+		return err
+	}
 
 	fmt.Printf("\nStarting download process to get %d wallpapers...\n", limit)
 
@@ -197,6 +235,7 @@ func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
 	totalDownloaded := 0
 	totalSkipped := 0
 	totalFailed := 0
+	totalEvicted := 0
 	currentPage := page
 	maxPages := results.Meta.LastPage
 
@@ -228,6 +267,8 @@ func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
 		if err != nil {
 			return fmt.Errorf("failed to download wallpapers from page %d: %w", currentPage, err)
 		}
+		run.PagesProcessed++
+		a.recordFetchResults(db, run, downloadResults)
 
 		// Process results and report progress
 		pageDownloaded := 0
@@ -235,6 +276,10 @@ func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
 		pageFailed := 0
 
 		for _, result := range downloadResults {
+			for _, img := range result.Evicted {
+				fmt.Printf("  🗑️  Deleted ID %d %s to make room\n", img.ID, filepath.Base(img.LocalPath))
+				totalEvicted++
+			}
 			if result.Error != nil {
 				fmt.Printf("  ❌ %s - Error: %v\n", result.Wallpaper.ID, result.Error)
 				pageFailed++
@@ -266,6 +311,12 @@ func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
 			break
 		}
 
+		if full := dl.Full(); full != nil {
+			fmt.Printf("\n⚠️  Library full: %v\n", full)
+			fmt.Printf("   Run 'wallfetch prune', raise the library limits or use --on-full evict.\n")
+			break
+		}
+
 		// Move to next page
 		currentPage++
 	}
@@ -278,6 +329,9 @@ func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
 	fmt.Printf("  Downloaded: %d\n", totalDownloaded)
 	fmt.Printf("  Skipped: %d\n", totalSkipped)
 	fmt.Printf("  Failed: %d\n", totalFailed)
+	if totalEvicted > 0 {
+		fmt.Printf("  Evicted (deleted permanently): %d\n", totalEvicted)
+	}
 	fmt.Printf("  Pages processed: %d\n", currentPage-page+1)
 
 	if totalDownloaded < limit {
@@ -288,6 +342,25 @@ func (a *App) runWallhavenFetch(cmd *cobra.Command) error {
 	return nil
 }
 
+// recordFetchResults adds the outcome of every download to a fetch run
+func (a *App) recordFetchResults(db *database.DB, run *database.FetchRun, results []downloader.DownloadResult) {
+	for _, result := range results {
+		switch {
+		case result.Error != nil:
+			run.Failed++
+			run.Errors = append(run.Errors, fmt.Sprintf("%s: %v", result.Wallpaper.ID, result.Error))
+		case result.Skipped:
+			run.Skipped++
+			run.SkipReasons[result.Reason]++
+		default:
+			run.Downloaded++
+			if err := db.AddFetchRunImage(run.ID, result.ImageID, result.Wallpaper.ID); err != nil {
+				fmt.Printf("⚠️  Failed to record %s in fetch history: %v\n", result.Wallpaper.ID, err)
+			}
+		}
+	}
+}
+
 // runList handles the list command
 func (a *App) runList(cmd *cobra.Command, args []string) error {
 	// Get flags
@@ -295,17 +368,26 @@ func (a *App) runList(cmd *cobra.Command, args []string) error {
 	limit, _ := cmd.Flags().GetInt("limit")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 
+	search, err := colorSearchFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	// Open database
-	db, err := database.Open(a.config.Database.Path)
+	db, err := a.openDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
 	// Get images from database
-	images, err := db.ListImages(source, limit)
+	images, distances, err := queryImages(db, imageQueryFromFlags(cmd, source, limit), search)
 	if err != nil {
-		return fmt.Errorf("failed to list images: %w", err)
+		return err
+	}
+	if search != nil && len(images) == 0 {
+		fmt.Printf("No wallpapers within a color difference of %.0f from %s.\n", search.tolerance, palette.Hex(search.target))
+		return nil
 	}
 
 	if len(images) == 0 {
@@ -324,29 +406,27 @@ func (a *App) runList(cmd *cobra.Command, args []string) error {
 	// Display images
 	for _, img := range images {
 		// Check if file exists
-		fileExists := true
-		if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
-			fileExists = false
-		}
+		status, note := fileStatus(&img)
 
 		if verbose {
 			fmt.Printf("ID: %d\n", img.ID)
 			fmt.Printf("Source: %s (%s)\n", img.Source, img.SourceID)
 			fmt.Printf("Resolution: %s\n", img.Resolution)
+			printImageMetadata(&img)
+			if search != nil {
+				fmt.Printf("Color Difference: %.1f\n", distances[img.ID])
+			}
 			fmt.Printf("File Size: %.2f MB\n", float64(img.FileSize)/(1024*1024))
 			fmt.Printf("Local Path: %s", img.LocalPath)
-			if !fileExists {
-				fmt.Printf(" ❌ (FILE MISSING)")
-			}
+			fmt.Print(note)
 			fmt.Printf("\n")
 			fmt.Printf("Tags: %s\n", img.Tags)
 			fmt.Printf("Downloaded: %s\n", img.DownloadedAt.Format("2006-01-02 15:04:05"))
 			fmt.Printf("Checksum: %s\n", img.Checksum[:16]+"...")
 			fmt.Println(strings.Repeat("-", 50))
 		} else {
-			status := "✅"
-			if !fileExists {
-				status = "❌"
+			if search != nil {
+				status += fmt.Sprintf(" ΔE %4.1f |", distances[img.ID])
 			}
 			fmt.Printf("%s %-8s | %-12s | %-15s | %s\n",
 				status,
@@ -360,6 +440,18 @@ func (a *App) runList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// fileStatus returns the status icon of an image's file and a note for
+// files that cannot be shown: missing, or on an unavailable library root
+func fileStatus(img *database.Image) (string, string) {
+	if img.Unavailable {
+		return "⏸️", fmt.Sprintf(" ⏸️ (UNAVAILABLE: library root %s is offline or not configured)", img.Root)
+	}
+	if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
+		return "❌", " ❌ (FILE MISSING)"
+	}
+	return "✅", ""
+}
+
 // runBrowse handles the browse command
 func (a *App) runBrowse(cmd *cobra.Command, args []string) error {
 	// Get flags
@@ -369,22 +461,31 @@ func (a *App) runBrowse(cmd *cobra.Command, args []string) error {
 	viewer, _ := cmd.Flags().GetString("viewer")
 	interactive, _ := cmd.Flags().GetBool("interactive")
 
+	search, err := colorSearchFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	source := ""
 	if len(args) > 0 {
 		source = args[0]
 	}
 
 	// Open database
-	db, err := database.Open(a.config.Database.Path)
+	db, err := a.openDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
 	// Get images from database
-	images, err := db.ListImages(source, limit)
+	images, _, err := queryImages(db, imageQueryFromFlags(cmd, source, limit), search)
 	if err != nil {
-		return fmt.Errorf("failed to list images: %w", err)
+		return err
+	}
+	if search != nil && len(images) == 0 {
+		fmt.Printf("No wallpapers within a color difference of %.0f from %s.\n", search.tolerance, palette.Hex(search.target))
+		return nil
 	}
 
 	if len(images) == 0 {
@@ -637,50 +738,48 @@ func (a *App) runInteractiveBrowse(images []database.Image, db *database.DB, pre
 			fmt.Printf("\n⚠️  Are you sure you want to delete this wallpaper?\n")
 			fmt.Printf("File: %s\n", filepath.Base(img.LocalPath))
 			fmt.Printf("ID: %d | %s (%s) | %s\n", img.ID, img.Source, img.SourceID, img.Resolution)
-			fmt.Print("Type 'yes' to confirm deletion: ")
+			fmt.Print("Type 'yes' to confirm deletion, or 'block' to delete and never fetch again: ")
 
 			confirmInput, _ := reader.ReadString('\n')
 			confirmInput = strings.ToLower(strings.TrimSpace(confirmInput))
 
-			if confirmInput == "yes" {
-				// Delete from database and file
-				localPath, err := db.DeleteImage(img.ID)
-				if err != nil {
-					fmt.Printf("Failed to delete from database: %v\nPress Enter to continue...", err)
+			if confirmInput == "yes" || confirmInput == "block" {
+				blockReason := ""
+				if confirmInput == "block" {
+					blockReason = "deleted"
+				}
+
+				// Move the file to the trash and soft-delete the row
+				if err := removeImage(db, a.openTrash(), img, false, blockReason); err != nil {
+					fmt.Printf("Failed to delete wallpaper: %v\nPress Enter to continue...", err)
 					_, _ = reader.ReadString('\n')
 				} else {
-					// Try to delete the file too
-					if err := os.Remove(localPath); err != nil && !os.IsNotExist(err) {
-						fmt.Printf("Deleted from database but failed to remove file: %v\nPress Enter to continue...", err)
-						_, _ = reader.ReadString('\n')
+					fmt.Printf("✅ Wallpaper moved to trash (restore with 'wallfetch trash restore %d')\n", img.ID)
+
+					// Remove from our images slice and adjust index
+					images = append(images[:currentIndex], images[currentIndex+1:]...)
+
+					// If we deleted the last image, quit
+					if len(images) == 0 {
+						fmt.Printf("No more wallpapers to browse.\n")
+						return nil
+					}
+
+					// Adjust current index if necessary
+					if currentIndex >= len(images) {
+						currentIndex = len(images) - 1
+					}
+
+					// Update viewer with new current image
+					if len(images) > 0 {
+						updateViewer(currentIndex)
 					} else {
-						fmt.Printf("✅ Wallpaper deleted successfully!\n")
-
-						// Remove from our images slice and adjust index
-						images = append(images[:currentIndex], images[currentIndex+1:]...)
-
-						// If we deleted the last image, quit
-						if len(images) == 0 {
-							fmt.Printf("No more wallpapers to browse.\n")
-							return nil
-						}
-
-						// Adjust current index if necessary
-						if currentIndex >= len(images) {
-							currentIndex = len(images) - 1
-						}
-
-						// Update viewer with new current image
-						if len(images) > 0 {
-							updateViewer(currentIndex)
-						} else {
-							// No more images, close viewer
-							cleanupViewer()
-						}
-
-						fmt.Printf("Press Enter to continue...")
-						_, _ = reader.ReadString('\n')
+						// No more images, close viewer
+						cleanupViewer()
 					}
+
+					fmt.Printf("Press Enter to continue...")
+					_, _ = reader.ReadString('\n')
 				}
 			} else {
 				fmt.Printf("Deletion cancelled.\nPress Enter to continue...")
@@ -704,6 +803,7 @@ func (a *App) runInteractiveBrowse(images []database.Image, db *database.DB, pre
 			fmt.Printf("Source: %s (%s)\n", img.Source, img.SourceID)
 			fmt.Printf("URL: %s\n", img.URL)
 			fmt.Printf("Resolution: %s\n", img.Resolution)
+			printImageMetadata(img)
 			fmt.Printf("File Size: %.2f MB\n", float64(img.FileSize)/(1024*1024))
 			fmt.Printf("Downloaded: %s\n", img.DownloadedAt.Format("2006-01-02 15:04:05"))
 			fmt.Printf("Checksum: %s\n", img.Checksum)
@@ -738,6 +838,39 @@ func (a *App) runInteractiveBrowse(images []database.Image, db *database.DB, pre
 	}
 }
 
+// printImageMetadata prints the source metadata fields that are set
+func printImageMetadata(img *database.Image) {
+	if img.Category != "" || img.Purity != "" {
+		fmt.Printf("Category: %s | Purity: %s\n", img.Category, img.Purity)
+	}
+	if img.FileType != "" {
+		fmt.Printf("File Type: %s\n", img.FileType)
+	}
+	if img.Colors != "" {
+		fmt.Printf("Colors: %s\n", img.Colors)
+	}
+	if img.Palette != "" {
+		fmt.Printf("Palette: %s\n", img.Palette)
+	}
+	if img.Rating > 0 {
+		fmt.Printf("Rating: %s\n", stars(img.Rating))
+	}
+	if img.Views > 0 || img.SourceFavorites > 0 {
+		fmt.Printf("Views: %d | Favorites: %d\n", img.Views, img.SourceFavorites)
+	}
+	if img.Uploader != "" {
+		fmt.Printf("Uploader: %s\n", img.Uploader)
+	}
+	if img.PageURL != "" {
+		fmt.Printf("Page: %s\n", img.PageURL)
+	}
+}
+
+// stars renders a rating as five filled or empty stars
+func stars(rating int) string {
+	return strings.Repeat("★", rating) + strings.Repeat("☆", 5-rating)
+}
+
 // detectImageViewer attempts to detect available image viewers
 func (a *App) detectImageViewer() string {
 	viewers := []string{
@@ -783,266 +916,6 @@ func (a *App) openWithViewer(imagePath, viewer string) error {
 	return cmd.Run()
 }
 
-// runPrune handles the prune command
-func (a *App) runPrune(cmd *cobra.Command, args []string) error {
-	keep, _ := cmd.Flags().GetInt("keep")
-	dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-	// Open database
-	db, err := database.Open(a.config.Database.Path)
-	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
-	}
-	defer db.Close()
-
-	// Get current count
-	totalCount, err := db.CountImages()
-	if err != nil {
-		return fmt.Errorf("failed to count images: %w", err)
-	}
-
-	if totalCount <= keep {
-		fmt.Printf("✅ Current collection has %d wallpapers (keep target: %d)\n", totalCount, keep)
-		fmt.Println("No pruning needed!")
-		return nil
-	}
-
-	toDelete := totalCount - keep
-	fmt.Printf("Collection Management:\n")
-	fmt.Printf("  Current wallpapers: %d\n", totalCount)
-	fmt.Printf("  Target to keep: %d\n", keep)
-	fmt.Printf("  Will delete: %d oldest wallpapers\n", toDelete)
-
-	if dryRun {
-		// Get the paths that would be deleted
-		pathsToDelete, err := db.DeleteOldImages(keep)
-		if err != nil {
-			return fmt.Errorf("failed to get old images list: %w", err)
-		}
-
-		fmt.Printf("\n🔍 DRY RUN - Would delete %d old wallpapers:\n", len(pathsToDelete))
-		for i, path := range pathsToDelete {
-			if i < 10 { // Show first 10
-				fmt.Printf("  - %s\n", filepath.Base(path))
-			} else if i == 10 {
-				fmt.Printf("  ... and %d more\n", len(pathsToDelete)-10)
-				break
-			}
-		}
-		fmt.Printf("\nRun without --dry-run to actually delete these files\n")
-		return nil
-	}
-
-	// Ask for confirmation
-	fmt.Printf("\n⚠️  This will permanently delete %d old wallpapers from both database and disk.\n", toDelete)
-	fmt.Printf("The %d most recently downloaded wallpapers will be kept.\n", keep)
-	fmt.Print("Do you want to continue? [y/N]: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		return fmt.Errorf("failed to read input: %w", err)
-	}
-
-	response = strings.ToLower(strings.TrimSpace(response))
-	if response != "y" && response != "yes" {
-		fmt.Println("❌ Operation cancelled")
-		return nil
-	}
-
-	// Perform the pruning
-	fmt.Printf("\n🗑️  Pruning old wallpapers...\n")
-
-	pathsToDelete, err := db.DeleteOldImages(keep)
-	if err != nil {
-		return fmt.Errorf("failed to delete old images: %w", err)
-	}
-
-	// Delete the actual files
-	deleted := 0
-	failed := 0
-	var totalSize int64
-
-	for _, path := range pathsToDelete {
-		// Get file size before deleting
-		if fileInfo, err := os.Stat(path); err == nil {
-			totalSize += fileInfo.Size()
-		}
-
-		if err := os.Remove(path); err != nil {
-			if !os.IsNotExist(err) {
-				fmt.Printf("  ⚠️  Failed to delete file %s: %v\n", filepath.Base(path), err)
-				failed++
-			} else {
-				fmt.Printf("  ✅ %s (file was already missing)\n", filepath.Base(path))
-				deleted++
-			}
-		} else {
-			fmt.Printf("  ✅ %s\n", filepath.Base(path))
-			deleted++
-		}
-	}
-
-	fmt.Println()
-	fmt.Println(strings.Repeat("=", 50))
-	fmt.Printf("PRUNE SUMMARY:\n")
-	fmt.Printf("  Files deleted: %d\n", deleted)
-	if failed > 0 {
-		fmt.Printf("  Failed deletions: %d\n", failed)
-	}
-	fmt.Printf("  Space freed: %.2f MB\n", float64(totalSize)/(1024*1024))
-	fmt.Printf("  Remaining wallpapers: %d\n", keep)
-
-	return nil
-}
-
-// runDedupe handles the dedupe command
-func (a *App) runDedupe(cmd *cobra.Command, args []string) error {
-	dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-	// Open database
-	db, err := database.Open(a.config.Database.Path)
-	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
-	}
-	defer db.Close()
-
-	// Find duplicates
-	duplicateGroups, err := db.FindDuplicates()
-	if err != nil {
-		return fmt.Errorf("failed to find duplicates: %w", err)
-	}
-
-	if len(duplicateGroups) == 0 {
-		fmt.Println("✅ No duplicates found!")
-		return nil
-	}
-
-	fmt.Printf("Found %d groups of duplicate wallpapers:\n\n", len(duplicateGroups))
-
-	totalDuplicates := 0
-	var toDelete []database.Image
-
-	for i, group := range duplicateGroups {
-		fmt.Printf("Duplicate Group %d (%d images):\n", i+1, len(group))
-		fmt.Printf("Checksum: %s\n", group[0].Checksum[:16]+"...")
-
-		// Sort by download date (keep the first one in the group)
-		for j, img := range group {
-			// Check if file still exists
-			fileExists := true
-			if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
-				fileExists = false
-			}
-
-			status := "✅"
-			if !fileExists {
-				status = "❌"
-			}
-
-			keepMarker := ""
-			if j == 0 {
-				keepMarker = " (KEEP)"
-			} else {
-				keepMarker = " (DELETE)"
-				toDelete = append(toDelete, img)
-				totalDuplicates++
-			}
-
-			fmt.Printf("  %s ID: %-4d | %s | %s | %s%s\n",
-				status,
-				img.ID,
-				img.SourceID,
-				img.Resolution,
-				filepath.Base(img.LocalPath),
-				keepMarker)
-		}
-		fmt.Println()
-	}
-
-	if totalDuplicates == 0 {
-		fmt.Println("✅ No duplicates to remove!")
-		return nil
-	}
-
-	fmt.Printf("Summary: Found %d duplicate files to remove\n", totalDuplicates)
-
-	if dryRun {
-		fmt.Printf("\n🔍 DRY RUN - Would delete %d duplicate files:\n", totalDuplicates)
-		for _, img := range toDelete {
-			fmt.Printf("  - ID %d: %s\n", img.ID, filepath.Base(img.LocalPath))
-		}
-		fmt.Printf("\nRun without --dry-run to actually remove duplicates\n")
-		return nil
-	}
-
-	// Ask for confirmation
-	fmt.Printf("\n⚠️  This will permanently delete %d duplicate files from both database and disk.\n", totalDuplicates)
-	fmt.Print("Do you want to continue? [y/N]: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		return fmt.Errorf("failed to read input: %w", err)
-	}
-
-	response = strings.ToLower(strings.TrimSpace(response))
-	if response != "y" && response != "yes" {
-		fmt.Println("❌ Operation cancelled")
-		return nil
-	}
-
-	// Delete duplicates
-	deleted := 0
-	failed := 0
-
-	fmt.Printf("\n🗑️  Removing duplicates...\n")
-	for _, img := range toDelete {
-		// Delete from database
-		_, err := db.DeleteImage(img.ID)
-		if err != nil {
-			fmt.Printf("  ❌ Failed to delete ID %d from database: %v\n", img.ID, err)
-			failed++
-			continue
-		}
-
-		// Delete file from disk
-		if err := os.Remove(img.LocalPath); err != nil {
-			if !os.IsNotExist(err) {
-				fmt.Printf("  ⚠️  Deleted from database but failed to delete file %s: %v\n", img.LocalPath, err)
-			} else {
-				fmt.Printf("  ✅ ID %d: %s (file was already missing)\n", img.ID, filepath.Base(img.LocalPath))
-			}
-		} else {
-			fmt.Printf("  ✅ ID %d: %s\n", img.ID, filepath.Base(img.LocalPath))
-		}
-		deleted++
-	}
-
-	fmt.Println()
-	fmt.Println(strings.Repeat("=", 50))
-	fmt.Printf("DEDUPE SUMMARY:\n")
-	fmt.Printf("  Successfully deleted: %d\n", deleted)
-	if failed > 0 {
-		fmt.Printf("  Failed: %d\n", failed)
-	}
-	fmt.Printf("  Space potentially freed: calculating...\n")
-
-	// Calculate space saved (rough estimate)
-	var totalSize int64
-	for _, img := range toDelete {
-		if deleted > failed { // only count if we successfully deleted most
-			totalSize += img.FileSize
-		}
-	}
-
-	if totalSize > 0 {
-		fmt.Printf("  Approximate space freed: %.2f MB\n", float64(totalSize)/(1024*1024))
-	}
-
-	return nil
-}
-
 // newDeleteCmd creates the delete command
 func (a *App) newDeleteCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -1052,8 +925,10 @@ func (a *App) newDeleteCmd() *cobra.Command {
 		RunE:  a.runDelete,
 	}
 
-	cmd.Flags().Bool("file", false, "Also delete the file from disk")
+	cmd.Flags().Bool("file", false, "Also delete the file from disk (moved to trash unless --permanent)")
 	cmd.Flags().StringP("source-id", "s", "", "Delete by source ID (e.g., wallhaven ID)")
+	cmd.Flags().BoolP("block", "b", false, "Add the wallpaper to the blocklist so it is never fetched again")
+	cmd.Flags().Bool("permanent", false, "Delete the file permanently instead of moving it to the trash")
 
 	return cmd
 }
@@ -1063,8 +938,11 @@ func (a *App) newCleanupCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "cleanup",
 		Short: "Clean up missing files",
-		Long:  "Remove database entries for wallpapers that no longer exist on disk",
-		RunE:  a.runCleanup,
+		Long: `Find wallpapers whose files no longer exist on disk. Files that were moved or
+renamed within the library directories (the download directory and
+library.roots) are found by checksum and relinked; the database entries of
+the rest are removed.`,
+		RunE: a.runCleanup,
 	}
 
 	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be cleaned without actually cleaning")
@@ -1090,73 +968,135 @@ func (a *App) newFavoritesCmd() *cobra.Command {
 	return cmd
 }
 
-// newImportCmd creates the import command
-func (a *App) newImportCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "import [directory]",
-		Short: "Import existing wallpapers into the database",
-		Long:  "Scan a directory for wallpaper files and add them to the database",
-		RunE:  a.runImport,
+// newRateCmd creates the rate command
+func (a *App) newRateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rate [id] [0-5]",
+		Short: "Rate a wallpaper",
+		Long:  "Rate a wallpaper from 1 to 5 stars, or 0 to clear its rating. Ratings can protect wallpapers from prune.",
+		Args:  cobra.ExactArgs(2),
+		RunE:  a.runRate,
+	}
+}
+
+// runRate handles the rate command
+func (a *App) runRate(cmd *cobra.Command, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid wallpaper ID: %s", args[0])
+	}
+	rating, err := strconv.Atoi(args[1])
+	if err != nil || rating < 0 || rating > 5 {
+		return fmt.Errorf("invalid rating: %s (use 1-5, or 0 to clear)", args[1])
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
 	}
+	defer db.Close()
 
-	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be imported without actually importing")
-	cmd.Flags().StringP("source", "s", "local", "Source name for imported wallpapers")
+	if err := db.SetRating(id, rating); err != nil {
+		return fmt.Errorf("failed to rate wallpaper: %w", err)
+	}
 
-	return cmd
+	if rating == 0 {
+		fmt.Printf("✅ Cleared the rating of wallpaper ID %d\n", id)
+	} else {
+		fmt.Printf("✅ Rated wallpaper ID %d %s\n", id, stars(rating))
+	}
+	return nil
 }
 
 // runDelete handles the delete command
 func (a *App) runDelete(cmd *cobra.Command, args []string) error {
 	deleteFile, _ := cmd.Flags().GetBool("file")
 	sourceID, _ := cmd.Flags().GetString("source-id")
+	block, _ := cmd.Flags().GetBool("block")
+	permanent, _ := cmd.Flags().GetBool("permanent")
 
 	if len(args) == 0 && sourceID == "" {
 		return fmt.Errorf("must provide either wallpaper ID or --source-id")
 	}
 
 	// Open database
-	db, err := database.Open(a.config.Database.Path)
+	db, err := a.openDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
-	var localPath string
+	var img *database.Image
+	var label string
 
 	if sourceID != "" {
-		// Delete by source ID
-		localPath, err = db.DeleteImageBySourceID("wallhaven", sourceID)
+		// Look up by source ID
+		img, err = db.GetImageBySourceID("wallhaven", sourceID)
 		if err != nil {
-			return fmt.Errorf("failed to delete wallpaper by source ID: %w", err)
+			return fmt.Errorf("failed to find wallpaper by source ID: %w", err)
 		}
-		fmt.Printf("Deleted wallpaper %s from database\n", sourceID)
+		label = sourceID
 	} else {
-		// Delete by ID
+		// Look up by ID
 		id, err := strconv.Atoi(args[0])
 		if err != nil {
 			return fmt.Errorf("invalid wallpaper ID: %s", args[0])
 		}
 
-		localPath, err = db.DeleteImage(id)
+		img, err = db.GetImageByID(id)
 		if err != nil {
-			return fmt.Errorf("failed to delete wallpaper: %w", err)
+			return fmt.Errorf("failed to find wallpaper: %w", err)
 		}
-		fmt.Printf("Deleted wallpaper ID %d from database\n", id)
+		label = fmt.Sprintf("ID %d", id)
 	}
 
-	// Delete file if requested
-	if deleteFile {
-		if err := os.Remove(localPath); err != nil {
-			if !os.IsNotExist(err) {
-				return fmt.Errorf("failed to delete file %s: %w", localPath, err)
-			}
-			fmt.Printf("File %s was already missing\n", localPath)
+	if img.DeletedAt != nil {
+		return fmt.Errorf("wallpaper %s is already in the trash", label)
+	}
+	if shared := sharedWith(db, img); deleteFile && shared != "" {
+		fmt.Printf("🔗 The file is linked to %s, whose data is kept\n", shared)
+	}
+
+	blockReason := ""
+	if block {
+		blockReason = "deleted"
+	}
+
+	switch {
+	case deleteFile && !permanent:
+		// Move to trash so the deletion can be undone
+		if err := removeImage(db, a.openTrash(), img, false, blockReason); err != nil {
+			return fmt.Errorf("failed to delete wallpaper: %w", err)
+		}
+		fmt.Printf("Moved wallpaper %s to trash\n", label)
+		fmt.Printf("Use 'wallfetch trash restore %d' to undo\n", img.ID)
+	case deleteFile:
+		_, statErr := os.Stat(img.LocalPath)
+		if err := removeImage(db, a.openTrash(), img, true, blockReason); err != nil {
+			return fmt.Errorf("failed to delete wallpaper: %w", err)
+		}
+		fmt.Printf("Deleted wallpaper %s from database\n", label)
+		if os.IsNotExist(statErr) {
+			fmt.Printf("File %s was already missing\n", img.LocalPath)
 		} else {
-			fmt.Printf("Deleted file: %s\n", localPath)
+			fmt.Printf("Deleted file: %s\n", img.LocalPath)
 		}
-	} else {
-		fmt.Printf("File preserved: %s\n", localPath)
+	default:
+		if _, err := db.DeleteImage(img.ID); err != nil {
+			return fmt.Errorf("failed to delete wallpaper: %w", err)
+		}
+		fmt.Printf("Deleted wallpaper %s from database\n", label)
+		fmt.Printf("File preserved: %s\n", img.LocalPath)
 		fmt.Printf("Use --file flag to also delete the file from disk\n")
+		if block {
+			if err := db.BlockImage(img, blockReason); err != nil {
+				return fmt.Errorf("failed to block wallpaper: %w", err)
+			}
+		}
+	}
+
+	if block {
+		fmt.Printf("Added wallpaper to blocklist\n")
 	}
 
 	return nil
@@ -1167,50 +1107,94 @@ func (a *App) runCleanup(cmd *cobra.Command, args []string) error {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 	// Open database
-	db, err := database.Open(a.config.Database.Path)
+	db, err := a.openDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
-	if dryRun {
-		// Get all images and check which ones are missing
-		images, err := db.ListImages("", 0)
-		if err != nil {
-			return fmt.Errorf("failed to list images: %w", err)
-		}
+	images, err := db.ListImages("", 0)
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
 
-		var missingFiles []string
-		for _, img := range images {
-			if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
-				missingFiles = append(missingFiles, img.LocalPath)
-			}
+	// Wallpapers on library roots that are offline, e.g. an unmounted
+	// drive, are left alone rather than counted as missing
+	var missing []database.Image
+	unavailable := 0
+	tracked := make(map[string]bool, len(images))
+	for _, img := range images {
+		if img.Unavailable {
+			unavailable++
+			continue
 		}
+		if abs, err := filepath.Abs(img.LocalPath); err == nil {
+			tracked[abs] = true
+		}
+		if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
+			missing = append(missing, img)
+		}
+	}
 
-		if len(missingFiles) == 0 {
-			fmt.Println("No missing files found")
-		} else {
-			fmt.Printf("Would clean up %d missing files:\n", len(missingFiles))
-			for _, path := range missingFiles {
-				fmt.Printf("  - %s\n", path)
+	if unavailable > 0 {
+		fmt.Printf("⏸️  Skipping %d wallpapers on unavailable library roots\n", unavailable)
+	}
+	if len(missing) == 0 {
+		fmt.Println("No missing files found")
+		return nil
+	}
+
+	// Files that were moved or renamed are relinked rather than dropped,
+	// which would lose their favorites, tags and ratings
+	dirs := a.config.LibraryDirs()
+	fmt.Printf("🔍 %d missing files, searching %s for moved copies...\n", len(missing), strings.Join(dirs, ", "))
+	untracked, err := verify.Untracked(dirs, tracked)
+	if err != nil {
+		return fmt.Errorf("failed to scan library directories: %w", err)
+	}
+	finder := verify.NewFinder(untracked)
+
+	relinked, removed := 0, 0
+	for _, img := range missing {
+		if path := finder.Find(&img); path != "" {
+			if dryRun {
+				fmt.Printf("  🔗 Would relink ID %d: %s -> %s\n", img.ID, img.LocalPath, path)
+			} else {
+				if err := db.SetLocalPath(img.ID, path); err != nil {
+					return fmt.Errorf("failed to relink image %d: %w", img.ID, err)
+				}
+				fmt.Printf("  🔗 Relinked ID %d: %s -> %s\n", img.ID, img.LocalPath, path)
 			}
-			fmt.Printf("\nRun without --dry-run to actually clean up\n")
-		}
-	} else {
-		// Actually clean up
-		deletedPaths, err := db.CleanupMissingFiles()
-		if err != nil {
-			return fmt.Errorf("failed to cleanup missing files: %w", err)
+			relinked++
+			continue
 		}
 
-		if len(deletedPaths) == 0 {
-			fmt.Println("No missing files found")
+		if dryRun {
+			fmt.Printf("  - Would remove ID %d: %s\n", img.ID, img.LocalPath)
 		} else {
-			fmt.Printf("Cleaned up %d missing files:\n", len(deletedPaths))
-			for _, path := range deletedPaths {
-				fmt.Printf("  - %s\n", path)
+			if _, err := db.DeleteImage(img.ID); err != nil {
+				return fmt.Errorf("failed to delete image %d: %w", img.ID, err)
 			}
+			fmt.Printf("  - Removed ID %d: %s\n", img.ID, img.LocalPath)
 		}
+		removed++
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("CLEANUP SUMMARY:\n")
+	if dryRun {
+		fmt.Printf("  Would relink: %d\n", relinked)
+		fmt.Printf("  Would remove: %d\n", removed)
+		fmt.Printf("\nRun without --dry-run to actually clean up\n")
+	} else {
+		fmt.Printf("  Relinked: %d\n", relinked)
+		fmt.Printf("  Removed: %d\n", removed)
+	}
+	if removed > 0 {
+		fmt.Printf("\nWallpapers moved outside the library directories can be relinked with\n")
+		fmt.Printf("'wallfetch relocate <old-prefix> <new-prefix>' or by adding the directory\n")
+		fmt.Printf("to library.roots before cleaning up\n")
 	}
 
 	return nil
@@ -1226,7 +1210,7 @@ func (a *App) runFavorites(cmd *cobra.Command, args []string) error {
 	verbose, _ := cmd.Flags().GetBool("verbose")
 
 	// Open database
-	db, err := database.Open(a.config.Database.Path)
+	db, err := a.openDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -1282,30 +1266,22 @@ func (a *App) runFavorites(cmd *cobra.Command, args []string) error {
 	// Display images
 	for _, img := range images {
 		// Check if file exists
-		fileExists := true
-		if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
-			fileExists = false
-		}
+		status, note := fileStatus(&img)
 
 		if verbose {
 			fmt.Printf("ID: %d\n", img.ID)
 			fmt.Printf("Source: %s (%s)\n", img.Source, img.SourceID)
 			fmt.Printf("Resolution: %s\n", img.Resolution)
+			printImageMetadata(&img)
 			fmt.Printf("File Size: %.2f MB\n", float64(img.FileSize)/(1024*1024))
 			fmt.Printf("Local Path: %s", img.LocalPath)
-			if !fileExists {
-				fmt.Printf(" ❌ (FILE MISSING)")
-			}
+			fmt.Print(note)
 			fmt.Printf("\n")
 			fmt.Printf("Tags: %s\n", img.Tags)
 			fmt.Printf("Downloaded: %s\n", img.DownloadedAt.Format("2006-01-02 15:04:05"))
 			fmt.Printf("Checksum: %s\n", img.Checksum[:16]+"...")
 			fmt.Println(strings.Repeat("-", 50))
 		} else {
-			status := "✅"
-			if !fileExists {
-				status = "❌"
-			}
 			fmt.Printf("%s %-8s | %-12s | %-15s | %s\n",
 				status,
 				img.SourceID,
@@ -1318,151 +1294,17 @@ func (a *App) runFavorites(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// runImport handles the import command
-func (a *App) runImport(cmd *cobra.Command, args []string) error {
-	dryRun, _ := cmd.Flags().GetBool("dry-run")
-	source, _ := cmd.Flags().GetString("source")
-
-	// Determine directory to scan
-	scanDir := a.config.DownloadDir
-	if len(args) > 0 {
-		scanDir = args[0]
-	}
-
-	fmt.Printf("Scanning directory: %s\n", scanDir)
-
-	// Check if directory exists
-	if _, err := os.Stat(scanDir); os.IsNotExist(err) {
-		return fmt.Errorf("directory does not exist: %s", scanDir)
-	}
-
-	// Open database
-	db, err := database.Open(a.config.Database.Path)
-	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
-	}
-	defer db.Close()
-
-	// Find image files
-	imageFiles, err := a.findImageFiles(scanDir)
-	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
-	}
-
-	if len(imageFiles) == 0 {
-		fmt.Println("No image files found in the directory.")
-		return nil
-	}
-
-	fmt.Printf("Found %d image files\n", len(imageFiles))
-
-	if dryRun {
-		fmt.Println("\n🔍 DRY RUN - Would import the following files:")
-	}
-
-	imported := 0
-	skipped := 0
-	failed := 0
-
-	for _, filePath := range imageFiles {
-		filename := filepath.Base(filePath)
-
-		if dryRun {
-			fmt.Printf("  - %s\n", filename)
-			continue
-		}
-
-		// Extract metadata
-		sourceID := a.extractSourceID(filename)
-		resolution, fileSize, err := a.getImageInfo(filePath)
-		if err != nil {
-			fmt.Printf("⚠️  Failed to get info for %s: %v\n", filename, err)
-			failed++
-			continue
-		}
-
-		// Calculate checksum
-		checksum, err := a.calculateChecksum(filePath)
-		if err != nil {
-			fmt.Printf("⚠️  Failed to calculate checksum for %s: %v\n", filename, err)
-			failed++
-			continue
-		}
-
-		// Check if already exists
-		exists, err := db.ExistsByChecksum(checksum)
-		if err != nil {
-			fmt.Printf("⚠️  Failed to check existence for %s: %v\n", filename, err)
-			failed++
-			continue
-		}
-
-		if exists {
-			fmt.Printf("⏭️  Skipped (already exists): %s\n", filename)
-			skipped++
-			continue
-		}
-
-		// Create image record
-		img := &database.Image{
-			Source:       source,
-			SourceID:     sourceID,
-			URL:          "", // Local file, no URL
-			LocalPath:    filePath,
-			Checksum:     checksum,
-			Tags:         "",
-			Resolution:   resolution,
-			FileSize:     fileSize,
-			DownloadedAt: time.Now(),
-			Favorite:     false,
-		}
-
-		// Insert into database
-		err = db.InsertImage(img)
-		if err != nil {
-			fmt.Printf("❌ Failed to import %s: %v\n", filename, err)
-			failed++
-		} else {
-			fmt.Printf("✅ Imported: %s\n", filename)
-			imported++
-		}
-	}
-
-	if dryRun {
-		fmt.Printf("\nWould import %d files\n", len(imageFiles))
-	} else {
-		fmt.Println()
-		fmt.Println(strings.Repeat("=", 50))
-		fmt.Printf("IMPORT SUMMARY:\n")
-		fmt.Printf("  Successfully imported: %d\n", imported)
-		fmt.Printf("  Skipped (already exist): %d\n", skipped)
-		if failed > 0 {
-			fmt.Printf("  Failed: %d\n", failed)
-		}
-		fmt.Printf("  Total files processed: %d\n", len(imageFiles))
-	}
-
-	return nil
-}
-
 // findImageFiles finds all image files in a directory
 func (a *App) findImageFiles(dir string) ([]string, error) {
 	var files []string
-	imageExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp"}
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !info.IsDir() {
-			ext := strings.ToLower(filepath.Ext(path))
-			for _, imageExt := range imageExts {
-				if ext == imageExt {
-					files = append(files, path)
-					break
-				}
-			}
+		if !info.IsDir() && imaging.IsImageFile(path) {
+			files = append(files, path)
 		}
 
 		return nil
@@ -1471,56 +1313,38 @@ func (a *App) findImageFiles(dir string) ([]string, error) {
 	return files, err
 }
 
-// extractSourceID extracts source ID from filename (e.g., wallhaven-1q3g79.png -> 1q3g79)
-func (a *App) extractSourceID(filename string) string {
-	// Try wallhaven pattern
-	if strings.HasPrefix(filename, "wallhaven-") && strings.Contains(filename, ".") {
-		parts := strings.Split(filename, ".")
-		if len(parts) >= 2 {
-			idPart := strings.TrimPrefix(parts[0], "wallhaven-")
-			return idPart
-		}
-	}
-
-	// For other sources or unknown, return empty string
-	return ""
-}
-
-// getImageInfo gets basic image information
-func (a *App) getImageInfo(filePath string) (resolution string, fileSize int64, err error) {
+// getImageInfo gets basic image information. Width and height are zero
+// when the dimensions could not be determined.
+func (a *App) getImageInfo(filePath string) (width, height int, fileSize int64, err error) {
 	// Get file size
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		return "", 0, err
+		return 0, 0, 0, err
 	}
 	fileSize = fileInfo.Size()
 
-	// Try to get image dimensions using identify (ImageMagick)
-	cmd := exec.Command("identify", "-format", "%wx%h", filePath)
+	// Read the dimensions from the image header
+	if width, height, _, err := imaging.Dimensions(filePath); err == nil {
+		return width, height, fileSize, nil
+	}
+
+	// Fall back to identify (ImageMagick) for formats Go cannot read
+	cmd := exec.Command("identify", "-format", "%w %h", filePath)
 	output, err := cmd.Output()
 	if err != nil {
-		// Fallback: just return unknown resolution
-		return "unknown", fileSize, nil
+		// Fallback: dimensions unknown
+		return 0, 0, fileSize, nil
 	}
 
-	resolution = strings.TrimSpace(string(output))
-	return resolution, fileSize, nil
+	if _, err := fmt.Sscanf(string(output), "%d %d", &width, &height); err != nil {
+		return 0, 0, fileSize, nil
+	}
+	return width, height, fileSize, nil
 }
 
 // calculateChecksum calculates SHA256 checksum of a file
 func (a *App) calculateChecksum(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
-	}
-
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return verify.FileChecksum(filePath)
 }
 
 // copyFile copies a file from src to dst
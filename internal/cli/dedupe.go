@@ -0,0 +1,629 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/analysis"
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/filelink"
+	"github.com/AccursedGalaxy/wallfetch/internal/imagehash"
+	"github.com/spf13/cobra"
+)
+
+// newDedupeCmd creates the dedupe command
+func (a *App) newDedupeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Remove duplicate wallpapers",
+		Long: `Remove duplicate wallpapers based on checksums.
+
+With --similar, near-duplicates are found as well: resized, re-encoded or
+slightly edited copies of the same image. They are matched by perceptual
+hashes, which are computed once and stored for next time. Every copy in a
+group is within --threshold bits of every other copy on both the pHash and
+the dHash, so whichever copy is kept, only near-duplicates of it go.
+
+One copy of each group is kept, chosen by --keep:
+  oldest               the first one downloaded (default)
+  newest               the last one downloaded
+  favorite             a favorite
+  highest-resolution   the largest image
+  path-prefix=<dir>    one stored under dir
+Copies whose files still exist are always preferred, and ties go to the
+oldest. Tags and favorites of removed copies are merged onto the one kept.
+With --interactive you choose the copy to keep for each group.
+
+With --link, duplicates stay in the library but their files are replaced
+by reflinks (btrfs, XFS) or hardlinks to the copy kept, so the data is
+stored once; with --similar, the linked copies show the kept image from
+then on. Linked copies are not reported as duplicates again, and deleting
+one of them keeps the data the others still use.`,
+		RunE: a.runDedupe,
+	}
+
+	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be deleted without actually deleting")
+	cmd.Flags().BoolP("block", "b", false, "Add removed duplicates to the blocklist so they are never fetched again")
+	cmd.Flags().Bool("permanent", false, "Delete files permanently instead of moving them to the trash")
+	cmd.Flags().Bool("similar", false, "Also find near-duplicates by perceptual hash")
+	cmd.Flags().Int("threshold", analysis.DefaultThreshold, "Maximum hash distance in bits for --similar (0-64)")
+	cmd.Flags().BoolP("preview", "p", false, "Preview each group side by side")
+	cmd.Flags().StringP("keep", "k", keepOldest, "Copy to keep: oldest, newest, favorite, highest-resolution or path-prefix=<dir>")
+	cmd.Flags().BoolP("interactive", "i", false, "Choose the copy to keep for each group")
+	cmd.Flags().String("link", "", "Replace duplicates with links instead of deleting them: auto, reflink or hardlink")
+	cmd.Flags().Lookup("link").NoOptDefVal = filelink.Auto
+
+	return cmd
+}
+
+// runDedupe handles the dedupe command
+func (a *App) runDedupe(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	block, _ := cmd.Flags().GetBool("block")
+	permanent, _ := cmd.Flags().GetBool("permanent")
+	similar, _ := cmd.Flags().GetBool("similar")
+	threshold, _ := cmd.Flags().GetInt("threshold")
+	preview, _ := cmd.Flags().GetBool("preview")
+	keepSpec, _ := cmd.Flags().GetString("keep")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	linkMode, _ := cmd.Flags().GetString("link")
+
+	policy, err := parseKeepPolicy(keepSpec)
+	if err != nil {
+		return err
+	}
+	if linkMode != "" {
+		if linkMode, err = filelink.ParseMode(linkMode); err != nil {
+			return err
+		}
+		if block {
+			return fmt.Errorf("--block cannot be combined with --link, linked duplicates stay in the library")
+		}
+	}
+	if threshold < 0 || threshold > imagehash.Bits {
+		return fmt.Errorf("invalid threshold: %d (must be 0-%d)", threshold, imagehash.Bits)
+	}
+
+	// Open database
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	// Find duplicates
+	var duplicateGroups [][]database.Image
+	if similar {
+		duplicateGroups, err = findSimilar(db, threshold)
+	} else {
+		duplicateGroups, err = db.FindDuplicates()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find duplicates: %w", err)
+	}
+	duplicateGroups = dropLinkedGroups(duplicateGroups)
+
+	kind := "duplicate"
+	if similar {
+		kind = "similar"
+	}
+
+	if len(duplicateGroups) == 0 {
+		fmt.Printf("✅ No %s wallpapers found!\n", kind)
+		return nil
+	}
+
+	var pm *PreviewManager
+	if preview {
+		pm = NewPreviewManager()
+		if !pm.CanPreview() {
+			fmt.Printf("⚠️  Preview mode requested but no preview tools available.\n")
+			fmt.Print(pm.InstallInstructions())
+			fmt.Printf("\nContinuing without preview...\n\n")
+			pm = nil
+		}
+	}
+
+	fmt.Printf("Found %d groups of %s wallpapers:\n\n", len(duplicateGroups), kind)
+
+	reader := bufio.NewReader(os.Stdin)
+	totalDuplicates := 0
+	var plans []dedupePlan
+
+	for i, group := range duplicateGroups {
+		if similar {
+			fmt.Printf("Similar Group %d (%d images):\n", i+1, len(group))
+		} else {
+			fmt.Printf("Duplicate Group %d (%d images):\n", i+1, len(group))
+			fmt.Printf("Checksum: %s\n", group[0].Checksum[:16]+"...")
+		}
+
+		keep := policy.choose(group)
+
+		action := " (DELETE)"
+		if linkMode != "" {
+			action = " (LINK)"
+		}
+
+		var previewPaths []string
+		for j, img := range group {
+			// Check if file still exists
+			fileExists := true
+			if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
+				fileExists = false
+			}
+
+			status := "✅"
+			if !fileExists {
+				status = "❌"
+			} else {
+				previewPaths = append(previewPaths, img.LocalPath)
+			}
+
+			keepMarker := action
+			switch {
+			case j == keep:
+				keepMarker = " (KEEP)"
+			case linkedTo(&img, &group[keep]):
+				keepMarker = " (LINKED)"
+			}
+
+			favorite := ""
+			if img.Favorite {
+				favorite = " ★"
+			}
+
+			distance := ""
+			if similar {
+				distance = fmt.Sprintf(" | %2d bits", analysis.HashDistance(&group[keep], &img))
+			}
+
+			fmt.Printf("  %s ID: %-4d | %s | %s | %s%s%s%s\n",
+				status,
+				img.ID,
+				img.SourceID,
+				img.Resolution,
+				filepath.Base(img.LocalPath),
+				favorite,
+				distance,
+				keepMarker)
+		}
+
+		if pm != nil && len(previewPaths) > 0 {
+			if err := pm.PreviewSideBySide(previewPaths); err != nil {
+				fmt.Printf("  ⚠️  Preview failed: %v\n", err)
+			}
+		}
+
+		if interactive {
+			choice, err := chooseSurvivor(reader, group, keep)
+			if err != nil {
+				return err
+			}
+			if choice == chooseQuit {
+				fmt.Println("  ⏭️  Skipping the remaining groups")
+				fmt.Println()
+				break
+			}
+			if choice == chooseSkip {
+				fmt.Println("  ⏭️  Keeping every copy")
+				fmt.Println()
+				continue
+			}
+			keep = choice
+			fmt.Printf("  → Keeping ID %d\n", group[keep].ID)
+		}
+		fmt.Println()
+
+		plan := dedupePlan{keep: group[keep]}
+		for j, img := range group {
+			if j != keep && !linkedTo(&img, &group[keep]) {
+				plan.remove = append(plan.remove, img)
+			}
+		}
+		if len(plan.remove) == 0 {
+			continue
+		}
+		plans = append(plans, plan)
+		totalDuplicates += len(plan.remove)
+	}
+
+	if totalDuplicates == 0 {
+		fmt.Println("✅ No duplicates to remove!")
+		return nil
+	}
+
+	if linkMode != "" {
+		return a.linkDuplicates(db, reader, plans, totalDuplicates, linkMode, dryRun)
+	}
+
+	fmt.Printf("Summary: Found %d duplicate files to remove\n", totalDuplicates)
+
+	if dryRun {
+		fmt.Printf("\n🔍 DRY RUN - Would delete %d duplicate files:\n", totalDuplicates)
+		for _, plan := range plans {
+			for _, img := range plan.remove {
+				fmt.Printf("  - ID %d: %s (merged into ID %d)\n", img.ID, filepath.Base(img.LocalPath), plan.keep.ID)
+			}
+		}
+		fmt.Printf("\nRun without --dry-run to actually remove duplicates\n")
+		return nil
+	}
+
+	// Ask for confirmation
+	if permanent {
+		fmt.Printf("\n⚠️  This will permanently delete %d duplicate files from both database and disk.\n", totalDuplicates)
+	} else {
+		fmt.Printf("\n⚠️  This will move %d duplicate files to the trash.\n", totalDuplicates)
+	}
+	fmt.Print("Do you want to continue? [y/N]: ")
+
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("❌ Operation cancelled")
+		return nil
+	}
+
+	// Delete duplicates
+	deleted := 0
+	failed := 0
+
+	blockReason := ""
+	if block {
+		blockReason = kind
+	}
+
+	t := a.openTrash()
+
+	fmt.Printf("\n🗑️  Removing duplicates...\n")
+	merged := 0
+	var freed int64
+	for _, plan := range plans {
+		if err := db.MergeDuplicates(&plan.keep, plan.remove); err != nil {
+			fmt.Printf("  ❌ Failed to merge into ID %d, keeping its duplicates: %v\n", plan.keep.ID, err)
+			failed += len(plan.remove)
+			continue
+		}
+		merged++
+
+		for _, img := range plan.remove {
+			_, statErr := os.Stat(img.LocalPath)
+			shared := sharedWith(db, &img)
+
+			if err := removeImage(db, t, &img, permanent, blockReason); err != nil {
+				fmt.Printf("  ❌ ID %d: %v\n", img.ID, err)
+				failed++
+				continue
+			}
+
+			switch {
+			case os.IsNotExist(statErr):
+				fmt.Printf("  ✅ ID %d: %s (file was already missing)\n", img.ID, filepath.Base(img.LocalPath))
+			case shared != "":
+				fmt.Printf("  ✅ ID %d: %s (data still used by %s)\n", img.ID, filepath.Base(img.LocalPath), shared)
+			default:
+				fmt.Printf("  ✅ ID %d: %s\n", img.ID, filepath.Base(img.LocalPath))
+				freed += img.FileSize
+			}
+			deleted++
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("DEDUPE SUMMARY:\n")
+	fmt.Printf("  Successfully deleted: %d\n", deleted)
+	if failed > 0 {
+		fmt.Printf("  Failed: %d\n", failed)
+	}
+	fmt.Printf("  Tags and favorites merged into: %d wallpapers\n", merged)
+	if freed > 0 {
+		fmt.Printf("  Approximate space freed: %.2f MB\n", float64(freed)/(1024*1024))
+	}
+
+	return nil
+}
+
+// findSimilar groups the library by perceptual hash. Images that have not
+// been hashed yet are hashed and stored first.
+func findSimilar(db *database.DB, threshold int) ([][]database.Image, error) {
+	images, err := db.QueryImages(database.ImageQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	if n := analysis.CountMissing(images); n > 0 {
+		fmt.Printf("🔍 Hashing %d wallpapers (stored for next time)...\n", n)
+		if _, err := analysis.Backfill(db, images); err != nil {
+			return nil, fmt.Errorf("failed to store hashes: %w", err)
+		}
+	}
+
+	return analysis.SimilarGroups(images, threshold), nil
+}
+
+// dedupePlan is the copy kept from a group of duplicates and the copies
+// removed in its favor
+type dedupePlan struct {
+	keep   database.Image
+	remove []database.Image
+}
+
+// linkDuplicates replaces the files of duplicates with links to the copy
+// kept in each plan. The rows stay in the library, recorded as sharing one
+// file's data.
+func (a *App) linkDuplicates(db *database.DB, reader *bufio.Reader, plans []dedupePlan, total int, mode string, dryRun bool) error {
+	fmt.Printf("Summary: Found %d duplicate files to link\n", total)
+
+	if dryRun {
+		fmt.Printf("\n🔍 DRY RUN - Would replace %d duplicate files with links:\n", total)
+		for _, plan := range plans {
+			for _, img := range plan.remove {
+				fmt.Printf("  - ID %d: %s → ID %d: %s\n", img.ID, filepath.Base(img.LocalPath), plan.keep.ID, filepath.Base(plan.keep.LocalPath))
+			}
+		}
+		fmt.Printf("\nRun without --dry-run to actually link duplicates\n")
+		return nil
+	}
+
+	fmt.Printf("\n⚠️  This will replace %d duplicate files with links (%s) to the copies kept.\n", total, mode)
+	fmt.Print("Do you want to continue? [y/N]: ")
+
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("❌ Operation cancelled")
+		return nil
+	}
+
+	linked := 0
+	failed := 0
+	var freed int64
+	modes := make(map[string]int)
+
+	fmt.Printf("\n🔗 Linking duplicates...\n")
+	for _, plan := range plans {
+		// Only link files whose content still matches the recorded checksum
+		if sum, err := a.calculateChecksum(plan.keep.LocalPath); err != nil || sum != plan.keep.Checksum {
+			fmt.Printf("  ❌ ID %d: %s changed or is unreadable, skipping its group\n", plan.keep.ID, filepath.Base(plan.keep.LocalPath))
+			failed += len(plan.remove)
+			continue
+		}
+
+		var done []*database.Image
+		for i := range plan.remove {
+			img := &plan.remove[i]
+
+			var size int64
+			if info, err := os.Stat(img.LocalPath); err == nil {
+				if sum, err := a.calculateChecksum(img.LocalPath); err != nil || sum != img.Checksum {
+					fmt.Printf("  ❌ ID %d: %s changed or is unreadable\n", img.ID, filepath.Base(img.LocalPath))
+					failed++
+					continue
+				}
+				if same, _ := filelink.SameFile(img.LocalPath, plan.keep.LocalPath); !same {
+					size = info.Size()
+				}
+			}
+
+			// A near-duplicate in another format takes the kept file's
+			// extension, so the link's name matches its content
+			oldPath := img.LocalPath
+			linkPath := oldPath
+			if ext := filepath.Ext(plan.keep.LocalPath); !strings.EqualFold(filepath.Ext(oldPath), ext) {
+				linkPath = strings.TrimSuffix(oldPath, filepath.Ext(oldPath)) + ext
+				if pathExists(linkPath) {
+					fmt.Printf("  ❌ ID %d: %s already exists\n", img.ID, filepath.Base(linkPath))
+					failed++
+					continue
+				}
+			}
+
+			used, err := filelink.Replace(linkPath, plan.keep.LocalPath, mode)
+			if err != nil {
+				fmt.Printf("  ❌ ID %d: %v\n", img.ID, err)
+				failed++
+				continue
+			}
+			if linkPath != oldPath {
+				if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
+					fmt.Printf("  ⚠️  ID %d: failed to remove %s: %v\n", img.ID, filepath.Base(oldPath), err)
+				}
+				img.LocalPath = linkPath
+			}
+
+			fmt.Printf("  ✅ ID %d: %s → ID %d (%s)\n", img.ID, filepath.Base(oldPath), plan.keep.ID, used)
+			modes[used]++
+			freed += size
+			done = append(done, img)
+			linked++
+		}
+
+		if len(done) > 0 {
+			if err := db.LinkImages(&plan.keep, done); err != nil {
+				fmt.Printf("  ⚠️  Failed to record links of ID %d: %v\n", plan.keep.ID, err)
+			}
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("DEDUPE SUMMARY:\n")
+	fmt.Printf("  Linked: %d", linked)
+	if linked > 0 {
+		fmt.Printf(" (%d reflinks, %d hardlinks)", modes[filelink.Reflink], modes[filelink.Hardlink])
+	}
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("  Failed: %d\n", failed)
+	}
+	fmt.Printf("  Space freed: %.2f MB\n", float64(freed)/(1024*1024))
+
+	return nil
+}
+
+// dropLinkedGroups removes groups whose images were all linked together
+// already; they are kept on purpose
+func dropLinkedGroups(groups [][]database.Image) [][]database.Image {
+	var kept [][]database.Image
+	for _, group := range groups {
+		for _, img := range group[1:] {
+			if !linkedTo(&img, &group[0]) {
+				kept = append(kept, group)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// linkedTo reports whether two images share a link group
+func linkedTo(a, b *database.Image) bool {
+	return a.LinkGroup != 0 && a.LinkGroup == b.LinkGroup
+}
+
+// Keep policies
+const (
+	keepOldest     = "oldest"
+	keepNewest     = "newest"
+	keepFavorite   = "favorite"
+	keepResolution = "highest-resolution"
+	keepPathPrefix = "path-prefix"
+)
+
+// keepPolicy decides which copy of a group of duplicates survives
+type keepPolicy struct {
+	name string
+	dir  string // for path-prefix
+}
+
+// parseKeepPolicy parses the --keep flag
+func parseKeepPolicy(spec string) (keepPolicy, error) {
+	name, arg, hasArg := strings.Cut(spec, "=")
+	switch name {
+	case keepOldest, keepNewest, keepFavorite, keepResolution:
+		if hasArg {
+			return keepPolicy{}, fmt.Errorf("keep policy %s takes no value", name)
+		}
+		return keepPolicy{name: name}, nil
+	case keepPathPrefix:
+		if arg == "" {
+			return keepPolicy{}, fmt.Errorf("path-prefix needs a directory (e.g. path-prefix=~/Pictures/Wallpapers/curated)")
+		}
+		dir, err := filepath.Abs(config.ExpandPath(arg))
+		if err != nil {
+			return keepPolicy{}, err
+		}
+		return keepPolicy{name: name, dir: dir}, nil
+	default:
+		return keepPolicy{}, fmt.Errorf("invalid keep policy %q (use %s, %s, %s, %s or %s=<dir>)",
+			spec, keepOldest, keepNewest, keepFavorite, keepResolution, keepPathPrefix)
+	}
+}
+
+// choose returns the index of the copy to keep
+func (p keepPolicy) choose(group []database.Image) int {
+	best := 0
+	for i := 1; i < len(group); i++ {
+		if p.better(&group[i], &group[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// better reports whether a should be kept rather than b
+func (p keepPolicy) better(a, b *database.Image) bool {
+	// A copy whose file still exists beats a missing one under any policy
+	if ea, eb := pathExists(a.LocalPath), pathExists(b.LocalPath); ea != eb {
+		return ea
+	}
+
+	switch p.name {
+	case keepNewest:
+		if !a.DownloadedAt.Equal(b.DownloadedAt) {
+			return a.DownloadedAt.After(b.DownloadedAt)
+		}
+	case keepFavorite:
+		if a.Favorite != b.Favorite {
+			return a.Favorite
+		}
+	case keepResolution:
+		if pa, pb := a.Width*a.Height, b.Width*b.Height; pa != pb {
+			return pa > pb
+		}
+	case keepPathPrefix:
+		if ia, ib := isUnder(a.LocalPath, p.dir), isUnder(b.LocalPath, p.dir); ia != ib {
+			return ia
+		}
+	}
+
+	// Ties go to the oldest copy
+	if !a.DownloadedAt.Equal(b.DownloadedAt) {
+		return a.DownloadedAt.Before(b.DownloadedAt)
+	}
+	return a.ID < b.ID
+}
+
+// pathExists reports whether a file exists
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// isUnder reports whether path is inside dir
+func isUnder(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Special answers of chooseSurvivor
+const (
+	chooseSkip = -1
+	chooseQuit = -2
+)
+
+// chooseSurvivor asks which copy of a group to keep. It returns the index
+// of the chosen copy, chooseSkip to keep them all or chooseQuit to stop
+// reviewing groups.
+func chooseSurvivor(reader *bufio.Reader, group []database.Image, suggested int) (int, error) {
+	for {
+		fmt.Printf("  Keep which ID? [Enter = %d, s = skip group, q = stop]: ", group[suggested].ID)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("failed to read input: %w", err)
+		}
+
+		switch answer := strings.ToLower(strings.TrimSpace(input)); answer {
+		case "":
+			return suggested, nil
+		case "s", "skip":
+			return chooseSkip, nil
+		case "q", "quit":
+			return chooseQuit, nil
+		default:
+			if id, err := strconv.Atoi(answer); err == nil {
+				for i, img := range group {
+					if img.ID == id {
+						return i, nil
+					}
+				}
+			}
+			fmt.Printf("  ❌ Enter the ID of one of the images above\n")
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// newHistoryCmd creates the history command
+func (a *App) newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show fetch run history",
+		Long:  "List previous fetch runs with their parameters and results",
+		Args:  cobra.NoArgs,
+		RunE:  a.runHistory,
+	}
+
+	cmd.Flags().IntP("limit", "l", 20, "Number of runs to show (0 for all)")
+	cmd.Flags().Bool("json", false, "Output as JSON")
+
+	// history show
+	showCmd := &cobra.Command{
+		Use:   "show <run-id>",
+		Short: "Show details of a fetch run",
+		Args:  cobra.ExactArgs(1),
+		RunE:  a.runHistoryShow,
+	}
+	showCmd.Flags().Bool("json", false, "Output as JSON")
+
+	// history undo
+	undoCmd := &cobra.Command{
+		Use:   "undo <run-id>",
+		Short: "Remove every wallpaper a fetch run added",
+		Args:  cobra.ExactArgs(1),
+		RunE:  a.runHistoryUndo,
+	}
+	undoCmd.Flags().BoolP("dry-run", "d", false, "Show what would be removed without removing")
+	undoCmd.Flags().BoolP("block", "b", false, "Add removed wallpapers to the blocklist")
+	undoCmd.Flags().Bool("permanent", false, "Delete files permanently instead of moving them to the trash")
+
+	cmd.AddCommand(showCmd)
+	cmd.AddCommand(undoCmd)
+
+	return cmd
+}
+
+// runHistory handles the history command
+func (a *App) runHistory(cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	runs, err := db.ListFetchRuns(limit)
+	if err != nil {
+		return fmt.Errorf("failed to list fetch runs: %w", err)
+	}
+
+	if asJSON {
+		if runs == nil {
+			runs = []database.FetchRun{}
+		}
+		return printJSON(runs)
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No fetch runs recorded yet.")
+		return nil
+	}
+
+	fmt.Printf("%-5s | %-16s | %-10s | %-9s | %5s | %5s | %5s | %s\n",
+		"RUN", "STARTED", "SOURCE", "STATUS", "NEW", "SKIP", "FAIL", "PARAMS")
+	for _, run := range runs {
+		fmt.Printf("%-5d | %-16s | %-10s | %-9s | %5d | %5d | %5d | %s\n",
+			run.ID,
+			run.StartedAt.Local().Format("2006-01-02 15:04"),
+			run.Source,
+			run.Status,
+			run.Downloaded,
+			run.Skipped,
+			run.Failed,
+			formatRunParams(run.Params))
+	}
+
+	return nil
+}
+
+// runHistoryShow handles the history show command
+func (a *App) runHistoryShow(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	runID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid run ID: %s", args[0])
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	run, err := db.GetFetchRun(runID)
+	if err != nil {
+		return fmt.Errorf("failed to find fetch run %d: %w", runID, err)
+	}
+
+	images, err := db.ListFetchRunImages(runID)
+	if err != nil {
+		return fmt.Errorf("failed to list images for run %d: %w", runID, err)
+	}
+
+	if asJSON {
+		if images == nil {
+			images = []database.Image{}
+		}
+		return printJSON(struct {
+			*database.FetchRun
+			Images []database.Image `json:"images"`
+		}{run, images})
+	}
+
+	fmt.Printf("Fetch Run %d\n", run.ID)
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Source: %s\n", run.Source)
+	fmt.Printf("Status: %s\n", run.Status)
+	fmt.Printf("Started: %s\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	if run.FinishedAt != nil {
+		fmt.Printf("Finished: %s (%s)\n", run.FinishedAt.Local().Format("2006-01-02 15:04:05"),
+			run.FinishedAt.Sub(run.StartedAt))
+	}
+	fmt.Printf("Parameters:\n")
+	for _, key := range sortedKeys(run.Params) {
+		if run.Params[key] != "" {
+			fmt.Printf("  %s: %s\n", key, run.Params[key])
+		}
+	}
+	fmt.Printf("Pages processed: %d\n", run.PagesProcessed)
+	fmt.Printf("Downloaded: %d | Skipped: %d | Failed: %d\n", run.Downloaded, run.Skipped, run.Failed)
+
+	if len(run.SkipReasons) > 0 {
+		fmt.Printf("\nSkip reasons:\n")
+		reasons := make([]string, 0, len(run.SkipReasons))
+		for reason := range run.SkipReasons {
+			reasons = append(reasons, reason)
+		}
+		sort.Slice(reasons, func(i, j int) bool {
+			return run.SkipReasons[reasons[i]] > run.SkipReasons[reasons[j]]
+		})
+		for _, reason := range reasons {
+			fmt.Printf("  %4d  %s\n", run.SkipReasons[reason], reason)
+		}
+	}
+
+	if len(run.Errors) > 0 {
+		fmt.Printf("\nErrors:\n")
+		for _, e := range run.Errors {
+			fmt.Printf("  - %s\n", e)
+		}
+	}
+
+	if len(images) > 0 {
+		fmt.Printf("\nAdded wallpapers (%d still tracked):\n", len(images))
+		for _, img := range images {
+			status := "✅"
+			if img.DeletedAt != nil {
+				status = "🗑️ "
+			}
+			fmt.Printf("  %s ID: %-4d | %-8s | %-12s | %s\n",
+				status, img.ID, img.SourceID, img.Resolution, filepath.Base(img.LocalPath))
+		}
+	}
+
+	return nil
+}
+
+// runHistoryUndo handles the history undo command
+func (a *App) runHistoryUndo(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	block, _ := cmd.Flags().GetBool("block")
+	permanent, _ := cmd.Flags().GetBool("permanent")
+
+	runID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid run ID: %s", args[0])
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.GetFetchRun(runID); err != nil {
+		return fmt.Errorf("failed to find fetch run %d: %w", runID, err)
+	}
+
+	images, err := db.ListFetchRunImages(runID)
+	if err != nil {
+		return fmt.Errorf("failed to list images for run %d: %w", runID, err)
+	}
+
+	// Only images still in the library can be removed
+	var toRemove []database.Image
+	for _, img := range images {
+		if img.DeletedAt == nil {
+			toRemove = append(toRemove, img)
+		}
+	}
+
+	if len(toRemove) == 0 {
+		fmt.Printf("Run %d has no wallpapers left in the library.\n", runID)
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("🔍 DRY RUN - Would remove %d wallpapers added by run %d:\n", len(toRemove), runID)
+		for _, img := range toRemove {
+			fmt.Printf("  - ID %d: %s\n", img.ID, filepath.Base(img.LocalPath))
+		}
+		return nil
+	}
+
+	if permanent {
+		fmt.Printf("⚠️  This will permanently delete %d wallpapers added by run %d.\n", len(toRemove), runID)
+	} else {
+		fmt.Printf("⚠️  This will move %d wallpapers added by run %d to the trash.\n", len(toRemove), runID)
+	}
+	fmt.Print("Do you want to continue? [y/N]: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("❌ Operation cancelled")
+		return nil
+	}
+
+	blockReason := ""
+	if block {
+		blockReason = "undone"
+	}
+
+	t := a.openTrash()
+	removed := 0
+	failed := 0
+
+	for _, img := range toRemove {
+		if err := removeImage(db, t, &img, permanent, blockReason); err != nil {
+			fmt.Printf("  ❌ ID %d: %v\n", img.ID, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("  ✅ ID %d: %s\n", img.ID, filepath.Base(img.LocalPath))
+		removed++
+	}
+
+	if failed == 0 {
+		if err := db.SetFetchRunStatus(runID, database.RunStatusUndone); err != nil {
+			return fmt.Errorf("failed to update run status: %w", err)
+		}
+	}
+
+	fmt.Printf("\n✅ Removed %d wallpapers from run %d\n", removed, runID)
+	if failed > 0 {
+		fmt.Printf("⚠️  Failed: %d\n", failed)
+	}
+
+	return nil
+}
+
+// formatRunParams formats the non-empty run parameters on one line
+func formatRunParams(params map[string]string) string {
+	var parts []string
+	for _, key := range sortedKeys(params) {
+		if key == "output" || params[key] == "" {
+			continue
+		}
+		parts = append(parts, key+"="+params[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+// sortedKeys returns the keys of a string map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// printJSON writes a value to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
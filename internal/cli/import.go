@@ -0,0 +1,428 @@
+package cli
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/analysis"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/sourceid"
+	"github.com/AccursedGalaxy/wallfetch/internal/verify"
+	"github.com/AccursedGalaxy/wallfetch/internal/wallhaven"
+	"github.com/spf13/cobra"
+)
+
+// importProgressEvery is how many processed files import reports progress
+// after
+const importProgressEvery = 100
+
+// newImportCmd creates the import command
+func (a *App) newImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import [directory]",
+		Short: "Import existing wallpapers into the database",
+		Long: `Scan a directory for wallpaper files and add them to the database.
+
+Files are hashed and decoded several at a time. The size, modification time
+and inode of every file processed are remembered, so later imports of the
+same directory skip files that have not changed; --rehash reads every file
+again.
+
+Source IDs are recognized in the names other tools save files under:
+wallhaven-<id>, Unsplash photo-<id> and <name>-<id>-unsplash, Reddit
+reddit-<id>, booru __<tags>__<md5> names and names that are the MD5 of the
+file, and the patterns of the import config block. Without --source,
+recognized files are recorded under their source and others as local. With
+--enrich, the tags, URL and category of new wallpapers with a recognized
+Wallhaven ID are looked up from the API.`,
+		RunE: a.runImport,
+	}
+
+	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be imported without actually importing")
+	cmd.Flags().StringP("source", "s", "", "Source name for imported wallpapers (default recognized from the file name, else local)")
+	cmd.Flags().Bool("rehash", false, "Hash every file again instead of skipping unchanged ones")
+	cmd.Flags().Bool("enrich", false, "Look up tags, URL and category of recognized wallpapers from the source API")
+
+	return cmd
+}
+
+// preparedImport is a file prepared for import by a worker
+type preparedImport struct {
+	path  string
+	img   *database.Image
+	match sourceMatch
+	err   error
+}
+
+// runImport handles the import command
+func (a *App) runImport(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	source, _ := cmd.Flags().GetString("source")
+	rehash, _ := cmd.Flags().GetBool("rehash")
+	enrich, _ := cmd.Flags().GetBool("enrich")
+
+	ids, err := sourceid.New(a.config.Import.Patterns)
+	if err != nil {
+		return err
+	}
+
+	// Determine directory to scan
+	scanDir := a.config.DownloadDir
+	if len(args) > 0 {
+		scanDir = args[0]
+	}
+	if abs, err := filepath.Abs(scanDir); err == nil {
+		scanDir = abs
+	}
+
+	fmt.Printf("Scanning directory: %s\n", scanDir)
+
+	// Check if directory exists
+	if _, err := os.Stat(scanDir); os.IsNotExist(err) {
+		return fmt.Errorf("directory does not exist: %s", scanDir)
+	}
+
+	// Open database
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	// Find image files
+	imageFiles, err := a.findImageFiles(scanDir)
+	if err != nil {
+		return fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	if len(imageFiles) == 0 {
+		fmt.Println("No image files found in the directory.")
+		return nil
+	}
+
+	fmt.Printf("Found %d image files\n", len(imageFiles))
+
+	if dryRun {
+		fmt.Println("\n🔍 DRY RUN - Would import the following files:")
+		for _, filePath := range imageFiles {
+			fmt.Printf("  - %s\n", filepath.Base(filePath))
+		}
+		fmt.Printf("\nWould import %d files\n", len(imageFiles))
+		return nil
+	}
+
+	cache := make(map[string]database.FileState)
+	if !rehash {
+		if cache, err = db.FileStates(scanDir); err != nil {
+			return fmt.Errorf("failed to read the import cache: %w", err)
+		}
+	}
+
+	// Files unchanged since they were last processed are skipped without
+	// reading them, as long as their contents are still in the library or
+	// blocked
+	imported := 0
+	skipped := 0
+	unchanged := 0
+	failed := 0
+	states := make(map[string]database.FileState)
+	var queue []string
+	for _, filePath := range imageFiles {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to import %s: %v\n", filepath.Base(filePath), err)
+			failed++
+			continue
+		}
+		state := verify.StateOf(filePath, info)
+		if cached, ok := cache[filePath]; ok && cached.Same(state) && checksumKnown(db, cached.Checksum) {
+			unchanged++
+			continue
+		}
+		states[filePath] = state
+		queue = append(queue, filePath)
+	}
+	if unchanged > 0 {
+		fmt.Printf("Skipping %d files unchanged since the last import\n", unchanged)
+	}
+
+	// Files are hashed and decoded in parallel; the database is only
+	// written from this goroutine, which keeps duplicate checks exact
+	jobs := make(chan string)
+	results := make(chan preparedImport)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filePath := range jobs {
+				img, match, err := a.prepareImport(filePath, source, ids)
+				results <- preparedImport{path: filePath, img: img, match: match, err: err}
+			}
+		}()
+	}
+	go func() {
+		for _, filePath := range queue {
+			jobs <- filePath
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var enricher *importEnricher
+	if enrich {
+		enricher = &importEnricher{wallhaven: wallhaven.NewClient(a.config.GetWallhavenAPIKey())}
+	}
+
+	var processed []database.FileState
+	done := 0
+	for r := range results {
+		done++
+		filename := filepath.Base(r.path)
+
+		if r.err != nil {
+			fmt.Printf("⚠️  Failed to import %s: %v\n", filename, r.err)
+			failed++
+		} else {
+			if enricher != nil && !checksumKnown(db, r.img.Checksum) {
+				if err := enricher.enrich(r.img, r.match); err != nil {
+					fmt.Printf("⚠️  Failed to look up %s: %v\n", filename, err)
+				}
+			}
+			status, err := addImport(db, r.img)
+			switch {
+			case err != nil:
+				fmt.Printf("❌ Failed to import %s: %v\n", filename, err)
+				failed++
+			case status == importAdded:
+				fmt.Printf("✅ Imported: %s\n", filename)
+				imported++
+			default:
+				fmt.Printf("⏭️  Skipped (%s): %s\n", status, filename)
+				skipped++
+			}
+			if err == nil {
+				state := states[r.path]
+				state.Checksum = r.img.Checksum
+				processed = append(processed, state)
+			}
+		}
+
+		if done%importProgressEvery == 0 && done < len(queue) {
+			fmt.Printf("  ... %d/%d files processed\n", done, len(queue))
+		}
+	}
+
+	// Remember what was processed and forget files that are gone
+	if err := db.SaveFileStates(processed); err != nil {
+		fmt.Printf("⚠️  Failed to update the import cache: %v\n", err)
+	}
+	found := make(map[string]bool, len(imageFiles))
+	for _, filePath := range imageFiles {
+		found[filePath] = true
+	}
+	var gone []string
+	for path := range cache {
+		if !found[path] {
+			gone = append(gone, path)
+		}
+	}
+	if err := db.ForgetFileStates(gone); err != nil {
+		fmt.Printf("⚠️  Failed to update the import cache: %v\n", err)
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("IMPORT SUMMARY:\n")
+	fmt.Printf("  Successfully imported: %d\n", imported)
+	fmt.Printf("  Skipped (already exist or blocked): %d\n", skipped)
+	if unchanged > 0 {
+		fmt.Printf("  Unchanged since the last import: %d\n", unchanged)
+	}
+	if failed > 0 {
+		fmt.Printf("  Failed: %d\n", failed)
+	}
+	if enricher != nil {
+		fmt.Printf("  Metadata looked up: %d\n", enricher.enriched)
+		if enricher.failed > 0 {
+			fmt.Printf("  Metadata lookups failed: %d\n", enricher.failed)
+		}
+	}
+	fmt.Printf("  Total files processed: %d\n", len(imageFiles))
+
+	return nil
+}
+
+// checksumKnown reports whether a file with checksum is already in the
+// library or blocked, so importing it again would be skipped
+func checksumKnown(db *database.DB, checksum string) bool {
+	if exists, err := db.ExistsByChecksum(checksum); err == nil && exists {
+		return true
+	}
+	blocked, err := db.IsChecksumBlocked(checksum)
+	return err == nil && blocked
+}
+
+// Outcomes of addImport
+const (
+	importAdded   = "imported"
+	importExists  = "already exists"
+	importBlocked = "blocked"
+)
+
+// sourceMatch is the source and ID recognized in a file name; both are
+// empty when none was
+type sourceMatch struct {
+	source string
+	id     string
+}
+
+// prepareImport reads an image file into a new image record: checksum,
+// size, dimensions and analysis. The source ID is recognized from the file
+// name by ids and returned too; an empty source means the recognized
+// source, or local. It does not use the database, so files can be prepared
+// concurrently.
+func (a *App) prepareImport(filePath, source string, ids *sourceid.Matcher) (*database.Image, sourceMatch, error) {
+	// Paths are stored absolute, or relative to the library root they are in
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+
+	width, height, fileSize, err := a.getImageInfo(filePath)
+	if err != nil {
+		return nil, sourceMatch{}, fmt.Errorf("failed to get info: %w", err)
+	}
+
+	checksum, err := a.calculateChecksum(filePath)
+	if err != nil {
+		return nil, sourceMatch{}, fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+
+	// Source IDs are unique per source, so files whose name carries no ID
+	// are identified by their contents
+	var match sourceMatch
+	sourceID := checksum[:12]
+	if recognized, id, ok := ids.Match(filePath); ok {
+		match = sourceMatch{source: recognized, id: id}
+		sourceID = id
+	}
+	if source == "" {
+		source = "local"
+		if match.source != "" {
+			source = match.source
+		}
+	}
+
+	resolution := "unknown"
+	if width > 0 && height > 0 {
+		resolution = fmt.Sprintf("%dx%d", width, height)
+	}
+	img := &database.Image{
+		Source:       source,
+		SourceID:     sourceID,
+		URL:          "", // Local file, no URL
+		LocalPath:    filePath,
+		Checksum:     checksum,
+		Tags:         "",
+		Resolution:   resolution,
+		FileSize:     fileSize,
+		DownloadedAt: time.Now(),
+		Favorite:     false,
+		Width:        width,
+		Height:       height,
+		FileType:     mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath))),
+	}
+	if result, err := analysis.AnalyzeFile(filePath); err == nil {
+		result.Apply(img)
+	}
+	return img, match, nil
+}
+
+// addImport inserts a prepared image unless a copy of it is already in the
+// library or blocked, and returns which of these happened
+func addImport(db *database.DB, img *database.Image) (string, error) {
+	exists, err := db.ExistsByChecksum(img.Checksum)
+	if err != nil {
+		return "", fmt.Errorf("failed to check existence: %w", err)
+	}
+	if exists {
+		return importExists, nil
+	}
+
+	blocked, err := db.IsChecksumBlocked(img.Checksum)
+	if err != nil {
+		return "", fmt.Errorf("failed to check blocklist: %w", err)
+	}
+	if blocked {
+		return importBlocked, nil
+	}
+
+	// Another copy of the same wallpaper, e.g. at a different resolution
+	exists, err = db.ExistsBySourceID(img.Source, img.SourceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check existence: %w", err)
+	}
+	if exists {
+		return importExists, nil
+	}
+
+	if err := db.InsertImage(img); err != nil {
+		return "", err
+	}
+	return importAdded, nil
+}
+
+// wallhavenInterval spaces out Wallhaven API requests to stay within its
+// limit of 45 requests a minute
+const wallhavenInterval = 1400 * time.Millisecond
+
+// importEnricher fills in the metadata of imported wallpapers from the API
+// of the source recognized from their file name
+type importEnricher struct {
+	wallhaven *wallhaven.Client
+	last      time.Time
+
+	enriched int
+	failed   int
+}
+
+// enrich looks up an image by the source ID recognized in its file name.
+// Images of sources without an API are left as they are.
+func (e *importEnricher) enrich(img *database.Image, match sourceMatch) error {
+	if match.source != "wallhaven" {
+		return nil
+	}
+
+	if wait := wallhavenInterval - time.Since(e.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	e.last = time.Now()
+
+	detail, err := e.wallhaven.GetWallpaper(match.id)
+	if err != nil {
+		e.failed++
+		return err
+	}
+
+	w := detail.Data
+	tags := make([]string, 0, len(w.Tags))
+	for _, tag := range w.Tags {
+		tags = append(tags, tag.Name)
+	}
+	img.Tags = strings.Join(tags, ",")
+	img.URL = w.Path
+	img.PageURL = w.URL
+	img.Category = w.Category
+	img.Purity = w.Purity
+	img.Views = w.Views
+	img.SourceFavorites = w.Favorites
+	e.enriched++
+	return nil
+}
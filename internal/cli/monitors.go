@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+	"github.com/spf13/cobra"
+)
+
+// newMonitorsCmd creates the monitors command
+func (a *App) newMonitorsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "monitors",
+		Short: "List connected monitors",
+		Long:  "List connected monitors as detected with hyprctl, swaymsg, wlr-randr or xrandr",
+		Args:  cobra.NoArgs,
+		RunE:  a.runMonitors,
+	}
+
+	cmd.Flags().Bool("json", false, "Output as JSON")
+
+	return cmd
+}
+
+// runMonitors handles the monitors command
+func (a *App) runMonitors(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	monitors, err := desktop.DetectMonitors(desktop.ExecRunner{}, os.Getenv)
+	if err != nil {
+		return fmt.Errorf("failed to detect monitors: %w", err)
+	}
+
+	if asJSON {
+		return printJSON(monitors)
+	}
+
+	// Show what each monitor currently displays, if known
+	var current map[string]database.WallpaperSet
+	if db, err := a.openDatabase(); err == nil {
+		current, _ = db.CurrentWallpapers()
+		db.Close()
+	}
+
+	fmt.Printf("%d monitors connected:\n\n", len(monitors))
+	for _, m := range monitors {
+		orientation := "landscape"
+		if m.Portrait() {
+			orientation = "portrait"
+		}
+		primary := ""
+		if m.Primary {
+			primary = " (primary)"
+		}
+
+		fmt.Printf("  %-12s %-10s %-9s at %d,%d%s\n", m.Name, m.Resolution(), orientation, m.X, m.Y, primary)
+
+		if s, ok := current[m.Name]; ok {
+			fmt.Printf("  %-12s └─ %s\n", "", s.Path)
+		} else if s, ok := current[""]; ok {
+			fmt.Printf("  %-12s └─ %s\n", "", s.Path)
+		}
+	}
+
+	return nil
+}
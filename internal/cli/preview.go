@@ -2,11 +2,16 @@ package cli
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
 )
 
 // PreviewTool represents available preview tools
@@ -252,6 +257,56 @@ func (pm *PreviewManager) PreviewImage(imagePath string) error {
 	}
 }
 
+// PreviewSideBySide displays several images next to each other, left to
+// right, by previewing a montage of them
+func (pm *PreviewManager) PreviewSideBySide(imagePaths []string) error {
+	if pm.availableTool == PreviewToolNone {
+		return fmt.Errorf("no image preview tool available (install 'chafa' or 'viu')")
+	}
+
+	const tileHeight, gap, maxTiles = 480, 24, 4
+	if len(imagePaths) > maxTiles {
+		imagePaths = imagePaths[:maxTiles]
+	}
+
+	var tiles []image.Image
+	width := 0
+	for _, path := range imagePaths {
+		img, _, err := imaging.Open(path)
+		if err != nil {
+			return err
+		}
+		b := img.Bounds()
+		tile := imaging.Resize(img, max(1, b.Dx()*tileHeight/max(1, b.Dy())), tileHeight)
+		if width > 0 {
+			width += gap
+		}
+		width += tile.Bounds().Dx()
+		tiles = append(tiles, tile)
+	}
+
+	montage := image.NewRGBA(image.Rect(0, 0, width, tileHeight))
+	draw.Draw(montage, montage.Bounds(), image.NewUniform(color.RGBA{R: 0x1e, G: 0x1e, B: 0x1e, A: 0xff}), image.Point{}, draw.Src)
+	x := 0
+	for _, tile := range tiles {
+		r := image.Rect(x, 0, x+tile.Bounds().Dx(), tileHeight)
+		draw.Draw(montage, r, tile, image.Point{}, draw.Src)
+		x = r.Max.X + gap
+	}
+
+	dir, err := os.MkdirTemp("", "wallfetch-preview-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "montage.png")
+	if err := imaging.Save(path, montage); err != nil {
+		return err
+	}
+	return pm.PreviewImage(path)
+}
+
 // previewWithKitty uses Kitty graphics protocol for highest quality
 func (pm *PreviewManager) previewWithKitty(imagePath string) error {
 	// Use chafa with kitty protocol for best quality
@@ -393,8 +448,14 @@ func (pm *PreviewManager) DisplayImageInfo(imagePath string) {
 	pm.tryGetImageDimensions(imagePath)
 }
 
-// tryGetImageDimensions attempts to get image dimensions using available tools
+// tryGetImageDimensions attempts to get image dimensions, from the image
+// header or using available tools
 func (pm *PreviewManager) tryGetImageDimensions(imagePath string) {
+	if width, height, format, err := imaging.Dimensions(imagePath); err == nil {
+		fmt.Printf("🖼️  Dimensions: %dx%d (%s)\n", width, height, format)
+		return
+	}
+
 	// Try using identify from ImageMagick
 	if pm.isCommandAvailable("identify") {
 		cmd := exec.Command("identify", "-format", "%wx%h", imagePath)
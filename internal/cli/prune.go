@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/downloader"
+	"github.com/AccursedGalaxy/wallfetch/internal/retention"
+	"github.com/spf13/cobra"
+)
+
+// defaultPruneKeep is how many wallpapers prune keeps when neither the
+// command line nor the retention config sets a policy
+const defaultPruneKeep = 100
+
+// newPruneCmd creates the prune command
+func (a *App) newPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Prune old wallpapers",
+		Long: `Remove wallpapers according to a retention policy.
+
+Limits (--keep, --max-size, --quota) remove the oldest wallpapers until the
+library fits. Filters (--older-than, --never-shown) restrict which
+wallpapers may be removed; used without limits, every wallpaper matching
+them is removed. Favorites and wallpapers rated --min-rating or higher are
+never removed.
+
+The policy defaults to the retention block of the config file; flags
+override it. Without any policy, the 100 most recent wallpapers are kept.`,
+		Example: `  wallfetch prune --keep 500 --dry-run
+  wallfetch prune --max-size 20GB --min-rating 4
+  wallfetch prune --older-than 90d --never-shown
+  wallfetch prune --quota wallhaven=10GB --quota local=200
+  wallfetch prune --yes  # apply the retention config without asking`,
+		RunE: a.runPrune,
+	}
+
+	cmd.Flags().IntP("keep", "k", 0, "Number of wallpapers to keep")
+	cmd.Flags().String("max-size", "", "Most disk space to keep, e.g. 20GB")
+	cmd.Flags().StringArray("quota", nil, "Per-source limit as source=COUNT or source=SIZE (repeatable)")
+	cmd.Flags().String("older-than", "", "Only remove wallpapers downloaded longer ago, e.g. 90d")
+	cmd.Flags().Bool("never-shown", false, "Only remove wallpapers rotation has never shown")
+	cmd.Flags().Bool("keep-favorites", true, "Never remove favorites")
+	cmd.Flags().Int("min-rating", 0, "Never remove wallpapers rated at least this high (1-5)")
+	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be deleted and why without deleting")
+	cmd.Flags().BoolP("block", "b", false, "Add pruned wallpapers to the blocklist so they are never fetched again")
+	cmd.Flags().Bool("permanent", false, "Delete files permanently instead of moving them to the trash")
+	cmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation, e.g. in scripts")
+
+	return cmd
+}
+
+// prunePolicy builds the retention policy from the config file and the
+// flags given on the command line
+func (a *App) prunePolicy(cmd *cobra.Command) (retention.Policy, error) {
+	policy, err := retention.FromConfig(a.config.Retention)
+	if err != nil {
+		return policy, err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("keep") {
+		policy.Keep, _ = flags.GetInt("keep")
+		if policy.Keep < 0 {
+			return policy, fmt.Errorf("invalid --keep: %d", policy.Keep)
+		}
+	}
+	if flags.Changed("max-size") {
+		value, _ := flags.GetString("max-size")
+		if policy.MaxSize, err = config.ParseSize(value); err != nil {
+			return policy, err
+		}
+	}
+	if flags.Changed("quota") {
+		values, _ := flags.GetStringArray("quota")
+		for _, value := range values {
+			source, limit, ok := strings.Cut(value, "=")
+			if !ok || source == "" {
+				return policy, fmt.Errorf("invalid --quota: %s (use source=COUNT or source=SIZE)", value)
+			}
+			q, err := retention.ParseQuota(limit)
+			if err != nil {
+				return policy, err
+			}
+			policy.SetQuota(source, q)
+		}
+	}
+	if flags.Changed("older-than") {
+		value, _ := flags.GetString("older-than")
+		if policy.OlderThan, err = config.ParseAge(value); err != nil {
+			return policy, err
+		}
+	}
+	if flags.Changed("never-shown") {
+		policy.NeverShown, _ = flags.GetBool("never-shown")
+	}
+	if flags.Changed("keep-favorites") {
+		policy.KeepFavorites, _ = flags.GetBool("keep-favorites")
+	}
+	if flags.Changed("min-rating") {
+		policy.MinRating, _ = flags.GetInt("min-rating")
+		if policy.MinRating < 0 || policy.MinRating > 5 {
+			return policy, fmt.Errorf("invalid --min-rating: %d (use 1-5, or 0 to disable)", policy.MinRating)
+		}
+	}
+
+	if !policy.HasLimits() && !policy.HasFilters() {
+		policy.Keep = defaultPruneKeep
+	}
+	return policy, nil
+}
+
+// runPrune handles the prune command
+func (a *App) runPrune(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	block, _ := cmd.Flags().GetBool("block")
+	permanent, _ := cmd.Flags().GetBool("permanent")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	policy, err := a.prunePolicy(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	images, err := db.QueryImages(database.ImageQuery{})
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+	shown, err := db.ShownImageIDs()
+	if err != nil {
+		return fmt.Errorf("failed to read wallpaper history: %w", err)
+	}
+
+	// Select the images to delete without modifying anything
+	plan := policy.Select(images, shown, time.Now())
+	totalCount := len(images)
+
+	fmt.Printf("Prune policy: %s\n", policy)
+	for _, unmet := range plan.Unmet {
+		fmt.Printf("⚠️  Cannot meet the policy: %s (the rest is protected or filtered out)\n", unmet)
+	}
+
+	if len(plan.Candidates) == 0 {
+		fmt.Printf("✅ Current collection has %d wallpapers\n", totalCount)
+		fmt.Println("No pruning needed!")
+		return nil
+	}
+
+	fmt.Printf("\nCollection Management:\n")
+	fmt.Printf("  Current wallpapers: %d\n", totalCount)
+	fmt.Printf("  Will delete: %d wallpapers (%.2f MB)\n", len(plan.Candidates), megabytes(plan.Freed))
+	fmt.Printf("  Will keep: %d wallpapers\n", totalCount-len(plan.Candidates))
+
+	if dryRun {
+		fmt.Printf("\n🔍 DRY RUN - Would delete %d wallpapers:\n", len(plan.Candidates))
+		for _, c := range plan.Candidates {
+			fmt.Printf("  - ID %d %s (%.2f MB): %s\n", c.Image.ID, filepath.Base(c.Image.LocalPath),
+				megabytes(c.Image.FileSize), c.Reason)
+		}
+		fmt.Printf("\nRun without --dry-run to actually delete these files\n")
+		return nil
+	}
+
+	// Ask for confirmation
+	if !yes {
+		if permanent {
+			fmt.Printf("\n⚠️  This will permanently delete %d wallpapers from both database and disk.\n", len(plan.Candidates))
+		} else {
+			fmt.Printf("\n⚠️  This will move %d wallpapers to the trash.\n", len(plan.Candidates))
+		}
+		fmt.Print("Do you want to continue? [y/N]: ")
+
+		reader := bufio.NewReader(os.Stdin)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("❌ Operation cancelled")
+			return nil
+		}
+	}
+
+	// Perform the pruning
+	fmt.Printf("\n🗑️  Pruning wallpapers...\n")
+
+	blockReason := ""
+	if block {
+		blockReason = "pruned"
+	}
+
+	// Each wallpaper is removed on its own, file and row together, so a
+	// failure leaves it tracked and the rest of the selection unaffected
+	t := a.openTrash()
+	deleted := 0
+	var failed []string
+	var totalSize int64
+
+	for _, c := range plan.Candidates {
+		img := c.Image
+		path := img.LocalPath
+
+		// Get file size before deleting; data still used by linked copies
+		// is not freed
+		var size int64
+		missing := true
+		if fileInfo, err := os.Stat(path); err == nil {
+			size = fileInfo.Size()
+			missing = false
+		}
+		shared := sharedWith(db, &img)
+		if shared != "" {
+			size = 0
+		}
+
+		if err := removeImage(db, t, &img, permanent, blockReason); err != nil {
+			fmt.Printf("  ❌ %s: %v\n", filepath.Base(path), err)
+			failed = append(failed, fmt.Sprintf("ID %d", img.ID))
+			continue
+		}
+
+		switch {
+		case missing:
+			fmt.Printf("  ✅ %s (file was already missing)\n", filepath.Base(path))
+		case shared != "":
+			fmt.Printf("  ✅ %s (data still used by %s)\n", filepath.Base(path), shared)
+		default:
+			fmt.Printf("  ✅ %s\n", filepath.Base(path))
+		}
+		totalSize += size
+		deleted++
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("PRUNE SUMMARY:\n")
+	if permanent {
+		fmt.Printf("  Files deleted: %d\n", deleted)
+	} else {
+		fmt.Printf("  Files moved to trash: %d\n", deleted)
+	}
+	if len(failed) > 0 {
+		fmt.Printf("  Failed deletions: %d (still in the library: %s)\n", len(failed), strings.Join(failed, ", "))
+	}
+	if block {
+		fmt.Printf("  Added to blocklist: %d\n", deleted)
+	}
+	if permanent {
+		fmt.Printf("  Space freed: %.2f MB\n", megabytes(totalSize))
+	} else {
+		fmt.Printf("  Space in trash: %.2f MB (run 'wallfetch trash empty' to free it)\n", megabytes(totalSize))
+	}
+	fmt.Printf("  Remaining wallpapers: %d\n", totalCount-deleted)
+
+	return nil
+}
+
+// applyLibraryBudget makes a fetch keep the library within the limits of
+// the library config block. When they are reached, fetching either stops
+// or evicts the wallpapers the retention policy would prune first.
+func (a *App) applyLibraryBudget(cmd *cobra.Command, db *database.DB, dl *downloader.Downloader) error {
+	onFull := a.config.Library.OnFull
+	if cmd.Flags().Changed("on-full") {
+		onFull, _ = cmd.Flags().GetString("on-full")
+	}
+	if onFull != "stop" && onFull != "evict" {
+		return fmt.Errorf("invalid on-full action: %s (use stop or evict)", onFull)
+	}
+
+	images, err := db.QueryImages(database.ImageQuery{})
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+	budget, err := retention.BudgetFromConfig(a.config.Library, images)
+	if err != nil || budget == nil {
+		return err
+	}
+
+	fmt.Printf("  Library limits: %s (when full: %s)\n", budget, onFull)
+	if onFull == "stop" {
+		dl.SetBudget(budget, nil, nil)
+		return nil
+	}
+
+	policy, err := retention.FromConfig(a.config.Retention)
+	if err != nil {
+		return err
+	}
+	shown, err := db.ShownImageIDs()
+	if err != nil {
+		return fmt.Errorf("failed to read wallpaper history: %w", err)
+	}
+
+	// Evicted files are deleted permanently: moving them to the trash would
+	// free no disk space, so the limits would not bound the library
+	t := a.openTrash()
+	dl.SetBudget(budget, policy.Evictable(images, shown, time.Now()), func(img *database.Image) error {
+		return removeImage(db, t, img, true, "")
+	})
+	return nil
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// newRelocateCmd creates the relocate command
+func (a *App) newRelocateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "relocate <old-prefix> <new-prefix>",
+		Short: "Update paths after moving wallpapers",
+		Long: `Point every wallpaper stored under old-prefix at the same relative path under
+new-prefix, e.g. after moving the wallpaper folder or mounting a drive
+elsewhere. Favorites, tags, ratings and history are kept. Files are not
+moved; move them first.`,
+		Example: `  wallfetch relocate ~/Pictures/Wallpapers /mnt/nas/wallpapers --dry-run
+  wallfetch relocate /media/old-drive/walls /media/new-drive/walls`,
+		Args: cobra.ExactArgs(2),
+		RunE: a.runRelocate,
+	}
+
+	cmd.Flags().BoolP("dry-run", "d", false, "Show what would be relocated without changing anything")
+
+	return cmd
+}
+
+// runRelocate handles the relocate command
+func (a *App) runRelocate(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	oldPrefix, err := relocatePrefix(args[0])
+	if err != nil {
+		return err
+	}
+	newPrefix, err := relocatePrefix(args[1])
+	if err != nil {
+		return err
+	}
+	if oldPrefix == newPrefix {
+		return fmt.Errorf("old and new prefix are the same: %s", oldPrefix)
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	images, err := db.QueryImages(database.ImageQuery{PathPrefix: oldPrefix})
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+
+	// Check the files are where the new paths will point before changing
+	// anything, so a typo in the new prefix is noticed
+	found := 0
+	var notFound []string
+	for _, img := range images {
+		path := newPrefix + strings.TrimPrefix(img.LocalPath, oldPrefix)
+		if _, err := os.Stat(path); err == nil {
+			found++
+		} else {
+			notFound = append(notFound, path)
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("🔍 DRY RUN - Would relocate %d wallpapers from %s to %s\n", len(images), oldPrefix, newPrefix)
+		fmt.Printf("  Files found at the new location: %d\n", found)
+		for _, path := range notFound {
+			fmt.Printf("  ⚠️  Not found: %s\n", path)
+		}
+		fmt.Printf("\nRun without --dry-run to actually relocate\n")
+		return nil
+	}
+
+	moved, err := db.RelocatePaths(oldPrefix, newPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to relocate wallpapers: %w", err)
+	}
+	if moved == 0 {
+		fmt.Printf("No wallpapers stored under %s\n", oldPrefix)
+		return nil
+	}
+
+	fmt.Printf("✅ Relocated %d wallpapers from %s to %s\n", moved, oldPrefix, newPrefix)
+	if len(notFound) > 0 {
+		fmt.Printf("⚠️  %d files were not found at the new location; run 'wallfetch cleanup --dry-run' to check them\n", len(notFound))
+	}
+	return nil
+}
+
+// relocatePrefix normalizes a path prefix given to relocate to the form
+// paths are stored in
+func relocatePrefix(prefix string) (string, error) {
+	if prefix == "" {
+		return "", fmt.Errorf("empty path prefix")
+	}
+	abs, err := filepath.Abs(config.ExpandPath(prefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid path prefix %s: %w", prefix, err)
+	}
+	if abs == "/" {
+		return "", fmt.Errorf("cannot relocate the root directory")
+	}
+	return abs, nil
+}
@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+	"github.com/AccursedGalaxy/wallfetch/internal/rotation"
+	"github.com/spf13/cobra"
+)
+
+// rotationOverrides holds rotate flags that take precedence over the config
+type rotationOverrides struct {
+	pool        string
+	schedule    string
+	backend     string
+	monitorMode string
+	avoidRecent int // negative when not set
+}
+
+// newRotateCmd creates the rotate command
+func (a *App) newRotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Cycle wallpapers on a schedule",
+		Long: `Cycle wallpapers from a pool in the foreground. Flags override the rotation
+settings in the config file, which is reloaded automatically when it changes.
+
+Pools: all, favorites, tag:<tag>[,<tag>...], collection:<dir>
+
+Control the running rotation with 'wallfetch next', 'prev', 'pause' and 'resume'.`,
+		Example: `  wallfetch rotate --every 30m
+  wallfetch rotate --pool favorites --schedule "08:00,13:00,20:00"
+  wallfetch rotate --pool tag:nature --schedule "0 */2 * * *"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			every, _ := cmd.Flags().GetString("every")
+			schedule, _ := cmd.Flags().GetString("schedule")
+			if every != "" && schedule != "" {
+				return fmt.Errorf("--every and --schedule cannot be used together")
+			}
+			if every != "" {
+				if _, err := time.ParseDuration(every); err != nil {
+					return fmt.Errorf("invalid interval: %s (use e.g. 30m, 2h)", every)
+				}
+				schedule = every
+			}
+
+			overrides := rotationOverrides{schedule: schedule, avoidRecent: -1}
+			overrides.pool, _ = cmd.Flags().GetString("pool")
+			overrides.backend, _ = cmd.Flags().GetString("backend")
+			overrides.monitorMode, _ = cmd.Flags().GetString("monitor-mode")
+			if cmd.Flags().Changed("avoid-recent") {
+				overrides.avoidRecent, _ = cmd.Flags().GetInt("avoid-recent")
+			}
+
+			return a.runRotation(overrides)
+		},
+	}
+
+	cmd.Flags().StringP("every", "e", "", "Rotation interval (e.g., 30m, 2h)")
+	cmd.Flags().StringP("schedule", "s", "", "Cron expression or times of day (e.g., \"*/30 * * * *\", \"08:00,20:00\")")
+	cmd.Flags().StringP("pool", "p", "", "Wallpaper pool (all, favorites, tag:<tag>, collection:<dir>)")
+	cmd.Flags().Int("avoid-recent", 0, "Number of recently shown wallpapers not to repeat")
+	cmd.Flags().StringP("backend", "b", "", "Wallpaper backend to use (overrides config)")
+	cmd.Flags().String("monitor-mode", "", "same, per-monitor or span (overrides config)")
+
+	return cmd
+}
+
+// newDaemonCmd creates the daemon command
+func (a *App) newDaemonCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run the wallpaper rotation daemon",
+		Long: `Run the wallpaper rotation daemon using the rotation settings from the config
+file. Start it from your session autostart (e.g., exec-once in Hyprland, exec in
+sway, or a systemd user service). The config file is reloaded when it changes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runRotation(rotationOverrides{avoidRecent: -1})
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the running daemon",
+		Args:  cobra.NoArgs,
+		RunE:  runDaemonCommand(rotation.CmdStatus),
+	}
+
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the running daemon",
+		Args:  cobra.NoArgs,
+		RunE:  runDaemonCommand(rotation.CmdStop),
+	}
+
+	cmd.AddCommand(statusCmd)
+	cmd.AddCommand(stopCmd)
+
+	return cmd
+}
+
+// newControlCmds creates the next, prev, pause and resume commands
+func (a *App) newControlCmds() []*cobra.Command {
+	commands := []struct {
+		name  string
+		short string
+	}{
+		{rotation.CmdNext, "Switch to the next wallpaper in the running rotation"},
+		{rotation.CmdPrev, "Switch back to the previous wallpaper in the running rotation"},
+		{rotation.CmdPause, "Pause the running rotation"},
+		{rotation.CmdResume, "Resume a paused rotation"},
+	}
+
+	var cmds []*cobra.Command
+	for _, c := range commands {
+		cmds = append(cmds, &cobra.Command{
+			Use:   c.name,
+			Short: c.short,
+			Args:  cobra.NoArgs,
+			RunE:  runDaemonCommand(c.name),
+		})
+	}
+	return cmds
+}
+
+// runDaemonCommand returns a handler sending a control command to the daemon
+func runDaemonCommand(command string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		reply, err := rotation.Send(command)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s\n", reply)
+		return nil
+	}
+}
+
+// runRotation runs the rotation daemon in the foreground
+func (a *App) runRotation(overrides rotationOverrides) error {
+	opts, err := rotationOptions(a.config, overrides)
+	if err != nil {
+		return err
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	opts.OnApply = themeHook(a.config, db)
+
+	daemon := &rotation.Daemon{
+		Rotator:    rotation.NewRotator(db, opts),
+		ConfigPath: config.Path(),
+		Reload: func() (rotation.Options, error) {
+			cfg, err := config.Load()
+			if err != nil {
+				return rotation.Options{}, err
+			}
+			opts, err := rotationOptions(cfg, overrides)
+			if err != nil {
+				return rotation.Options{}, err
+			}
+			opts.OnApply = themeHook(cfg, db)
+			return opts, nil
+		},
+		DetectMonitors: func() ([]desktop.Monitor, error) {
+			return desktop.DetectMonitors(desktop.ExecRunner{}, os.Getenv)
+		},
+		Logf: func(format string, args ...interface{}) {
+			fmt.Printf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("🔄 Rotating wallpapers from pool %s, %s, using %s\n", opts.Pool, opts.Schedule, opts.Backend.Name())
+	warnMonitorMode(opts)
+	return daemon.Run(ctx)
+}
+
+// themeHook returns a rotation hook writing theme files for each new
+// wallpaper, or nil if theme.on_set is off
+func themeHook(cfg *config.Config, db *database.DB) func(database.Image) {
+	if !cfg.Theme.OnSet {
+		return nil
+	}
+	return func(img database.Image) {
+		if err := exportTheme(cfg, db, &img, img.LocalPath); err != nil {
+			fmt.Printf("⚠️  Failed to write theme: %v\n", err)
+		}
+	}
+}
+
+// rotationOptions builds rotation options from the config and overrides
+func rotationOptions(cfg *config.Config, overrides rotationOverrides) (rotation.Options, error) {
+	poolSpec := cfg.Rotation.Pool
+	if overrides.pool != "" {
+		poolSpec = overrides.pool
+	}
+	pool, err := rotation.ParsePool(poolSpec)
+	if err != nil {
+		return rotation.Options{}, err
+	}
+
+	scheduleSpec := cfg.Rotation.Schedule
+	if overrides.schedule != "" {
+		scheduleSpec = overrides.schedule
+	}
+	schedule, err := rotation.ParseSchedule(scheduleSpec)
+	if err != nil {
+		return rotation.Options{}, err
+	}
+
+	avoidRecent := cfg.Rotation.AvoidRecent
+	if overrides.avoidRecent >= 0 {
+		avoidRecent = overrides.avoidRecent
+	}
+
+	backendName := cfg.Desktop.Backend
+	if overrides.backend != "" {
+		backendName = overrides.backend
+	}
+	backend, err := desktop.Get(backendName, desktop.ExecRunner{}, os.Getenv)
+	if err != nil {
+		return rotation.Options{}, fmt.Errorf("failed to select wallpaper backend: %w", err)
+	}
+
+	monitorMode := cfg.Desktop.MonitorMode
+	if overrides.monitorMode != "" {
+		monitorMode = overrides.monitorMode
+	}
+	if err := validateMonitorMode(monitorMode); err != nil {
+		return rotation.Options{}, err
+	}
+
+	daylight, err := rotation.ParseDaylight(cfg.Rotation.TimeOfDay)
+	if err != nil {
+		return rotation.Options{}, err
+	}
+
+	// Monitors are only needed to fit wallpapers; carry on without them
+	monitors, _ := desktop.DetectMonitors(desktop.ExecRunner{}, os.Getenv)
+
+	return rotation.Options{
+		Pool:        pool,
+		Schedule:    schedule,
+		AvoidRecent: avoidRecent,
+		Backend:     backend,
+		Monitors:    monitors,
+		PerMonitor:  monitorMode == monitorModePerMonitor,
+		Span:        spanSplitter(cfg, monitorMode),
+		Daylight:    daylight,
+	}, nil
+}
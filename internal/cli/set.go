@@ -0,0 +1,484 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/analysis"
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+	"github.com/AccursedGalaxy/wallfetch/internal/rotation"
+	"github.com/AccursedGalaxy/wallfetch/internal/span"
+	"github.com/spf13/cobra"
+)
+
+// Monitor modes
+const (
+	monitorModeSame       = "same"
+	monitorModePerMonitor = "per-monitor"
+	monitorModeSpan       = "span"
+)
+
+// newSetCmd creates the set command
+func (a *App) newSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set [id|path]",
+		Short: "Set a wallpaper on the desktop",
+		Long: `Apply a wallpaper from the library (by ID) or any image file (by path) to the desktop.
+
+The backend is detected from XDG_CURRENT_DESKTOP and WAYLAND_DISPLAY unless
+desktop.backend is set in the config or --backend is given. Supported backends:
+gnome, kde, xfce, hyprpaper, swww, swaybg, feh and xwallpaper.
+
+With --monitor-mode per-monitor, --random and --favorite pick a wallpaper that
+fits each monitor's resolution and orientation. With --monitor-mode span, one
+wide wallpaper is cut into slices along the monitor layout, skipping
+desktop.bezel pixels between screens. --monitor sets one output only.
+
+Without arguments, shows the current wallpaper and recent history.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: a.runSet,
+	}
+
+	cmd.Flags().BoolP("random", "r", false, "Set a random wallpaper from the library")
+	cmd.Flags().BoolP("favorite", "f", false, "Set a random favorite wallpaper")
+	cmd.Flags().StringP("backend", "b", "", "Wallpaper backend to use (overrides config)")
+	cmd.Flags().StringP("monitor", "m", "", "Only set the wallpaper of this monitor (see 'wallfetch monitors')")
+	cmd.Flags().String("monitor-mode", "", "same, per-monitor or span (overrides config)")
+	cmd.Flags().IntP("limit", "l", 10, "Number of history entries to show")
+
+	return cmd
+}
+
+// runSet handles the set command
+func (a *App) runSet(cmd *cobra.Command, args []string) error {
+	random, _ := cmd.Flags().GetBool("random")
+	favorite, _ := cmd.Flags().GetBool("favorite")
+	backendName, _ := cmd.Flags().GetString("backend")
+	monitorName, _ := cmd.Flags().GetString("monitor")
+	monitorMode, _ := cmd.Flags().GetString("monitor-mode")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	if len(args) > 0 && (random || favorite) {
+		return fmt.Errorf("cannot combine a wallpaper argument with --random or --favorite")
+	}
+	if monitorMode == "" {
+		monitorMode = a.config.Desktop.MonitorMode
+	}
+	if err := validateMonitorMode(monitorMode); err != nil {
+		return err
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if len(args) == 0 && !random && !favorite {
+		return showWallpaperHistory(db, limit)
+	}
+
+	backend, err := a.desktopBackend(backendName)
+	if err != nil {
+		return err
+	}
+
+	daylight, err := rotation.ParseDaylight(a.config.Rotation.TimeOfDay)
+	if err != nil {
+		return err
+	}
+
+	pool := rotation.Pool{Kind: rotation.PoolAll}
+	if favorite {
+		pool.Kind = rotation.PoolFavorites
+	}
+
+	// A single monitor
+	if monitorName != "" {
+		monitors, err := desktop.DetectMonitors(desktop.ExecRunner{}, os.Getenv)
+		if err != nil {
+			return fmt.Errorf("failed to detect monitors: %w", err)
+		}
+		target, err := findMonitor(monitors, monitorName)
+		if err != nil {
+			return err
+		}
+
+		var img *database.Image
+		var path string
+		if random || favorite {
+			img, err = a.pickForMonitor(db, pool, target, daylight)
+			if err != nil {
+				return err
+			}
+			path = img.LocalPath
+		} else {
+			img, path, err = resolveWallpaper(db, args[0])
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := applyToMonitor(db, backend, monitors, target, img, path); err != nil {
+			return err
+		}
+		printWallpaperSet(img, path, backend.Name()+", "+target.Name)
+		a.writeTheme(db, img, path)
+		return nil
+	}
+
+	// Every monitor
+	if random || favorite {
+		opts := rotation.Options{
+			Pool:        pool,
+			AvoidRecent: a.config.Rotation.AvoidRecent,
+			Backend:     backend,
+			PerMonitor:  monitorMode == monitorModePerMonitor,
+			Span:        spanSplitter(a.config, monitorMode),
+			Daylight:    daylight,
+		}
+		// Monitors are only needed to fit wallpapers; carry on without them
+		opts.Monitors, _ = desktop.DetectMonitors(desktop.ExecRunner{}, os.Getenv)
+		warnMonitorMode(opts)
+
+		images, err := rotation.NewRotator(db, opts).Next()
+		if err != nil {
+			return err
+		}
+		for _, img := range images {
+			printWallpaperSet(&img, img.LocalPath, backend.Name())
+		}
+		a.writeTheme(db, &images[0], images[0].LocalPath)
+		return nil
+	}
+
+	img, path, err := resolveWallpaper(db, args[0])
+	if err != nil {
+		return err
+	}
+
+	if splitter := spanSplitter(a.config, monitorMode); splitter != nil {
+		monitors, err := desktop.DetectMonitors(desktop.ExecRunner{}, os.Getenv)
+		if err != nil {
+			return fmt.Errorf("failed to detect monitors: %w", err)
+		}
+		if len(monitors) > 1 {
+			if err := applySpan(db, backend, splitter, monitors, img, path); err != nil {
+				return err
+			}
+			printWallpaperSet(img, path, fmt.Sprintf("%s, spanning %d monitors", backend.Name(), len(monitors)))
+			a.writeTheme(db, img, path)
+			return nil
+		}
+		fmt.Printf("⚠️  Fewer than two monitors detected; using one wallpaper for all monitors\n")
+	}
+
+	if err := applyWallpaper(db, backend, img, path); err != nil {
+		return err
+	}
+	printWallpaperSet(img, path, backend.Name())
+	a.writeTheme(db, img, path)
+
+	return nil
+}
+
+// printWallpaperSet reports an applied wallpaper
+func printWallpaperSet(img *database.Image, path, where string) {
+	if img != nil {
+		fmt.Printf("✅ Set wallpaper ID %d: %s (%s)\n", img.ID, filepath.Base(path), where)
+	} else {
+		fmt.Printf("✅ Set wallpaper: %s (%s)\n", path, where)
+	}
+}
+
+// writeTheme writes theme files for a newly set wallpaper when theme.on_set
+// is enabled. Failures are reported but do not undo the wallpaper change.
+func (a *App) writeTheme(db *database.DB, img *database.Image, path string) {
+	if !a.config.Theme.OnSet {
+		return
+	}
+	if err := exportTheme(a.config, db, img, path); err != nil {
+		fmt.Printf("⚠️  Failed to write theme: %v\n", err)
+		return
+	}
+	fmt.Printf("🎨 Wrote theme files to %s\n", a.config.Theme.Dir)
+}
+
+// showWallpaperHistory prints the current wallpaper and recent history
+func showWallpaperHistory(db *database.DB, limit int) error {
+	history, err := db.ListWallpaperHistory(limit)
+	if err != nil {
+		return fmt.Errorf("failed to list wallpaper history: %w", err)
+	}
+
+	if len(history) == 0 {
+		fmt.Println("No wallpaper has been set yet. Use 'wallfetch set <id|path>' or 'wallfetch set --random'.")
+		return nil
+	}
+
+	current, err := db.CurrentWallpapers()
+	if err != nil {
+		return fmt.Errorf("failed to read current wallpapers: %w", err)
+	}
+
+	if len(current) == 1 {
+		for _, s := range current {
+			fmt.Printf("Current wallpaper: %s\n\n", s.Path)
+		}
+	} else {
+		monitors := make([]string, 0, len(current))
+		for monitor := range current {
+			monitors = append(monitors, monitor)
+		}
+		sort.Strings(monitors)
+
+		fmt.Printf("Current wallpapers:\n")
+		for _, monitor := range monitors {
+			label := monitor
+			if label == "" {
+				label = "others"
+			}
+			fmt.Printf("  %-12s %s\n", label, current[monitor].Path)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Recent wallpapers:\n")
+	for _, s := range history {
+		id := "-"
+		if s.ImageID > 0 {
+			id = strconv.Itoa(s.ImageID)
+		}
+		monitor := s.Monitor
+		if monitor == "" {
+			monitor = "all"
+		}
+		fmt.Printf("  %s | ID: %-4s | %-10s | %-10s | %s\n",
+			s.SetAt.Local().Format("2006-01-02 15:04"), id, s.Backend, monitor, filepath.Base(s.Path))
+	}
+
+	return nil
+}
+
+// desktopBackend returns the named backend, falling back to the configured one
+func (a *App) desktopBackend(name string) (desktop.Backend, error) {
+	if name == "" {
+		name = a.config.Desktop.Backend
+	}
+
+	backend, err := desktop.Get(name, desktop.ExecRunner{}, os.Getenv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select wallpaper backend: %w", err)
+	}
+	return backend, nil
+}
+
+// validateMonitorMode checks a monitor mode value
+func validateMonitorMode(mode string) error {
+	switch mode {
+	case "", monitorModeSame, monitorModePerMonitor, monitorModeSpan:
+		return nil
+	default:
+		return fmt.Errorf("invalid monitor mode: %s (valid: %s, %s, %s)", mode, monitorModeSame, monitorModePerMonitor, monitorModeSpan)
+	}
+}
+
+// spanSplitter returns the splitter for span mode, or nil for other modes
+func spanSplitter(cfg *config.Config, mode string) *span.Splitter {
+	if mode != monitorModeSpan {
+		return nil
+	}
+	return &span.Splitter{CacheDir: span.DefaultCacheDir(), Bezel: cfg.Desktop.Bezel}
+}
+
+// warnMonitorMode explains when the monitor mode cannot be honoured and
+// one wallpaper is shown on every monitor instead
+func warnMonitorMode(opts rotation.Options) {
+	if !opts.PerMonitor && opts.Span == nil {
+		return
+	}
+	switch {
+	case !desktop.SupportsPerMonitor(opts.Backend):
+		fmt.Printf("⚠️  Backend %s cannot set wallpapers per monitor; using one wallpaper for all monitors\n", opts.Backend.Name())
+	case len(opts.Monitors) < 2:
+		fmt.Printf("⚠️  Fewer than two monitors detected; using one wallpaper for all monitors\n")
+	}
+}
+
+// findMonitor looks up a monitor by name
+func findMonitor(monitors []desktop.Monitor, name string) (desktop.Monitor, error) {
+	var names []string
+	for _, m := range monitors {
+		if m.Name == name {
+			return m, nil
+		}
+		names = append(names, m.Name)
+	}
+	return desktop.Monitor{}, fmt.Errorf("unknown monitor: %s (connected: %s)", name, strings.Join(names, ", "))
+}
+
+// pickForMonitor picks a random pool wallpaper that fits a monitor,
+// preferring ones that have not been shown recently and, with daylight
+// set, ones whose brightness suits the time of day
+func (a *App) pickForMonitor(db *database.DB, pool rotation.Pool, m desktop.Monitor, daylight rotation.Daylight) (*database.Image, error) {
+	candidates, err := db.QueryImages(pool.Query())
+	if err != nil {
+		return nil, fmt.Errorf("failed to pick a wallpaper: %w", err)
+	}
+
+	if daylight != nil {
+		if _, err := analysis.Backfill(db, candidates); err != nil {
+			return nil, fmt.Errorf("failed to store wallpaper brightness: %w", err)
+		}
+		candidates = rotation.MatchTimeOfDay(candidates, daylight, time.Now())
+	}
+
+	recent := make(map[int]bool)
+	ids, err := db.RecentWallpaperImageIDs(a.config.Rotation.AvoidRecent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallpaper history: %w", err)
+	}
+	for _, id := range ids {
+		recent[id] = true
+	}
+
+	img := rotation.PickForMonitor(candidates, m, func(img *database.Image) bool { return recent[img.ID] })
+	if img == nil {
+		img = rotation.PickForMonitor(candidates, m, nil)
+	}
+	if img == nil {
+		return nil, fmt.Errorf("no wallpapers available in pool %s", pool)
+	}
+	return img, nil
+}
+
+// resolveWallpaper resolves a set argument to a library image and/or file
+// path. Numeric arguments are treated as IDs unless a file by that name
+// exists; files outside the library are returned with a nil image.
+func resolveWallpaper(db *database.DB, arg string) (*database.Image, string, error) {
+	if _, statErr := os.Stat(arg); statErr != nil {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, "", fmt.Errorf("no such file: %s", arg)
+		}
+
+		img, err := db.GetImageByID(id)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to find wallpaper %d: %w", id, err)
+		}
+		if img.DeletedAt != nil {
+			return nil, "", fmt.Errorf("wallpaper %d is in the trash", id)
+		}
+		return img, img.LocalPath, nil
+	}
+
+	path, err := filepath.Abs(arg)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	img, err := db.GetImageByPath(path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, path, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look up %s: %w", path, err)
+	}
+	return img, path, nil
+}
+
+// applyWallpaper sets a wallpaper on every monitor with the given backend
+// and records it. img may be nil for files that are not in the library.
+func applyWallpaper(db *database.DB, backend desktop.Backend, img *database.Image, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("wallpaper file not found: %s", path)
+	}
+
+	if err := backend.Set(path); err != nil {
+		return fmt.Errorf("failed to set wallpaper with %s: %w", backend.Name(), err)
+	}
+
+	if err := db.RecordWallpaperSet(imageID(img), path, backend.Name(), ""); err != nil {
+		return fmt.Errorf("failed to record wallpaper: %w", err)
+	}
+
+	return nil
+}
+
+// applyToMonitor sets the wallpaper of one monitor. Other monitors keep
+// their recorded wallpapers, since some backends redraw every output.
+func applyToMonitor(db *database.DB, backend desktop.Backend, monitors []desktop.Monitor, target desktop.Monitor, img *database.Image, path string) error {
+	if !desktop.SupportsPerMonitor(backend) {
+		return fmt.Errorf("backend %s cannot set wallpapers per monitor", backend.Name())
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("wallpaper file not found: %s", path)
+	}
+
+	current, err := db.CurrentWallpapers()
+	if err != nil {
+		return fmt.Errorf("failed to read current wallpapers: %w", err)
+	}
+
+	var assignments []desktop.Assignment
+	for _, m := range monitors {
+		switch {
+		case m.Name == target.Name:
+			assignments = append(assignments, desktop.Assignment{Monitor: m, Path: path})
+		case current[m.Name].Path != "":
+			assignments = append(assignments, desktop.Assignment{Monitor: m, Path: current[m.Name].Path})
+		case current[""].Path != "":
+			assignments = append(assignments, desktop.Assignment{Monitor: m, Path: current[""].Path})
+		}
+	}
+
+	if err := desktop.Apply(backend, assignments); err != nil {
+		return fmt.Errorf("failed to set wallpaper with %s: %w", backend.Name(), err)
+	}
+
+	if err := db.RecordWallpaperSet(imageID(img), path, backend.Name(), target.Name); err != nil {
+		return fmt.Errorf("failed to record wallpaper: %w", err)
+	}
+
+	return nil
+}
+
+// applySpan splits a wallpaper across the monitors and records the slice
+// shown on each one
+func applySpan(db *database.DB, backend desktop.Backend, splitter *span.Splitter, monitors []desktop.Monitor, img *database.Image, path string) error {
+	if !desktop.SupportsPerMonitor(backend) {
+		return fmt.Errorf("backend %s cannot set wallpapers per monitor", backend.Name())
+	}
+
+	assignments, err := splitter.Split(path, monitors)
+	if err != nil {
+		return fmt.Errorf("failed to split wallpaper: %w", err)
+	}
+	if err := desktop.Apply(backend, assignments); err != nil {
+		return fmt.Errorf("failed to set wallpaper with %s: %w", backend.Name(), err)
+	}
+
+	for _, a := range assignments {
+		if err := db.RecordWallpaperSet(imageID(img), a.Path, backend.Name(), a.Monitor.Name); err != nil {
+			return fmt.Errorf("failed to record wallpaper: %w", err)
+		}
+	}
+	return nil
+}
+
+// imageID returns the library ID of img, or 0 for files outside the library
+func imageID(img *database.Image) int {
+	if img == nil {
+		return 0
+	}
+	return img.ID
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// newStatsCmd creates the stats command
+func (a *App) newStatsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show library statistics",
+		Long:  "Show counts and disk usage of your wallpaper library by source, category, purity, resolution and aspect ratio",
+		Args:  cobra.NoArgs,
+		RunE:  a.runStats,
+	}
+
+	cmd.Flags().Bool("json", false, "Output as JSON")
+	cmd.Flags().Bool("csv", false, "Output as CSV")
+	cmd.Flags().IntP("top", "t", 10, "Number of top tags and largest files to show")
+	cmd.Flags().IntP("weeks", "w", 12, "Number of weeks of download history to show")
+
+	return cmd
+}
+
+// runStats handles the stats command
+func (a *App) runStats(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	asCSV, _ := cmd.Flags().GetBool("csv")
+	top, _ := cmd.Flags().GetInt("top")
+	weeks, _ := cmd.Flags().GetInt("weeks")
+
+	if asJSON && asCSV {
+		return fmt.Errorf("--json and --csv cannot be used together")
+	}
+	if top < 1 {
+		return fmt.Errorf("invalid top: %d (must be at least 1)", top)
+	}
+	if weeks < 1 {
+		return fmt.Errorf("invalid weeks: %d (must be at least 1)", weeks)
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	stats, err := db.GetStats(top, weeks)
+	if err != nil {
+		return fmt.Errorf("failed to compute statistics: %w", err)
+	}
+
+	switch {
+	case asJSON:
+		return printJSON(stats)
+	case asCSV:
+		return writeStatsCSV(stats)
+	}
+
+	fmt.Printf("Library Statistics\n")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("  Wallpapers: %d (%.2f MB)\n", stats.TotalImages, megabytes(stats.TotalSize))
+	fmt.Printf("  Favorites: %d (%.1f%%)\n", stats.Favorites, stats.FavoritesRatio*100)
+	if stats.Missing > 0 {
+		fmt.Printf("  Missing on disk: %d ❌ (run 'wallfetch cleanup')\n", stats.Missing)
+	} else {
+		fmt.Printf("  Missing on disk: 0\n")
+	}
+	if stats.Unavailable > 0 {
+		fmt.Printf("  Unavailable: %d ⏸️ (library root offline or not configured)\n", stats.Unavailable)
+	}
+	if stats.Trashed > 0 {
+		fmt.Printf("  In trash: %d (%.2f MB)\n", stats.Trashed, megabytes(stats.TrashedSize))
+	}
+
+	printStatBuckets("By Source", stats.BySource, stats.TotalImages)
+	printStatBuckets("By Category", stats.ByCategory, stats.TotalImages)
+	printStatBuckets("By Purity", stats.ByPurity, stats.TotalImages)
+	printStatBuckets("By Resolution", stats.ByResolution, stats.TotalImages)
+	printStatBuckets("By Aspect Ratio", stats.ByAspectRatio, stats.TotalImages)
+	printStatBuckets("Downloads per Week", stats.DownloadsPerWeek, stats.TotalImages)
+
+	if len(stats.TopTags) > 0 {
+		fmt.Printf("\nTop Tags:\n")
+		for _, b := range stats.TopTags {
+			fmt.Printf("  %-30s %6d\n", b.Key, b.Count)
+		}
+	}
+
+	if len(stats.Largest) > 0 {
+		fmt.Printf("\nLargest Files:\n")
+		for _, img := range stats.Largest {
+			fmt.Printf("  %8.2f MB | ID: %-4d | %-12s | %s\n",
+				megabytes(img.FileSize), img.ID, img.Resolution, img.LocalPath)
+		}
+	}
+
+	return nil
+}
+
+// printStatBuckets prints one statistics section as a table
+func printStatBuckets(title string, buckets []database.StatBucket, total int) {
+	if len(buckets) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s:\n", title)
+	for _, b := range buckets {
+		percent := 0.0
+		if total > 0 {
+			percent = float64(b.Count) / float64(total) * 100
+		}
+		fmt.Printf("  %-20s %6d %6.1f%% %10.2f MB\n", b.Key, b.Count, percent, megabytes(b.Size))
+	}
+}
+
+// writeStatsCSV writes the statistics as section,key,count,bytes rows
+func writeStatsCSV(stats *database.LibraryStats) error {
+	w := csv.NewWriter(os.Stdout)
+
+	records := [][]string{
+		{"section", "key", "count", "bytes"},
+		{"total", "wallpapers", strconv.Itoa(stats.TotalImages), strconv.FormatInt(stats.TotalSize, 10)},
+		{"total", "favorites", strconv.Itoa(stats.Favorites), ""},
+		{"total", "missing", strconv.Itoa(stats.Missing), ""},
+		{"total", "unavailable", strconv.Itoa(stats.Unavailable), ""},
+		{"total", "trashed", strconv.Itoa(stats.Trashed), strconv.FormatInt(stats.TrashedSize, 10)},
+	}
+
+	sections := []struct {
+		name    string
+		buckets []database.StatBucket
+	}{
+		{"source", stats.BySource},
+		{"category", stats.ByCategory},
+		{"purity", stats.ByPurity},
+		{"resolution", stats.ByResolution},
+		{"aspect_ratio", stats.ByAspectRatio},
+		{"week", stats.DownloadsPerWeek},
+		{"tag", stats.TopTags},
+	}
+	for _, section := range sections {
+		for _, b := range section.buckets {
+			records = append(records, []string{section.name, b.Key, strconv.Itoa(b.Count), strconv.FormatInt(b.Size, 10)})
+		}
+	}
+
+	for _, img := range stats.Largest {
+		records = append(records, []string{"largest", img.LocalPath, "1", strconv.FormatInt(img.FileSize, 10)})
+	}
+
+	if err := w.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// megabytes converts a byte count to megabytes
+func megabytes(size int64) float64 {
+	return float64(size) / (1024 * 1024)
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/analysis"
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/palette"
+	"github.com/spf13/cobra"
+)
+
+// newThemeCmd creates the theme command
+func (a *App) newThemeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "theme [id|path]",
+		Short: "Export a color theme from a wallpaper",
+		Long: `Generate a 16-color terminal theme from a wallpaper's palette, in the style of
+pywal. Without arguments, the current wallpaper is used.
+
+The palette is computed once with k-means clustering and stored in the
+database. Set theme.on_set in the config to write theme files to theme.dir
+whenever a wallpaper is set or rotated.
+
+Formats: kitty, alacritty, xresources, json (pywal layout), css`,
+		Example: `  wallfetch theme 42 --format kitty > ~/.config/kitty/colors.conf
+  wallfetch theme --format alacritty --output ~/.config/alacritty/colors.toml
+  wallfetch theme --write
+  wallfetch theme 42 --palette`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: a.runTheme,
+	}
+
+	cmd.Flags().StringP("format", "F", palette.FormatJSON, "Theme format ("+strings.Join(palette.Formats(), ", ")+")")
+	cmd.Flags().StringP("output", "o", "", "Write the theme to a file instead of stdout")
+	cmd.Flags().BoolP("write", "w", false, "Write every format in theme.formats to theme.dir")
+	cmd.Flags().Bool("palette", false, "Show the palette and source colors instead of a theme")
+	cmd.Flags().Bool("recompute", false, "Recompute the stored palette")
+
+	return cmd
+}
+
+// runTheme handles the theme command
+func (a *App) runTheme(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	write, _ := cmd.Flags().GetBool("write")
+	showPalette, _ := cmd.Flags().GetBool("palette")
+	recompute, _ := cmd.Flags().GetBool("recompute")
+
+	if !slices.Contains(palette.Formats(), format) {
+		return fmt.Errorf("invalid theme format: %s (valid: %s)", format, strings.Join(palette.Formats(), ", "))
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	var img *database.Image
+	var path string
+	if len(args) > 0 {
+		img, path, err = resolveWallpaper(db, args[0])
+	} else {
+		img, path, err = currentWallpaper(db)
+	}
+	if err != nil {
+		return err
+	}
+
+	colors, err := wallpaperPalette(db, img, path, recompute)
+	if err != nil {
+		return err
+	}
+
+	if showPalette {
+		printPalette("Palette", colors)
+		if img != nil && img.Colors != "" {
+			if source, err := palette.Parse(img.Colors); err == nil {
+				printPalette("Source colors", source)
+			}
+		}
+		return nil
+	}
+
+	theme, err := palette.NewTheme(colors, path)
+	if err != nil {
+		return err
+	}
+
+	if write {
+		if err := writeThemeFiles(a.config.Theme, theme); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Wrote %s theme files to %s\n", strings.Join(a.config.Theme.Formats, ", "), a.config.Theme.Dir)
+		return nil
+	}
+
+	text, err := theme.Render(format)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(config.ExpandPath(output), []byte(text), 0644); err != nil {
+		return fmt.Errorf("failed to write theme: %w", err)
+	}
+	fmt.Printf("✅ Wrote %s theme to %s\n", format, output)
+	return nil
+}
+
+// currentWallpaper returns the wallpaper set most recently, preferring the
+// one shown on every monitor over per-monitor ones
+func currentWallpaper(db *database.DB) (*database.Image, string, error) {
+	current, err := db.CurrentWallpapers()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read current wallpapers: %w", err)
+	}
+	if len(current) == 0 {
+		return nil, "", fmt.Errorf("no wallpaper has been set yet; pass an ID or path")
+	}
+
+	monitors := make([]string, 0, len(current))
+	for monitor := range current {
+		monitors = append(monitors, monitor)
+	}
+	sort.Strings(monitors)
+	s := current[monitors[0]]
+
+	// Spanned slices are recorded with the library image they came from
+	if s.ImageID > 0 {
+		img, err := db.GetImageByID(s.ImageID)
+		if err == nil && img.DeletedAt == nil {
+			return img, img.LocalPath, nil
+		}
+	}
+	return nil, s.Path, nil
+}
+
+// wallpaperPalette returns the palette of a wallpaper, computing it if it is
+// not stored yet. Palettes of library images are stored for next time.
+func wallpaperPalette(db *database.DB, img *database.Image, path string, recompute bool) ([]color.RGBA, error) {
+	if img != nil && img.Palette != "" && !recompute {
+		if colors, err := palette.Parse(img.Palette); err == nil && len(colors) > 0 {
+			return colors, nil
+		}
+	}
+
+	result, err := analysis.AnalyzeFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute palette: %w", err)
+	}
+
+	if img != nil {
+		result.Apply(img)
+		if err := db.SetAnalysis(img); err != nil {
+			return nil, fmt.Errorf("failed to store palette: %w", err)
+		}
+	}
+	return result.Colors.Palette, nil
+}
+
+// exportTheme writes the configured theme files for a wallpaper
+func exportTheme(cfg *config.Config, db *database.DB, img *database.Image, path string) error {
+	colors, err := wallpaperPalette(db, img, path, false)
+	if err != nil {
+		return err
+	}
+
+	theme, err := palette.NewTheme(colors, path)
+	if err != nil {
+		return err
+	}
+	return writeThemeFiles(cfg.Theme, theme)
+}
+
+// writeThemeFiles renders a theme in every configured format into the theme directory
+func writeThemeFiles(cfg config.ThemeConfig, theme palette.Theme) error {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create theme directory: %w", err)
+	}
+
+	for _, format := range cfg.Formats {
+		text, err := theme.Render(format)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(cfg.Dir, palette.Filename(format)), []byte(text), 0644); err != nil {
+			return fmt.Errorf("failed to write %s theme: %w", format, err)
+		}
+	}
+	return nil
+}
+
+// printPalette prints colors as hex codes with a 24-bit color swatch
+func printPalette(title string, colors []color.RGBA) {
+	fmt.Printf("%s:\n", title)
+	for _, c := range colors {
+		fmt.Printf("  \x1b[48;2;%d;%d;%dm      \x1b[0m %s\n", c.R, c.G, c.B, palette.Hex(c))
+	}
+}
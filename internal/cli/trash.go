@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/trash"
+	"github.com/spf13/cobra"
+)
+
+// newTrashCmd creates the trash command
+func (a *App) newTrashCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "Manage deleted wallpapers",
+		Long: `List, restore or permanently remove wallpapers that were deleted by
+delete, prune, dedupe or interactive browse.`,
+	}
+
+	// trash ls
+	lsCmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List trashed wallpapers",
+		RunE:    a.runTrashList,
+	}
+
+	// trash restore
+	restoreCmd := &cobra.Command{
+		Use:   "restore <id>...",
+		Short: "Restore trashed wallpapers to their original location",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  a.runTrashRestore,
+	}
+
+	// trash empty
+	emptyCmd := &cobra.Command{
+		Use:   "empty",
+		Short: "Permanently delete trashed wallpapers",
+		RunE:  a.runTrashEmpty,
+	}
+	emptyCmd.Flags().String("older-than", "", "Only delete wallpapers trashed longer ago than this (e.g., 30d, 12h)")
+
+	cmd.AddCommand(lsCmd)
+	cmd.AddCommand(restoreCmd)
+	cmd.AddCommand(emptyCmd)
+
+	return cmd
+}
+
+// runTrashList handles the trash ls command
+func (a *App) runTrashList(cmd *cobra.Command, args []string) error {
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	images, err := db.ListTrashed()
+	if err != nil {
+		return fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	if len(images) == 0 {
+		fmt.Println("Trash is empty.")
+		return nil
+	}
+
+	var totalSize int64
+	fmt.Printf("%d wallpapers in trash:\n\n", len(images))
+	for _, img := range images {
+		totalSize += img.FileSize
+		status := "🗑️ "
+		if img.TrashPath == "" {
+			status = "❌"
+		}
+		fmt.Printf("%s %-4d | %-16s | %8.2f MB | %s\n",
+			status,
+			img.ID,
+			img.DeletedAt.Format("2006-01-02 15:04"),
+			float64(img.FileSize)/(1024*1024),
+			img.LocalPath)
+	}
+	fmt.Printf("\nTotal: %.2f MB\n", float64(totalSize)/(1024*1024))
+
+	return nil
+}
+
+// runTrashRestore handles the trash restore command
+func (a *App) runTrashRestore(cmd *cobra.Command, args []string) error {
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	t := a.openTrash()
+
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid wallpaper ID: %s", arg)
+		}
+
+		img, err := db.GetImageByID(id)
+		if err != nil {
+			return fmt.Errorf("failed to find wallpaper %d: %w", id, err)
+		}
+		if img.DeletedAt == nil {
+			return fmt.Errorf("wallpaper %d is not in the trash", id)
+		}
+		if img.Unavailable {
+			return fmt.Errorf("cannot restore ID %d: library root %s is unavailable", id, img.Root)
+		}
+
+		if img.TrashPath != "" {
+			if err := t.Restore(img.TrashPath, img.LocalPath); err != nil {
+				return fmt.Errorf("failed to restore file for wallpaper %d: %w", id, err)
+			}
+		}
+
+		if err := db.RestoreImage(id); err != nil {
+			return fmt.Errorf("failed to restore wallpaper %d: %w", id, err)
+		}
+
+		fmt.Printf("✅ Restored ID %d: %s\n", id, img.LocalPath)
+	}
+
+	return nil
+}
+
+// runTrashEmpty handles the trash empty command
+func (a *App) runTrashEmpty(cmd *cobra.Command, args []string) error {
+	olderThan, _ := cmd.Flags().GetString("older-than")
+
+	var cutoff time.Time
+	if olderThan != "" {
+		age, err := config.ParseAge(olderThan)
+		if err != nil {
+			return err
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	trashed, err := db.ListTrashed()
+	if err != nil {
+		return fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	var toPurge []database.Image
+	for _, img := range trashed {
+		if cutoff.IsZero() || img.DeletedAt.Before(cutoff) {
+			toPurge = append(toPurge, img)
+		}
+	}
+
+	if len(toPurge) == 0 {
+		fmt.Println("Nothing to remove from the trash.")
+		return nil
+	}
+
+	fmt.Printf("⚠️  This will permanently delete %d wallpapers from the trash.\n", len(toPurge))
+	fmt.Print("Do you want to continue? [y/N]: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("❌ Operation cancelled")
+		return nil
+	}
+
+	t := a.openTrash()
+	deleted := 0
+	failed := 0
+	var totalSize int64
+
+	for _, img := range toPurge {
+		if img.TrashPath != "" {
+			if err := t.Remove(img.TrashPath); err != nil {
+				fmt.Printf("  ⚠️  Failed to delete %s: %v\n", img.TrashPath, err)
+				failed++
+				continue
+			}
+		}
+
+		if _, err := db.DeleteImage(img.ID); err != nil {
+			fmt.Printf("  ❌ Failed to delete ID %d from database: %v\n", img.ID, err)
+			failed++
+			continue
+		}
+
+		totalSize += img.FileSize
+		deleted++
+	}
+
+	fmt.Printf("\n✅ Permanently deleted %d wallpapers (%.2f MB)\n", deleted, float64(totalSize)/(1024*1024))
+	if failed > 0 {
+		fmt.Printf("⚠️  Failed: %d\n", failed)
+	}
+
+	return nil
+}
+
+// openTrash returns the configured trash can
+func (a *App) openTrash() *trash.Trash {
+	if a.config.Trash.UseSystemTrash {
+		return trash.System()
+	}
+	return trash.New(a.config.Trash.Dir)
+}
+
+// removeImage takes an image out of the library, moving its file to the
+// trash unless permanent is set, and adds it to the blocklist if
+// blockReason is set. The file and the database row change in lockstep: the
+// row is changed in a transaction that is only committed once the file is
+// out of the way, and the file is put back if anything fails, so an image
+// that cannot be removed stays tracked. Files deleted permanently are moved
+// aside first and only deleted once the row is gone. Images on an
+// unavailable library root are refused, as their files cannot be reached.
+func removeImage(db *database.DB, t *trash.Trash, img *database.Image, permanent bool, blockReason string) error {
+	name := filepath.Base(img.LocalPath)
+	if img.Unavailable {
+		return fmt.Errorf("cannot remove ID %d: library root %s is unavailable", img.ID, img.Root)
+	}
+
+	r, err := db.BeginRemoval(img)
+	if err != nil {
+		return fmt.Errorf("failed to remove ID %d from database: %w", img.ID, err)
+	}
+	defer r.Rollback()
+
+	// moved is where the file went, empty if it was already missing
+	var moved string
+	if permanent {
+		moved = filepath.Join(filepath.Dir(img.LocalPath), "."+name+".wallfetch-delete")
+		if err := os.Rename(img.LocalPath, moved); err != nil {
+			if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete file %s: %w", name, err)
+			}
+			moved = ""
+		}
+		err = r.Delete()
+	} else {
+		moved, err = t.Move(img.LocalPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to move %s to trash: %w", name, err)
+		}
+		err = r.Trash(moved)
+	}
+	if err == nil && blockReason != "" {
+		err = r.Block(blockReason)
+	}
+	if err == nil {
+		err = r.Commit()
+	}
+
+	if err != nil {
+		// Put the file back so the library stays consistent
+		if moved != "" {
+			if permanent {
+				_ = os.Rename(moved, img.LocalPath)
+			} else {
+				_ = t.Restore(moved, img.LocalPath)
+			}
+		}
+		return fmt.Errorf("failed to remove ID %d from database: %w", img.ID, err)
+	}
+
+	if permanent && moved != "" {
+		if err := os.Remove(moved); err != nil {
+			return fmt.Errorf("removed ID %d from the library, but failed to delete %s: %w", img.ID, moved, err)
+		}
+	}
+
+	return nil
+}
+
+// sharedWith describes the library images still using img's file data
+// through links made by dedupe --link, e.g. "ID 4, ID 9", or returns "" if
+// deleting img frees its data
+func sharedWith(db *database.DB, img *database.Image) string {
+	linked, err := db.LinkedImages(img)
+	if err != nil || len(linked) == 0 {
+		return ""
+	}
+	ids := make([]string, len(linked))
+	for i, other := range linked {
+		ids[i] = fmt.Sprintf("ID %d", other.ID)
+	}
+	return strings.Join(ids, ", ")
+}
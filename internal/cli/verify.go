@@ -0,0 +1,244 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/downloader"
+	"github.com/AccursedGalaxy/wallfetch/internal/trash"
+	"github.com/AccursedGalaxy/wallfetch/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// newVerifyCmd creates the verify command
+func (a *App) newVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [dirs...]",
+		Short: "Check wallpaper files against the database",
+		Long: `Re-hash every wallpaper file and compare it with the checksum recorded when
+it was added. Reports missing files, size and checksum mismatches, files that
+no longer decode as images, and image files in the library directories (the
+download directory, library roots and any directories given) that the
+library does not track.
+
+With --fix:
+  - missing files are relinked to a file with the same checksum found in the
+    scanned directories, or re-downloaded from their URL
+  - changed or corrupted files are replaced by a re-download with the
+    recorded checksum, and the bad copy is quarantined
+  - otherwise, changed files that still decode are accepted as they are, and
+    files that do not decode are moved to the trash
+
+Untracked files are only reported; add them with 'wallfetch import'.`,
+		RunE: a.runVerify,
+	}
+
+	cmd.Flags().Bool("fix", false, "Repair the problems found")
+
+	return cmd
+}
+
+// runVerify handles the verify command
+func (a *App) runVerify(cmd *cobra.Command, args []string) error {
+	fix, _ := cmd.Flags().GetBool("fix")
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	all, err := db.QueryImages(database.ImageQuery{})
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+
+	// Files on library roots that are offline cannot be checked
+	var images []database.Image
+	for _, img := range all {
+		if !img.Unavailable {
+			images = append(images, img)
+		}
+	}
+
+	fmt.Printf("🔍 Verifying %d wallpapers...\n", len(images))
+	issues := verify.Check(images, func(done int) {
+		if done%100 == 0 || done == len(images) {
+			fmt.Printf("\r  Checked %d/%d", done, len(images))
+		}
+	})
+	if len(images) > 0 {
+		fmt.Println()
+	}
+
+	tracked := make(map[string]bool, len(images))
+	for _, img := range images {
+		if abs, err := filepath.Abs(img.LocalPath); err == nil {
+			tracked[abs] = true
+		}
+	}
+	dirs := a.config.LibraryDirs()
+	for _, dir := range args {
+		dirs = append(dirs, config.ExpandPath(dir))
+	}
+	untracked, err := verify.Untracked(dirs, tracked)
+	if err != nil {
+		return fmt.Errorf("failed to scan for untracked files: %w", err)
+	}
+
+	counts := make(map[string]int)
+	if len(issues) > 0 {
+		fmt.Printf("\nProblems:\n")
+	}
+	for _, issue := range issues {
+		counts[issue.Kind]++
+		fmt.Printf("  ❌ ID %d %s: %s (%s)\n", issue.Image.ID, issue.Image.LocalPath, issue.Kind, issue.Detail)
+	}
+	if len(untracked) > 0 {
+		fmt.Printf("\nUntracked files:\n")
+		for _, path := range untracked {
+			fmt.Printf("  ❓ %s\n", path)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("VERIFY SUMMARY:\n")
+	fmt.Printf("  Checked: %d\n", len(images))
+	fmt.Printf("  OK: %d\n", len(images)-len(issues))
+	if unavailable := len(all) - len(images); unavailable > 0 {
+		fmt.Printf("  Unavailable (not checked): %d\n", unavailable)
+	}
+	for _, kind := range []string{verify.Missing, verify.SizeMismatch, verify.ChecksumMismatch, verify.Undecodable} {
+		if counts[kind] > 0 {
+			fmt.Printf("  %s: %d\n", strings.ToUpper(kind[:1])+kind[1:], counts[kind])
+		}
+	}
+	fmt.Printf("  Untracked files: %d\n", len(untracked))
+
+	if len(issues) == 0 {
+		if len(untracked) > 0 {
+			fmt.Printf("\nRun 'wallfetch import <dir>' to add untracked files\n")
+		}
+		return nil
+	}
+	if !fix {
+		fmt.Printf("\nRun 'wallfetch verify --fix' to repair these problems\n")
+		return nil
+	}
+
+	fmt.Printf("\n🔧 Fixing...\n")
+	f := &verifyFixer{
+		db:         db,
+		trash:      a.openTrash(),
+		dl:         downloader.NewDownloader(a.config.DownloadDir, 1, db),
+		quarantine: filepath.Join(filepath.Dir(a.config.Trash.Dir), "quarantine"),
+		finder:     verify.NewFinder(untracked),
+	}
+	fixed := 0
+	for _, issue := range issues {
+		if f.fix(issue) {
+			fixed++
+		}
+	}
+
+	fmt.Printf("\n✅ Fixed %d of %d problems\n", fixed, len(issues))
+	if fixed < len(issues) {
+		fmt.Printf("   Missing files that cannot be recovered can be removed with 'wallfetch cleanup'\n")
+	}
+	return nil
+}
+
+// verifyFixer repairs the problems found by verify
+type verifyFixer struct {
+	db         *database.DB
+	trash      *trash.Trash
+	dl         *downloader.Downloader
+	quarantine string
+	// finder searches the untracked files for moved library files
+	finder *verify.Finder
+}
+
+// fix repairs one problem and reports whether it succeeded
+func (f *verifyFixer) fix(issue verify.Issue) bool {
+	img := issue.Image
+	name := filepath.Base(img.LocalPath)
+
+	if issue.Kind == verify.Missing {
+		if path := f.finder.Find(&img); path != "" {
+			if err := f.db.SetLocalPath(img.ID, path); err != nil {
+				fmt.Printf("  ❌ ID %d: failed to relink: %v\n", img.ID, err)
+				return false
+			}
+			fmt.Printf("  🔗 ID %d: found at %s\n", img.ID, path)
+			return true
+		}
+	}
+
+	if img.URL != "" {
+		err := f.redownload(&img, issue.Kind != verify.Missing)
+		if err == nil {
+			fmt.Printf("  ⬇️  ID %d: re-downloaded %s\n", img.ID, name)
+			return true
+		}
+		fmt.Printf("  ⚠️  ID %d: re-download failed: %v\n", img.ID, err)
+	}
+
+	switch issue.Kind {
+	case verify.Missing:
+		fmt.Printf("  ❌ ID %d: no copy of %s found\n", img.ID, name)
+		return false
+	case verify.Undecodable:
+		if err := removeImage(f.db, f.trash, &img, false, ""); err != nil {
+			fmt.Printf("  ❌ ID %d: %v\n", img.ID, err)
+			return false
+		}
+		fmt.Printf("  🗑️  ID %d: moved %s to the trash (restore with 'wallfetch trash restore %d')\n", img.ID, name, img.ID)
+		return true
+	default:
+		// The file still decodes, so it was most likely edited on purpose
+		if err := f.db.SetFileInfo(img.ID, issue.Checksum, issue.Size); err != nil {
+			fmt.Printf("  ❌ ID %d: failed to update checksum: %v\n", img.ID, err)
+			return false
+		}
+		fmt.Printf("  ✏️  ID %d: accepted the changed %s\n", img.ID, name)
+		return true
+	}
+}
+
+// redownload fetches an image's file again from its URL and puts it in
+// place if it has the recorded checksum. With quarantine, the file it
+// replaces is kept in the quarantine directory.
+func (f *verifyFixer) redownload(img *database.Image, quarantine bool) error {
+	dir := filepath.Dir(img.LocalPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tempPath, checksum, err := f.dl.DownloadTemp(img.URL, dir)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempPath)
+
+	if checksum != img.Checksum {
+		return fmt.Errorf("the downloaded file does not match the recorded checksum")
+	}
+
+	if quarantine {
+		if err := os.MkdirAll(f.quarantine, 0700); err != nil {
+			return fmt.Errorf("failed to create quarantine directory: %w", err)
+		}
+		dest := filepath.Join(f.quarantine, fmt.Sprintf("%d-%s", img.ID, filepath.Base(img.LocalPath)))
+		if err := os.Rename(img.LocalPath, dest); err != nil {
+			return fmt.Errorf("failed to quarantine the bad copy: %w", err)
+		}
+		fmt.Printf("  🚫 ID %d: quarantined the bad copy as %s\n", img.ID, dest)
+	}
+
+	return os.Rename(tempPath, img.LocalPath)
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+	"github.com/AccursedGalaxy/wallfetch/internal/sourceid"
+	"github.com/AccursedGalaxy/wallfetch/internal/verify"
+	"github.com/AccursedGalaxy/wallfetch/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+// newWatchCmd creates the watch command
+func (a *App) newWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch [dirs...]",
+		Short: "Import wallpapers as they are added to folders",
+		Long: `Watch folders and import new wallpapers as soon as they finish being written,
+with the same duplicate and blocklist checks as 'wallfetch import'.
+
+Files are imported once they have not changed for the debounce period, so
+partial downloads and copies are not picked up. A file moved or renamed
+within the watched folders keeps its library entry, favorites and tags.
+Entries of removed files are kept, so nothing is lost when a folder is moved
+away or a drive is unmounted; 'wallfetch cleanup' relinks or removes them.
+Files already in the folders are not imported; run 'wallfetch import' for
+those.
+
+Without arguments, the folders of the watch config block are watched. Runs
+until interrupted.`,
+		Example: `  wallfetch watch ~/Downloads/wallpapers
+  wallfetch watch --source unsplash --debounce 5s ~/Pictures/unsplash`,
+		RunE: a.runWatch,
+	}
+
+	cmd.Flags().StringP("source", "s", "", "Source name for imported wallpapers (default from config, else recognized from the file name or local)")
+	cmd.Flags().String("debounce", "", "How long files must stay unchanged before they are imported (default from config, 2s)")
+
+	return cmd
+}
+
+// runWatch handles the watch command
+func (a *App) runWatch(cmd *cobra.Command, args []string) error {
+	source := a.config.Watch.Source
+	if cmd.Flags().Changed("source") {
+		source, _ = cmd.Flags().GetString("source")
+	}
+	ids, err := sourceid.New(a.config.Import.Patterns)
+	if err != nil {
+		return err
+	}
+
+	debounce := a.config.Watch.Debounce
+	if cmd.Flags().Changed("debounce") {
+		debounce, _ = cmd.Flags().GetString("debounce")
+	}
+	quiet, err := time.ParseDuration(debounce)
+	if err != nil || quiet <= 0 {
+		return fmt.Errorf("invalid debounce: %s (use a duration such as 2s)", debounce)
+	}
+
+	dirs := a.config.Watch.Dirs
+	if len(args) > 0 {
+		dirs = nil
+		for _, dir := range args {
+			dirs = append(dirs, config.ExpandPath(dir))
+		}
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no folders to watch: pass them as arguments or set watch.dirs in the config")
+	}
+	for i, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("invalid folder %s: %w", dir, err)
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return fmt.Errorf("directory does not exist: %s", dir)
+		}
+		dirs[i] = abs
+	}
+
+	db, err := a.openDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	w, err := watch.New(dirs, quiet)
+	if err != nil {
+		return err
+	}
+	wi := &watchImporter{app: a, db: db, source: source, ids: ids}
+	w.Handle = wi.handle
+	w.Logf = logf
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("👀 Watching %d folders (%d directories) for new wallpapers, press Ctrl+C to stop\n", len(dirs), w.Dirs())
+	for _, dir := range dirs {
+		fmt.Printf("  %s\n", dir)
+	}
+	return w.Run(ctx)
+}
+
+// logf prints a timestamped line, as long-running commands do
+func logf(format string, args ...interface{}) {
+	fmt.Printf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+}
+
+// watchImporter keeps the library in step with the watched folders
+type watchImporter struct {
+	app    *App
+	db     *database.DB
+	source string
+	ids    *sourceid.Matcher
+}
+
+// handle applies a change in a watched folder to the library
+func (wi *watchImporter) handle(ev watch.Event) {
+	switch ev.Op {
+	case watch.Written:
+		wi.written(ev.Path)
+	case watch.Gone:
+		wi.gone(ev.Path)
+	}
+}
+
+// written imports a new file, relinks a moved one or records that a
+// tracked file changed
+func (wi *watchImporter) written(path string) {
+	name := filepath.Base(path)
+	if _, err := os.Stat(path); err != nil {
+		return // removed again before it settled
+	}
+	if _, _, err := imaging.Open(path); err != nil {
+		logf("⚠️  Skipped %s: not a complete image (%v)", name, err)
+		return
+	}
+
+	if tracked, err := wi.db.GetImageByPath(path); err == nil {
+		checksum, err := verify.FileChecksum(path)
+		if err != nil || checksum == tracked.Checksum {
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if err := wi.db.SetFileInfo(tracked.ID, checksum, info.Size()); err != nil {
+			logf("❌ Failed to update %s: %v", name, err)
+			return
+		}
+		logf("✏️  Updated: %s (file changed)", name)
+		return
+	}
+
+	img, _, err := wi.app.prepareImport(path, wi.source, wi.ids)
+	if err != nil {
+		logf("⚠️  Failed to import %s: %v", name, err)
+		return
+	}
+
+	// A tracked image whose file is missing was moved or renamed here
+	if moved := wi.movedImage(img.Checksum); moved != nil {
+		if err := wi.db.SetLocalPath(moved.ID, img.LocalPath); err != nil {
+			logf("❌ Failed to relink %s: %v", name, err)
+			return
+		}
+		logf("🔗 Moved: ID %d %s -> %s", moved.ID, moved.LocalPath, img.LocalPath)
+		return
+	}
+
+	status, err := addImport(wi.db, img)
+	switch {
+	case err != nil:
+		logf("❌ Failed to import %s: %v", name, err)
+	case status == importAdded:
+		logf("✅ Imported: %s (ID %d)", name, img.ID)
+	default:
+		logf("⏭️  Skipped (%s): %s", status, name)
+	}
+}
+
+// movedImage returns the tracked image with a checksum whose file no
+// longer exists, or nil
+func (wi *watchImporter) movedImage(checksum string) *database.Image {
+	images, err := wi.db.ImagesByChecksum(checksum)
+	if err != nil {
+		return nil
+	}
+	for _, img := range images {
+		if img.Unavailable {
+			continue
+		}
+		if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
+			return &img
+		}
+	}
+	return nil
+}
+
+// gone reports the library entries of a file or directory that was
+// removed, unless the files were relinked in the meantime. The entries are
+// kept: the files may have been moved out of the watched folders or be on
+// a drive that was unmounted, and cleanup relinks or removes them.
+func (wi *watchImporter) gone(path string) {
+	if _, err := os.Stat(path); err == nil {
+		return // replaced, e.g. saved by renaming over it
+	}
+
+	var images []database.Image
+	if img, err := wi.db.GetImageByPath(path); err == nil {
+		images = append(images, *img)
+	}
+	if under, err := wi.db.QueryImages(database.ImageQuery{PathPrefix: path}); err == nil {
+		images = append(images, under...)
+	}
+
+	var missing []database.Image
+	for _, img := range images {
+		if img.Unavailable {
+			continue
+		}
+		if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
+			missing = append(missing, img)
+		}
+	}
+
+	switch len(missing) {
+	case 0:
+	case 1:
+		logf("❓ Missing: ID %d %s (run 'wallfetch cleanup' to relink or remove it)", missing[0].ID, missing[0].LocalPath)
+	default:
+		logf("❓ Missing: %d wallpapers under %s (run 'wallfetch cleanup' to relink or remove them)", len(missing), path)
+	}
+}
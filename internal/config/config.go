@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,6 +24,30 @@ type Config struct {
 
 	// Database settings
 	Database DatabaseConfig `yaml:"database"`
+
+	// Trash settings
+	Trash TrashConfig `yaml:"trash"`
+
+	// Desktop integration settings
+	Desktop DesktopConfig `yaml:"desktop"`
+
+	// Wallpaper rotation settings
+	Rotation RotationConfig `yaml:"rotation"`
+
+	// Color theme export settings
+	Theme ThemeConfig `yaml:"theme"`
+
+	// Library size limits enforced by fetch
+	Library LibraryConfig `yaml:"library"`
+
+	// Library retention settings used by prune
+	Retention RetentionConfig `yaml:"retention"`
+
+	// Watch folder settings
+	Watch WatchConfig `yaml:"watch"`
+
+	// Import settings
+	Import ImportConfig `yaml:"import"`
 }
 
 // DefaultOptions represents default options for each source
@@ -45,12 +70,133 @@ type DatabaseConfig struct {
 	AutoVacuum bool   `yaml:"auto_vacuum"`
 }
 
+// TrashConfig represents where deleted wallpapers are moved to
+type TrashConfig struct {
+	Dir            string `yaml:"dir"`
+	UseSystemTrash bool   `yaml:"use_system_trash"`
+}
+
+// DesktopConfig represents how wallpapers are applied to the desktop
+type DesktopConfig struct {
+	// Backend is "auto" or one of gnome, kde, xfce, hyprpaper, swww, swaybg, feh, xwallpaper
+	Backend string `yaml:"backend"`
+	// MonitorMode is "same" to show one wallpaper on every monitor,
+	// "per-monitor" to pick a fitting wallpaper for each one or "span" to
+	// split one wide wallpaper across all of them
+	MonitorMode string `yaml:"monitor_mode"`
+	// Bezel is the width in pixels of the frames between monitors, skipped
+	// when spanning a wallpaper so it lines up across screens
+	Bezel int `yaml:"bezel"`
+}
+
+// RotationConfig represents how the rotation daemon cycles wallpapers
+type RotationConfig struct {
+	// Pool is all, favorites, tag:<tag>[,<tag>...] or collection:<dir>
+	Pool string `yaml:"pool"`
+	// Schedule is an interval (30m), a cron expression (*/30 * * * *)
+	// or times of day (08:00,13:00,20:00)
+	Schedule string `yaml:"schedule"`
+	// AvoidRecent is how many recently shown wallpapers are not repeated
+	AvoidRecent int `yaml:"avoid_recent"`
+	// TimeOfDay picks light wallpapers during the day and dark ones at night
+	TimeOfDay TimeOfDayConfig `yaml:"time_of_day"`
+}
+
+// TimeOfDayConfig represents when it is day for matching wallpaper brightness
+type TimeOfDayConfig struct {
+	// Mode is "off", "hours" to use DayStart and DayEnd, or "sun" to use
+	// sunrise and sunset at Latitude and Longitude
+	Mode      string  `yaml:"mode"`
+	DayStart  string  `yaml:"day_start"`
+	DayEnd    string  `yaml:"day_end"`
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+}
+
+// ThemeConfig represents how color themes are exported from wallpapers
+type ThemeConfig struct {
+	// OnSet writes theme files whenever a wallpaper is set or rotated
+	OnSet bool `yaml:"on_set"`
+	// Formats are the theme files written: kitty, alacritty, xresources, json, css
+	Formats []string `yaml:"formats"`
+	// Dir is where theme files are written
+	Dir string `yaml:"dir"`
+}
+
+// LibraryConfig represents the size limits fetch keeps the library within
+type LibraryConfig struct {
+	// MaxSize is the most disk space the library may use, e.g. 20GB
+	MaxSize string `yaml:"max_size"`
+	// SourceQuotas limits each source to a number of wallpapers (500) or
+	// an amount of disk space (10GB)
+	SourceQuotas map[string]string `yaml:"source_quotas"`
+	// OnFull is what fetch does when a download would exceed a limit:
+	// "stop", or "evict" to permanently delete the wallpapers the
+	// retention policy would prune first. Evicted files bypass the trash,
+	// which would free no space.
+	OnFull string `yaml:"on_full"`
+	// Roots names other directories holding library wallpapers besides
+	// the download directory, e.g. {archive: /mnt/nas/wallpapers}. Paths
+	// are stored relative to them, and cleanup searches them for files
+	// that were moved.
+	Roots map[string]string `yaml:"roots"`
+}
+
+// RetentionConfig represents which wallpapers prune removes. Limits evict
+// the oldest wallpapers until the library fits; filters only make wallpapers
+// eligible for removal. Protected wallpapers are never removed.
+type RetentionConfig struct {
+	// Keep is the most wallpapers to keep (0 for no limit)
+	Keep int `yaml:"keep"`
+	// MaxSize is the most disk space the library may use, e.g. 20GB
+	MaxSize string `yaml:"max_size"`
+	// OlderThan only removes wallpapers downloaded longer ago, e.g. 90d
+	OlderThan string `yaml:"older_than"`
+	// NeverShown only removes wallpapers rotation has never shown
+	NeverShown bool `yaml:"never_shown"`
+	// KeepFavorites protects favorite wallpapers
+	KeepFavorites bool `yaml:"keep_favorites"`
+	// MinRating protects wallpapers rated at least this high (0 to disable)
+	MinRating int `yaml:"min_rating"`
+	// SourceQuotas limits each source to a number of wallpapers (500) or
+	// an amount of disk space (10GB)
+	SourceQuotas map[string]string `yaml:"source_quotas"`
+}
+
+// WatchConfig represents the folders 'wallfetch watch' imports from
+type WatchConfig struct {
+	// Dirs are watched when none are given on the command line
+	Dirs []string `yaml:"dirs"`
+	// Source is recorded for the imported wallpapers; when empty, the
+	// source recognized from the file name is used, or local
+	Source string `yaml:"source"`
+	// Debounce is how long a file must stay unchanged before it is
+	// imported, e.g. 2s
+	Debounce string `yaml:"debounce"`
+}
+
+// ImportConfig represents how import and watch recognize where files came
+// from
+type ImportConfig struct {
+	// Patterns recognize source IDs in file names, before the built-in
+	// patterns for Wallhaven, Unsplash, Reddit and booru names
+	Patterns []FilenamePattern `yaml:"patterns"`
+}
+
+// FilenamePattern is a regular expression matched against file names
+// without their extension. Its first group, or the group named "id", is
+// the source ID.
+type FilenamePattern struct {
+	Source  string `yaml:"source"`
+	Pattern string `yaml:"pattern"`
+}
+
 // Load loads configuration from the config file and environment
 func Load() (*Config, error) {
 	cfg := Default()
 
 	// Try to load from config file
-	configPath := getConfigPath()
+	configPath := Path()
 	if _, err := os.Stat(configPath); err == nil {
 		data, err := os.ReadFile(configPath)
 		if err != nil {
@@ -71,12 +217,54 @@ func Load() (*Config, error) {
 	}
 
 	// Expand paths
-	cfg.DownloadDir = expandPath(cfg.DownloadDir)
-	cfg.Database.Path = expandPath(cfg.Database.Path)
+	cfg.DownloadDir = ExpandPath(cfg.DownloadDir)
+	cfg.Database.Path = ExpandPath(cfg.Database.Path)
+	cfg.Trash.Dir = ExpandPath(cfg.Trash.Dir)
+	cfg.Theme.Dir = ExpandPath(cfg.Theme.Dir)
+	for name, root := range cfg.Library.Roots {
+		cfg.Library.Roots[name] = ExpandPath(root)
+	}
+	for i, dir := range cfg.Watch.Dirs {
+		cfg.Watch.Dirs[i] = ExpandPath(dir)
+	}
 
 	return cfg, nil
 }
 
+// DownloadsRoot is the name of the library root for the download directory
+const DownloadsRoot = "downloads"
+
+// LibraryRoots returns the library roots by name: the download directory
+// as DownloadsRoot and the roots of the library block
+func (c *Config) LibraryRoots() map[string]string {
+	roots := map[string]string{DownloadsRoot: c.DownloadDir}
+	for name, root := range c.Library.Roots {
+		if name != DownloadsRoot {
+			roots[name] = root
+		}
+	}
+	return roots
+}
+
+// LibraryDirs returns the directories holding library wallpapers: the
+// download directory followed by the library roots in name order
+func (c *Config) LibraryDirs() []string {
+	dirs := []string{c.DownloadDir}
+	names := make([]string, 0, len(c.Library.Roots))
+	for name := range c.Library.Roots {
+		if name != DownloadsRoot {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if root := c.Library.Roots[name]; root != "" && root != c.DownloadDir {
+			dirs = append(dirs, root)
+		}
+	}
+	return dirs
+}
+
 // Default returns a configuration with default values
 func Default() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -102,6 +290,36 @@ func Default() *Config {
 			Path:       filepath.Join(homeDir, ".local", "share", "wallfetch", "wallpapers.db"),
 			AutoVacuum: true,
 		},
+		Trash: TrashConfig{
+			Dir: filepath.Join(homeDir, ".local", "share", "wallfetch", "trash"),
+		},
+		Desktop: DesktopConfig{
+			Backend:     "auto",
+			MonitorMode: "same",
+		},
+		Rotation: RotationConfig{
+			Pool:        "all",
+			Schedule:    "30m",
+			AvoidRecent: 20,
+			TimeOfDay: TimeOfDayConfig{
+				Mode:     "off",
+				DayStart: "07:00",
+				DayEnd:   "19:00",
+			},
+		},
+		Theme: ThemeConfig{
+			Formats: []string{"kitty", "alacritty", "xresources", "json", "css"},
+			Dir:     filepath.Join(homeDir, ".cache", "wallfetch", "theme"),
+		},
+		Library: LibraryConfig{
+			OnFull: "stop",
+		},
+		Retention: RetentionConfig{
+			KeepFavorites: true,
+		},
+		Watch: WatchConfig{
+			Debounce: "2s",
+		},
 	}
 }
 
@@ -117,7 +335,7 @@ func (c *Config) GetWallhavenAPIKey() string {
 
 // Save saves the current configuration to the config file
 func (c *Config) Save() error {
-	configPath := getConfigPath()
+	configPath := Path()
 
 	// Ensure config directory exists
 	configDir := filepath.Dir(configPath)
@@ -137,8 +355,8 @@ func (c *Config) Save() error {
 	return nil
 }
 
-// getConfigPath returns the path to the configuration file
-func getConfigPath() string {
+// Path returns the path to the configuration file
+func Path() string {
 	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
 		return filepath.Join(configHome, "wallfetch", "config.yaml")
 	}
@@ -147,8 +365,8 @@ func getConfigPath() string {
 	return filepath.Join(homeDir, ".config", "wallfetch", "config.yaml")
 }
 
-// expandPath expands ~ to home directory
-func expandPath(path string) string {
+// ExpandPath expands ~ to home directory
+func ExpandPath(path string) string {
 	if path == "" {
 		return path
 	}
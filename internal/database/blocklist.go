@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// BlockedImage represents an entry in the blocklist. Blocked images are
+// never downloaded or imported again.
+type BlockedImage struct {
+	ID        int       `json:"id"`
+	Source    string    `json:"source"`
+	SourceID  string    `json:"source_id"`
+	Checksum  string    `json:"checksum"`
+	Reason    string    `json:"reason"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// blockQuery adds an image to the blocklist, updating the reason of an
+// existing entry
+const blockQuery = `
+	INSERT INTO blocked (source, source_id, checksum, reason)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(checksum) DO UPDATE SET reason = excluded.reason
+	`
+
+// BlockImage adds an image to the blocklist
+func (db *DB) BlockImage(img *Image, reason string) error {
+	_, err := db.conn.Exec(blockQuery, img.Source, img.SourceID, img.Checksum, reason)
+	return err
+}
+
+// IsBlocked checks if an image is blocked by source and source ID
+func (db *DB) IsBlocked(source, sourceID string) (bool, error) {
+	if sourceID == "" {
+		return false, nil
+	}
+	query := `SELECT COUNT(*) FROM blocked WHERE source = ? AND source_id = ?`
+	var count int
+	err := db.conn.QueryRow(query, source, sourceID).Scan(&count)
+	return count > 0, err
+}
+
+// IsChecksumBlocked checks if an image is blocked by checksum
+func (db *DB) IsChecksumBlocked(checksum string) (bool, error) {
+	query := `SELECT COUNT(*) FROM blocked WHERE checksum = ?`
+	var count int
+	err := db.conn.QueryRow(query, checksum).Scan(&count)
+	return count > 0, err
+}
+
+// ListBlocked lists all blocklist entries, most recent first
+func (db *DB) ListBlocked() ([]BlockedImage, error) {
+	query := `SELECT id, source, source_id, checksum, reason, blocked_at FROM blocked ORDER BY blocked_at DESC, id DESC`
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []BlockedImage
+	for rows.Next() {
+		var b BlockedImage
+		if err := rows.Scan(&b.ID, &b.Source, &b.SourceID, &b.Checksum, &b.Reason, &b.BlockedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, b)
+	}
+
+	return entries, rows.Err()
+}
+
+// UnblockImage removes a blocklist entry by ID
+func (db *DB) UnblockImage(id int) error {
+	result, err := db.conn.Exec(`DELETE FROM blocked WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("blocklist entry with ID %d not found", id)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -12,7 +13,8 @@ import (
 
 // DB represents the database connection
 type DB struct {
-	conn *sql.DB
+	conn  *sql.DB
+	roots []root
 }
 
 // Image represents an image record in the database
@@ -28,6 +30,90 @@ type Image struct {
 	FileSize     int64     `json:"file_size"`
 	DownloadedAt time.Time `json:"downloaded_at"`
 	Favorite     bool      `json:"favorite"`
+	// Rating is the user's rating from 1 to 5 stars; 0 when unrated
+	Rating int `json:"rating"`
+
+	// Source metadata
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	Category        string `json:"category"`
+	Purity          string `json:"purity"`
+	FileType        string `json:"file_type"`
+	Colors          string `json:"colors"`
+	Views           int    `json:"views"`
+	SourceFavorites int    `json:"source_favorites"`
+	Uploader        string `json:"uploader"`
+	PageURL         string `json:"page_url"`
+
+	// Palette is the computed color palette as comma-separated hex colors,
+	// most common first; empty until computed
+	Palette string `json:"palette,omitempty"`
+	// Luminance is the average lightness from 0 to 1 and Brightness its
+	// class, "light" or "dark"; empty until computed
+	Luminance  float64 `json:"luminance"`
+	Brightness string  `json:"brightness,omitempty"`
+	// PHash and DHash are perceptual hashes as 16 hex digits, used to find
+	// near-duplicates; empty until computed
+	PHash string `json:"phash,omitempty"`
+	DHash string `json:"dhash,omitempty"`
+	// LinkGroup is shared by images whose files were linked together by
+	// dedupe --link and so store their data once; 0 for unlinked images
+	LinkGroup int `json:"link_group,omitempty"`
+
+	// Trash state; DeletedAt is nil for images in the library
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	TrashPath string     `json:"trash_path,omitempty"`
+
+	// Root is the library root LocalPath is stored relative to, or empty
+	// for paths stored as they are. Unavailable images are in a root that
+	// is offline or no longer configured; their files cannot be checked.
+	Root        string `json:"root,omitempty"`
+	Unavailable bool   `json:"unavailable,omitempty"`
+}
+
+// imageColumns is the column list matching scanImage
+const imageColumns = `id, source, source_id, url, local_path, checksum, tags, resolution, file_size, downloaded_at, favorite,
+	width, height, category, purity, file_type, colors, views, source_favorites, uploader, page_url,
+	deleted_at, trash_path, palette, luminance, brightness, phash, dhash, link_group, rating, root`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanImage scans a row selected with imageColumns into an Image
+func (db *DB) scanImage(row rowScanner) (*Image, error) {
+	var img Image
+	var tags sql.NullString
+	var deletedAt sql.NullTime
+	err := row.Scan(&img.ID, &img.Source, &img.SourceID, &img.URL, &img.LocalPath,
+		&img.Checksum, &tags, &img.Resolution, &img.FileSize, &img.DownloadedAt, &img.Favorite,
+		&img.Width, &img.Height, &img.Category, &img.Purity, &img.FileType, &img.Colors,
+		&img.Views, &img.SourceFavorites, &img.Uploader, &img.PageURL,
+		&deletedAt, &img.TrashPath, &img.Palette, &img.Luminance, &img.Brightness,
+		&img.PHash, &img.DHash, &img.LinkGroup, &img.Rating, &img.Root)
+	if err != nil {
+		return nil, err
+	}
+	db.resolve(&img)
+	img.Tags = tags.String
+	if deletedAt.Valid {
+		img.DeletedAt = &deletedAt.Time
+	}
+	return &img, nil
+}
+
+// scanImages scans all rows selected with imageColumns
+func (db *DB) scanImages(rows *sql.Rows) ([]Image, error) {
+	var images []Image
+	for rows.Next() {
+		img, err := db.scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *img)
+	}
+	return images, rows.Err()
 }
 
 // Open opens the database connection
@@ -74,40 +160,229 @@ func (db *DB) init() error {
 		file_size INTEGER,
 		downloaded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		favorite BOOLEAN DEFAULT FALSE,
-		UNIQUE(source, source_id),
+		UNIQUE(source, source_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS blocked (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		source_id TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		blocked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		UNIQUE(checksum)
 	);
 
+	CREATE INDEX IF NOT EXISTS idx_blocked_source ON blocked(source, source_id);
+
+	CREATE TABLE IF NOT EXISTS fetch_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		params TEXT NOT NULL DEFAULT '{}',
+		status TEXT NOT NULL DEFAULT 'running',
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		finished_at DATETIME,
+		pages_processed INTEGER NOT NULL DEFAULT 0,
+		downloaded INTEGER NOT NULL DEFAULT 0,
+		skipped INTEGER NOT NULL DEFAULT 0,
+		failed INTEGER NOT NULL DEFAULT 0,
+		skip_reasons TEXT NOT NULL DEFAULT '{}',
+		errors TEXT NOT NULL DEFAULT '[]'
+	);
+
+	CREATE TABLE IF NOT EXISTS fetch_run_images (
+		run_id INTEGER NOT NULL,
+		image_id INTEGER NOT NULL,
+		source_id TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY(run_id, image_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_fetch_run_images_image ON fetch_run_images(image_id);
+
+	CREATE TABLE IF NOT EXISTS wallpaper_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		image_id INTEGER NOT NULL DEFAULT 0,
+		path TEXT NOT NULL,
+		backend TEXT NOT NULL,
+		monitor TEXT NOT NULL DEFAULT '',
+		set_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_wallpaper_history_image ON wallpaper_history(image_id, set_at);
+
+	CREATE TABLE IF NOT EXISTS file_states (
+		path TEXT PRIMARY KEY,
+		size INTEGER NOT NULL,
+		mod_time INTEGER NOT NULL,
+		inode INTEGER NOT NULL DEFAULT 0,
+		checksum TEXT NOT NULL
+	);
+	`
+
+	_, err := db.conn.Exec(schema)
+	if err != nil {
+		return err
+	}
+
+	// Migrations: add columns that didn't exist in earlier versions.
+	// Each statement fails silently if the column already exists.
+	migrations := []string{
+		`ALTER TABLE images ADD COLUMN favorite BOOLEAN DEFAULT FALSE`,
+		`ALTER TABLE images ADD COLUMN width INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN height INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN category TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN purity TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN file_type TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN colors TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN views INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN source_favorites INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN uploader TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN page_url TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN deleted_at DATETIME`,
+		`ALTER TABLE images ADD COLUMN trash_path TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE wallpaper_history ADD COLUMN monitor TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN palette TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN luminance REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN brightness TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN phash TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN dhash TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE images ADD COLUMN link_group INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN rating INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN root TEXT NOT NULL DEFAULT ''`,
+	}
+	for _, migration := range migrations {
+		_, _ = db.conn.Exec(migration)
+	}
+
+	if err := db.relaxChecksumConstraint(); err != nil {
+		return fmt.Errorf("failed to migrate checksum constraint: %w", err)
+	}
+
+	// Indexes on migrated columns can only be created once the columns exist.
+	// Checksums are unique except among copies linked by dedupe --link.
+	indexes := `
 	CREATE INDEX IF NOT EXISTS idx_source ON images(source);
 	CREATE INDEX IF NOT EXISTS idx_checksum ON images(checksum);
 	CREATE INDEX IF NOT EXISTS idx_downloaded_at ON images(downloaded_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_checksum_unlinked ON images(checksum) WHERE link_group = 0;
 	CREATE INDEX IF NOT EXISTS idx_favorite ON images(favorite);
+	CREATE INDEX IF NOT EXISTS idx_category ON images(category);
+	CREATE INDEX IF NOT EXISTS idx_purity ON images(purity);
+	CREATE INDEX IF NOT EXISTS idx_dimensions ON images(width, height);
+	CREATE INDEX IF NOT EXISTS idx_deleted_at ON images(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_brightness ON images(brightness);
+	CREATE INDEX IF NOT EXISTS idx_link_group ON images(link_group);
 	`
-
-	_, err := db.conn.Exec(schema)
-	if err != nil {
+	if _, err := db.conn.Exec(indexes); err != nil {
 		return err
 	}
 
-	// Migration: add favorite column if it doesn't exist (for backward compatibility)
-	migration := `
-	ALTER TABLE images ADD COLUMN favorite BOOLEAN DEFAULT FALSE;
+	// Backfill width/height for rows that only have a "WxH" resolution string
+	backfill := `
+	UPDATE images SET
+		width = CAST(substr(resolution, 1, instr(resolution, 'x') - 1) AS INTEGER),
+		height = CAST(substr(resolution, instr(resolution, 'x') + 1) AS INTEGER)
+	WHERE width = 0 AND resolution GLOB '[0-9]*x[0-9]*'
 	`
-	// This will fail silently if the column already exists
-	_, _ = db.conn.Exec(migration)
+	if _, err := db.conn.Exec(backfill); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// relaxChecksumConstraint rebuilds the images table of databases whose
+// checksums are unique table-wide, as created before copies could be linked
+// together. SQLite cannot drop a table constraint in place; uniqueness is
+// enforced by the partial idx_checksum_unlinked index instead.
+func (db *DB) relaxChecksumConstraint() error {
+	var schema string
+	err := db.conn.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'images'`).Scan(&schema)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(schema, "UNIQUE(checksum)") {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rebuild := []string{`
+	CREATE TABLE images_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		source_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		local_path TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		tags TEXT,
+		resolution TEXT,
+		file_size INTEGER,
+		downloaded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		favorite BOOLEAN DEFAULT FALSE,
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		category TEXT NOT NULL DEFAULT '',
+		purity TEXT NOT NULL DEFAULT '',
+		file_type TEXT NOT NULL DEFAULT '',
+		colors TEXT NOT NULL DEFAULT '',
+		views INTEGER NOT NULL DEFAULT 0,
+		source_favorites INTEGER NOT NULL DEFAULT 0,
+		uploader TEXT NOT NULL DEFAULT '',
+		page_url TEXT NOT NULL DEFAULT '',
+		deleted_at DATETIME,
+		trash_path TEXT NOT NULL DEFAULT '',
+		palette TEXT NOT NULL DEFAULT '',
+		luminance REAL NOT NULL DEFAULT 0,
+		brightness TEXT NOT NULL DEFAULT '',
+		phash TEXT NOT NULL DEFAULT '',
+		dhash TEXT NOT NULL DEFAULT '',
+		link_group INTEGER NOT NULL DEFAULT 0,
+		rating INTEGER NOT NULL DEFAULT 0,
+		root TEXT NOT NULL DEFAULT '',
+		UNIQUE(source, source_id)
+	)`,
+		`INSERT INTO images_new (` + imageColumns + `) SELECT ` + imageColumns + ` FROM images`,
+		`DROP TABLE images`,
+		`ALTER TABLE images_new RENAME TO images`,
+	}
+	for _, stmt := range rebuild {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // InsertImage inserts a new image record
 func (db *DB) InsertImage(img *Image) error {
 	query := `
-	INSERT INTO images (source, source_id, url, local_path, checksum, tags, resolution, file_size, favorite)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO images (source, source_id, url, root, local_path, checksum, tags, resolution, file_size, favorite,
+		width, height, category, purity, file_type, colors, views, source_favorites, uploader, page_url,
+		palette, luminance, brightness, phash, dhash)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := db.conn.Exec(query, img.Source, img.SourceID, img.URL, img.LocalPath,
-		img.Checksum, img.Tags, img.Resolution, img.FileSize, img.Favorite)
-	return err
+	root, path := db.relative(img.LocalPath)
+	result, err := db.conn.Exec(query, img.Source, img.SourceID, img.URL, root, path,
+		img.Checksum, img.Tags, img.Resolution, img.FileSize, img.Favorite,
+		img.Width, img.Height, img.Category, img.Purity, img.FileType, img.Colors,
+		img.Views, img.SourceFavorites, img.Uploader, img.PageURL,
+		img.Palette, img.Luminance, img.Brightness, img.PHash, img.DHash)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	img.ID = int(id)
+
+	return nil
 }
 
 // ExistsBySourceID checks if an image exists by source and source ID
@@ -118,6 +393,12 @@ func (db *DB) ExistsBySourceID(source, sourceID string) (bool, error) {
 	return count > 0, err
 }
 
+// GetImageBySourceID gets an image by source and source ID
+func (db *DB) GetImageBySourceID(source, sourceID string) (*Image, error) {
+	query := `SELECT ` + imageColumns + ` FROM images WHERE source = ? AND source_id = ?`
+	return db.scanImage(db.conn.QueryRow(query, source, sourceID))
+}
+
 // ExistsByChecksum checks if an image exists by checksum
 func (db *DB) ExistsByChecksum(checksum string) (bool, error) {
 	query := `SELECT COUNT(*) FROM images WHERE checksum = ?`
@@ -126,21 +407,120 @@ func (db *DB) ExistsByChecksum(checksum string) (bool, error) {
 	return count > 0, err
 }
 
+// ImagesByChecksum returns the images in the library with a checksum
+func (db *DB) ImagesByChecksum(checksum string) ([]Image, error) {
+	query := `SELECT ` + imageColumns + ` FROM images WHERE checksum = ? AND deleted_at IS NULL ORDER BY id`
+	rows, err := db.conn.Query(query, checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return db.scanImages(rows)
+}
+
+// ImageQuery describes filtering and ordering options for QueryImages
+type ImageQuery struct {
+	Source        string
+	Category      string
+	Purity        string
+	FileType      string
+	MinWidth      int
+	MinHeight     int
+	FavoritesOnly bool
+	Brightness    string   // light or dark
+	Tags          []string // every tag must be present
+	PathPrefix    string   // only images stored under this directory
+	SortBy        string   // downloaded_at, resolution, views, favorites, file_size, random
+	Ascending     bool
+	Limit         int
+}
+
+// imageSortColumns maps ImageQuery.SortBy values to ORDER BY expressions
+var imageSortColumns = map[string]string{
+	"":              "downloaded_at",
+	"downloaded_at": "downloaded_at",
+	"resolution":    "width * height",
+	"views":         "views",
+	"favorites":     "source_favorites",
+	"file_size":     "file_size",
+	"random":        "RANDOM()",
+}
+
+// ValidImageSorts returns the accepted ImageQuery.SortBy values
+func ValidImageSorts() []string {
+	return []string{"downloaded_at", "resolution", "views", "favorites", "file_size", "random"}
+}
+
 // ListImages lists images with optional filtering
 func (db *DB) ListImages(source string, limit int) ([]Image, error) {
-	query := `SELECT id, source, source_id, url, local_path, checksum, tags, resolution, file_size, downloaded_at, favorite FROM images`
+	return db.QueryImages(ImageQuery{Source: source, Limit: limit})
+}
+
+// QueryImages lists images matching the given query
+func (db *DB) QueryImages(q ImageQuery) ([]Image, error) {
+	orderBy, ok := imageSortColumns[q.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort field: %s", q.SortBy)
+	}
+
+	query := `SELECT ` + imageColumns + ` FROM images`
+	conditions := []string{`deleted_at IS NULL`}
 	args := []interface{}{}
 
-	if source != "" {
-		query += ` WHERE source = ?`
-		args = append(args, source)
+	if q.Source != "" {
+		conditions = append(conditions, `source = ?`)
+		args = append(args, q.Source)
+	}
+	if q.Category != "" {
+		conditions = append(conditions, `category = ?`)
+		args = append(args, q.Category)
+	}
+	if q.Purity != "" {
+		conditions = append(conditions, `purity = ?`)
+		args = append(args, q.Purity)
+	}
+	if q.FileType != "" {
+		conditions = append(conditions, `file_type = ?`)
+		args = append(args, q.FileType)
+	}
+	if q.MinWidth > 0 {
+		conditions = append(conditions, `width >= ?`)
+		args = append(args, q.MinWidth)
+	}
+	if q.MinHeight > 0 {
+		conditions = append(conditions, `height >= ?`)
+		args = append(args, q.MinHeight)
+	}
+	if q.FavoritesOnly {
+		conditions = append(conditions, `favorite = TRUE`)
+	}
+	if q.Brightness != "" {
+		conditions = append(conditions, `brightness = ?`)
+		args = append(args, q.Brightness)
+	}
+	for _, tag := range q.Tags {
+		// Tags are stored comma-separated; match whole entries only
+		conditions = append(conditions, `(',' || tags || ',') LIKE ? ESCAPE '\'`)
+		args = append(args, "%,"+escapeLike(strings.TrimSpace(tag))+",%")
+	}
+	if q.PathPrefix != "" {
+		condition, prefixArgs := db.underCondition(q.PathPrefix)
+		conditions = append(conditions, condition)
+		args = append(args, prefixArgs...)
 	}
 
-	query += ` ORDER BY downloaded_at DESC`
+	query += ` WHERE ` + strings.Join(conditions, ` AND `)
 
-	if limit > 0 {
+	direction := "DESC"
+	if q.Ascending {
+		direction = "ASC"
+	}
+	query += ` ORDER BY ` + orderBy + ` ` + direction
+
+	if q.Limit > 0 {
 		query += ` LIMIT ?`
-		args = append(args, limit)
+		args = append(args, q.Limit)
 	}
 
 	rows, err := db.conn.Query(query, args...)
@@ -149,75 +529,30 @@ func (db *DB) ListImages(source string, limit int) ([]Image, error) {
 	}
 	defer rows.Close()
 
-	var images []Image
-	for rows.Next() {
-		var img Image
-		err := rows.Scan(&img.ID, &img.Source, &img.SourceID, &img.URL, &img.LocalPath,
-			&img.Checksum, &img.Tags, &img.Resolution, &img.FileSize, &img.DownloadedAt, &img.Favorite)
-		if err != nil {
-			return nil, err
-		}
-		images = append(images, img)
-	}
+	return db.scanImages(rows)
+}
 
-	return images, rows.Err()
+// escapeLike escapes LIKE wildcards so s matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
 
-// CountImages returns the total number of images
+// CountImages returns the total number of images, excluding trashed ones
 func (db *DB) CountImages() (int, error) {
-	query := `SELECT COUNT(*) FROM images`
+	query := `SELECT COUNT(*) FROM images WHERE deleted_at IS NULL`
 	var count int
 	err := db.conn.QueryRow(query).Scan(&count)
 	return count, err
 }
 
-// DeleteOldImages deletes old images keeping only the most recent ones
-func (db *DB) DeleteOldImages(keepCount int) ([]string, error) {
-	// First, get the paths of images that will be deleted
-	query := `
-	SELECT local_path FROM images 
-	ORDER BY downloaded_at DESC 
-	LIMIT -1 OFFSET ?
-	`
-	rows, err := db.conn.Query(query, keepCount)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var paths []string
-	for rows.Next() {
-		var path string
-		if err := rows.Scan(&path); err != nil {
-			return nil, err
-		}
-		paths = append(paths, path)
-	}
-
-	// Delete the old records
-	deleteQuery := `
-	DELETE FROM images 
-	WHERE id NOT IN (
-		SELECT id FROM images 
-		ORDER BY downloaded_at DESC 
-		LIMIT ?
-	)
-	`
-	_, err = db.conn.Exec(deleteQuery, keepCount)
-	if err != nil {
-		return nil, err
-	}
-
-	return paths, nil
-}
-
 // FindDuplicates finds duplicate images by checksum
 func (db *DB) FindDuplicates() ([][]Image, error) {
 	query := `
-	SELECT id, source, source_id, url, local_path, checksum, tags, resolution, file_size, downloaded_at, favorite
+	SELECT ` + imageColumns + `
 	FROM images
-	WHERE checksum IN (
+	WHERE deleted_at IS NULL AND checksum IN (
 		SELECT checksum FROM images
+		WHERE deleted_at IS NULL
 		GROUP BY checksum
 		HAVING COUNT(*) > 1
 	)
@@ -232,13 +567,11 @@ func (db *DB) FindDuplicates() ([][]Image, error) {
 
 	duplicateGroups := make(map[string][]Image)
 	for rows.Next() {
-		var img Image
-		err := rows.Scan(&img.ID, &img.Source, &img.SourceID, &img.URL, &img.LocalPath,
-			&img.Checksum, &img.Tags, &img.Resolution, &img.FileSize, &img.DownloadedAt, &img.Favorite)
+		img, err := db.scanImage(rows)
 		if err != nil {
 			return nil, err
 		}
-		duplicateGroups[img.Checksum] = append(duplicateGroups[img.Checksum], img)
+		duplicateGroups[img.Checksum] = append(duplicateGroups[img.Checksum], *img)
 	}
 
 	var result [][]Image
@@ -252,12 +585,13 @@ func (db *DB) FindDuplicates() ([][]Image, error) {
 // DeleteImage deletes an image by ID and returns the local path
 func (db *DB) DeleteImage(id int) (string, error) {
 	// First get the local path
-	var localPath string
-	query := `SELECT local_path FROM images WHERE id = ?`
-	err := db.conn.QueryRow(query, id).Scan(&localPath)
+	var root, localPath string
+	query := `SELECT root, local_path FROM images WHERE id = ?`
+	err := db.conn.QueryRow(query, id).Scan(&root, &localPath)
 	if err != nil {
 		return "", err
 	}
+	localPath, _ = db.absolute(root, localPath)
 
 	// Delete from database
 	deleteQuery := `DELETE FROM images WHERE id = ?`
@@ -272,12 +606,13 @@ func (db *DB) DeleteImage(id int) (string, error) {
 // DeleteImageBySourceID deletes an image by source and source_id
 func (db *DB) DeleteImageBySourceID(source, sourceID string) (string, error) {
 	// First get the local path
-	var localPath string
-	query := `SELECT local_path FROM images WHERE source = ? AND source_id = ?`
-	err := db.conn.QueryRow(query, source, sourceID).Scan(&localPath)
+	var root, localPath string
+	query := `SELECT root, local_path FROM images WHERE source = ? AND source_id = ?`
+	err := db.conn.QueryRow(query, source, sourceID).Scan(&root, &localPath)
 	if err != nil {
 		return "", err
 	}
+	localPath, _ = db.absolute(root, localPath)
 
 	// Delete from database
 	deleteQuery := `DELETE FROM images WHERE source = ? AND source_id = ?`
@@ -289,40 +624,17 @@ func (db *DB) DeleteImageBySourceID(source, sourceID string) (string, error) {
 	return localPath, nil
 }
 
-// CleanupMissingFiles removes database entries for files that no longer exist on disk
-func (db *DB) CleanupMissingFiles() ([]string, error) {
-	// Get all images
-	images, err := db.ListImages("", 0)
-	if err != nil {
-		return nil, err
-	}
-
-	var deletedPaths []string
-	for _, img := range images {
-		// Check if file exists
-		if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
-			// File doesn't exist, remove from database
-			_, err = db.conn.Exec(`DELETE FROM images WHERE id = ?`, img.ID)
-			if err != nil {
-				return deletedPaths, fmt.Errorf("failed to delete image %d: %w", img.ID, err)
-			}
-			deletedPaths = append(deletedPaths, img.LocalPath)
-		}
-	}
-
-	return deletedPaths, nil
-}
-
 // GetImageByID gets an image by ID
 func (db *DB) GetImageByID(id int) (*Image, error) {
-	query := `SELECT id, source, source_id, url, local_path, checksum, tags, resolution, file_size, downloaded_at, favorite FROM images WHERE id = ?`
-	var img Image
-	err := db.conn.QueryRow(query, id).Scan(&img.ID, &img.Source, &img.SourceID, &img.URL, &img.LocalPath,
-		&img.Checksum, &img.Tags, &img.Resolution, &img.FileSize, &img.DownloadedAt, &img.Favorite)
-	if err != nil {
-		return nil, err
-	}
-	return &img, nil
+	query := `SELECT ` + imageColumns + ` FROM images WHERE id = ?`
+	return db.scanImage(db.conn.QueryRow(query, id))
+}
+
+// GetImageByPath gets a non-trashed image by its local path
+func (db *DB) GetImageByPath(path string) (*Image, error) {
+	root, stored := db.relative(path)
+	query := `SELECT ` + imageColumns + ` FROM images WHERE root = ? AND local_path = ? AND deleted_at IS NULL`
+	return db.scanImage(db.conn.QueryRow(query, root, stored))
 }
 
 // ToggleFavorite toggles the favorite status of an image
@@ -365,40 +677,106 @@ func (db *DB) SetFavorite(id int, favorite bool) error {
 	return nil
 }
 
-// ListFavorites lists all favorite images
-func (db *DB) ListFavorites(limit int) ([]Image, error) {
-	query := `SELECT id, source, source_id, url, local_path, checksum, tags, resolution, file_size, downloaded_at, favorite FROM images WHERE favorite = TRUE ORDER BY downloaded_at DESC`
-	args := []interface{}{}
+// SetLocalPath records that an image's file is now at path
+func (db *DB) SetLocalPath(id int, path string) error {
+	root, stored := db.relative(path)
+	return db.execOne(`UPDATE images SET root = ?, local_path = ? WHERE id = ?`, id, root, stored, id)
+}
 
-	if limit > 0 {
-		query += ` LIMIT ?`
-		args = append(args, limit)
+// RelocatePaths moves every image stored under oldPrefix, trashed ones
+// included, to the same relative path under newPrefix and returns how many
+// were moved. Both prefixes are directories without a trailing slash.
+func (db *DB) RelocatePaths(oldPrefix, newPrefix string) (int64, error) {
+	condition, args := db.underCondition(oldPrefix)
+	rows, err := db.conn.Query(`SELECT id, root, local_path FROM images WHERE `+condition, args...)
+	if err != nil {
+		return 0, err
+	}
+	moved := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var root, path string
+		if err := rows.Scan(&id, &root, &path); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		path, _ = db.absolute(root, path)
+		if rel, ok := under(path, oldPrefix); ok {
+			moved[id] = filepath.Join(newPrefix, rel)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
 	}
 
-	rows, err := db.conn.Query(query, args...)
+	tx, err := db.conn.Begin()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var images []Image
-	for rows.Next() {
-		var img Image
-		err := rows.Scan(&img.ID, &img.Source, &img.SourceID, &img.URL, &img.LocalPath,
-			&img.Checksum, &img.Tags, &img.Resolution, &img.FileSize, &img.DownloadedAt, &img.Favorite)
-		if err != nil {
-			return nil, err
+	for id, path := range moved {
+		root, stored := db.relative(path)
+		if _, err := tx.Exec(`UPDATE images SET root = ?, local_path = ? WHERE id = ?`, root, stored, id); err != nil {
+			return 0, err
 		}
-		images = append(images, img)
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(moved)), nil
+}
+
+// SetFileInfo records the checksum and size of an image's file after it
+// changed on disk
+func (db *DB) SetFileInfo(id int, checksum string, size int64) error {
+	return db.execOne(`UPDATE images SET checksum = ?, file_size = ? WHERE id = ?`, id, checksum, size, id)
+}
+
+// SetRating sets the rating of an image, from 1 to 5 or 0 to clear it
+func (db *DB) SetRating(id, rating int) error {
+	if rating < 0 || rating > 5 {
+		return fmt.Errorf("invalid rating %d: must be between 0 and 5", rating)
+	}
+	return db.execOne(`UPDATE images SET rating = ? WHERE id = ?`, id, rating, id)
+}
 
-	return images, rows.Err()
+// SetAnalysis stores the computed palette, brightness and perceptual
+// hashes of an image
+func (db *DB) SetAnalysis(img *Image) error {
+	query := `UPDATE images SET palette = ?, luminance = ?, brightness = ?, phash = ?, dhash = ? WHERE id = ?`
+	return db.execOne(query, img.ID, img.Palette, img.Luminance, img.Brightness, img.PHash, img.DHash, img.ID)
+}
+
+// ListFavorites lists all favorite images
+func (db *DB) ListFavorites(limit int) ([]Image, error) {
+	return db.QueryImages(ImageQuery{FavoritesOnly: true, Limit: limit})
 }
 
 // CountFavorites returns the number of favorite images
 func (db *DB) CountFavorites() (int, error) {
-	query := `SELECT COUNT(*) FROM images WHERE favorite = TRUE`
+	query := `SELECT COUNT(*) FROM images WHERE favorite = TRUE AND deleted_at IS NULL`
 	var count int
 	err := db.conn.QueryRow(query).Scan(&count)
 	return count, err
 }
+
+// execOne executes a statement that must affect exactly the image with the given ID
+func (db *DB) execOne(query string, id int, args ...interface{}) error {
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("image with ID %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package database
+
+// FileState is what import recorded about a file it processed, so the file
+// is not read again while its size, modification time and inode stay the same
+type FileState struct {
+	Path     string
+	Size     int64
+	ModTime  int64 // nanoseconds since the Unix epoch
+	Inode    uint64
+	Checksum string
+}
+
+// Same reports whether two states describe the same unchanged file
+func (s FileState) Same(other FileState) bool {
+	return s.Size == other.Size && s.ModTime == other.ModTime && s.Inode == other.Inode
+}
+
+// FileStates returns the recorded states of the files under dir by path
+func (db *DB) FileStates(dir string) (map[string]FileState, error) {
+	query := `SELECT path, size, mod_time, inode, checksum FROM file_states WHERE instr(path, ?) = 1`
+	rows, err := db.conn.Query(query, dir+"/")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]FileState)
+	for rows.Next() {
+		var s FileState
+		var inode int64
+		if err := rows.Scan(&s.Path, &s.Size, &s.ModTime, &inode, &s.Checksum); err != nil {
+			return nil, err
+		}
+		s.Inode = uint64(inode)
+		states[s.Path] = s
+	}
+	return states, rows.Err()
+}
+
+// SaveFileStates records the states of processed files
+func (db *DB) SaveFileStates(states []FileState) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT OR REPLACE INTO file_states (path, size, mod_time, inode, checksum) VALUES (?, ?, ?, ?, ?)`
+	for _, s := range states {
+		if _, err := tx.Exec(query, s.Path, s.Size, s.ModTime, int64(s.Inode), s.Checksum); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ForgetFileStates removes the recorded states of files that are gone
+func (db *DB) ForgetFileStates(paths []string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, path := range paths {
+		if _, err := tx.Exec(`DELETE FROM file_states WHERE path = ?`, path); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
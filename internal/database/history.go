@@ -0,0 +1,170 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Fetch run statuses
+const (
+	RunStatusRunning   = "running"
+	RunStatusCompleted = "completed"
+	RunStatusFailed    = "failed"
+	RunStatusUndone    = "undone"
+)
+
+// FetchRun represents a single invocation of the fetch command
+type FetchRun struct {
+	ID             int               `json:"id"`
+	Source         string            `json:"source"`
+	Params         map[string]string `json:"params"`
+	Status         string            `json:"status"`
+	StartedAt      time.Time         `json:"started_at"`
+	FinishedAt     *time.Time        `json:"finished_at,omitempty"`
+	PagesProcessed int               `json:"pages_processed"`
+	Downloaded     int               `json:"downloaded"`
+	Skipped        int               `json:"skipped"`
+	Failed         int               `json:"failed"`
+	SkipReasons    map[string]int    `json:"skip_reasons"`
+	Errors         []string          `json:"errors"`
+}
+
+// fetchRunColumns is the column list matching scanFetchRun
+const fetchRunColumns = `id, source, params, status, started_at, finished_at, pages_processed,
+	downloaded, skipped, failed, skip_reasons, errors`
+
+// StartFetchRun records the start of a fetch run
+func (db *DB) StartFetchRun(source string, params map[string]string) (*FetchRun, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := db.conn.Exec(`INSERT INTO fetch_runs (source, params) VALUES (?, ?)`, source, string(paramsJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetFetchRun(int(id))
+}
+
+// FinishFetchRun stores the final counters and status of a fetch run
+func (db *DB) FinishFetchRun(run *FetchRun) error {
+	skipReasons, err := json.Marshal(run.SkipReasons)
+	if err != nil {
+		return err
+	}
+	errors, err := json.Marshal(run.Errors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE fetch_runs SET status = ?, finished_at = CURRENT_TIMESTAMP, pages_processed = ?,
+		downloaded = ?, skipped = ?, failed = ?, skip_reasons = ?, errors = ?
+	WHERE id = ?
+	`
+	_, err = db.conn.Exec(query, run.Status, run.PagesProcessed, run.Downloaded, run.Skipped,
+		run.Failed, string(skipReasons), string(errors), run.ID)
+	return err
+}
+
+// SetFetchRunStatus updates only the status of a fetch run
+func (db *DB) SetFetchRunStatus(id int, status string) error {
+	return db.execOne(`UPDATE fetch_runs SET status = ? WHERE id = ?`, id, status, id)
+}
+
+// AddFetchRunImage records that a fetch run added an image
+func (db *DB) AddFetchRunImage(runID, imageID int, sourceID string) error {
+	query := `INSERT OR IGNORE INTO fetch_run_images (run_id, image_id, source_id) VALUES (?, ?, ?)`
+	_, err := db.conn.Exec(query, runID, imageID, sourceID)
+	return err
+}
+
+// GetFetchRun gets a fetch run by ID
+func (db *DB) GetFetchRun(id int) (*FetchRun, error) {
+	query := `SELECT ` + fetchRunColumns + ` FROM fetch_runs WHERE id = ?`
+	return scanFetchRun(db.conn.QueryRow(query, id))
+}
+
+// ListFetchRuns lists fetch runs, most recent first
+func (db *DB) ListFetchRuns(limit int) ([]FetchRun, error) {
+	query := `SELECT ` + fetchRunColumns + ` FROM fetch_runs ORDER BY id DESC`
+	args := []interface{}{}
+
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []FetchRun
+	for rows.Next() {
+		run, err := scanFetchRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+
+	return runs, rows.Err()
+}
+
+// ListFetchRunImages lists the images a fetch run added that are still
+// tracked, including trashed ones
+func (db *DB) ListFetchRunImages(runID int) ([]Image, error) {
+	query := `
+	SELECT ` + imageColumns + ` FROM images
+	WHERE id IN (SELECT image_id FROM fetch_run_images WHERE run_id = ?)
+	ORDER BY id
+	`
+	rows, err := db.conn.Query(query, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return db.scanImages(rows)
+}
+
+// scanFetchRun scans a row selected with fetchRunColumns into a FetchRun
+func scanFetchRun(row rowScanner) (*FetchRun, error) {
+	var run FetchRun
+	var params, skipReasons, errors string
+	var finishedAt sql.NullTime
+
+	err := row.Scan(&run.ID, &run.Source, &params, &run.Status, &run.StartedAt, &finishedAt,
+		&run.PagesProcessed, &run.Downloaded, &run.Skipped, &run.Failed, &skipReasons, &errors)
+	if err != nil {
+		return nil, err
+	}
+
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	if err := json.Unmarshal([]byte(params), &run.Params); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(skipReasons), &run.SkipReasons); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(errors), &run.Errors); err != nil {
+		return nil, err
+	}
+	if run.SkipReasons == nil {
+		run.SkipReasons = make(map[string]int)
+	}
+
+	return &run, nil
+}
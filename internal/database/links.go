@@ -0,0 +1,76 @@
+package database
+
+// LinkImages records that the files of the linked images now share the data
+// of the survivor's file. They take over its content metadata — checksum,
+// size, dimensions and analysis — and join its link group, or start one
+// named after it. Their local paths are stored as set by the caller. The
+// images are updated in place.
+func (db *DB) LinkImages(survivor *Image, linked []*Image) error {
+	group := survivor.LinkGroup
+	if group == 0 {
+		group = survivor.ID
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE images SET link_group = ? WHERE id = ?`, group, survivor.ID); err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE images SET root = ?, local_path = ?, checksum = ?, resolution = ?, file_size = ?, width = ?, height = ?,
+		file_type = ?, palette = ?, luminance = ?, brightness = ?, phash = ?, dhash = ?, link_group = ?
+	WHERE id = ?
+	`
+	for _, img := range linked {
+		root, path := db.relative(img.LocalPath)
+		_, err := tx.Exec(query, root, path, survivor.Checksum, survivor.Resolution, survivor.FileSize,
+			survivor.Width, survivor.Height, survivor.FileType, survivor.Palette, survivor.Luminance,
+			survivor.Brightness, survivor.PHash, survivor.DHash, group, img.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	survivor.LinkGroup = group
+	for _, img := range linked {
+		img.Checksum = survivor.Checksum
+		img.Resolution = survivor.Resolution
+		img.FileSize = survivor.FileSize
+		img.Width = survivor.Width
+		img.Height = survivor.Height
+		img.FileType = survivor.FileType
+		img.Palette = survivor.Palette
+		img.Luminance = survivor.Luminance
+		img.Brightness = survivor.Brightness
+		img.PHash = survivor.PHash
+		img.DHash = survivor.DHash
+		img.LinkGroup = group
+	}
+	return nil
+}
+
+// LinkedImages returns the other images in the library whose files share
+// data with img, because dedupe --link linked them together
+func (db *DB) LinkedImages(img *Image) ([]Image, error) {
+	if img.LinkGroup == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT ` + imageColumns + ` FROM images WHERE link_group = ? AND id != ? AND deleted_at IS NULL ORDER BY id`
+	rows, err := db.conn.Query(query, img.LinkGroup, img.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return db.scanImages(rows)
+}
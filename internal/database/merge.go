@@ -0,0 +1,68 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeDuplicates folds what the user attached to duplicate images into the
+// image that survives deduplication: tags are combined, the favorite flag
+// and the highest rating carry over and the wallpaper history is pointed at the survivor so
+// rotation still knows when it was last shown. The duplicates themselves
+// are left for the caller to delete. survivor is updated in place.
+func (db *DB) MergeDuplicates(survivor *Image, duplicates []Image) error {
+	tags := survivor.Tags
+	favorite := survivor.Favorite
+	rating := survivor.Rating
+	for _, dup := range duplicates {
+		tags = mergeTags(tags, dup.Tags)
+		favorite = favorite || dup.Favorite
+		rating = max(rating, dup.Rating)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE images SET tags = ?, favorite = ?, rating = ? WHERE id = ?`, tags, favorite, rating, survivor.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("image with ID %d not found", survivor.ID)
+	}
+
+	for _, dup := range duplicates {
+		if _, err := tx.Exec(`UPDATE wallpaper_history SET image_id = ? WHERE image_id = ?`, survivor.ID, dup.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	survivor.Tags = tags
+	survivor.Favorite = favorite
+	survivor.Rating = rating
+	return nil
+}
+
+// mergeTags appends the comma-separated tags of b that a lacks
+func mergeTags(a, b string) string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, tag := range strings.Split(a+","+b, ",") {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, tag)
+	}
+	return strings.Join(merged, ",")
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Removal takes one image out of the library inside a transaction, so the
+// caller can move or delete its file between the database changes and the
+// commit and roll back if the file operation fails
+type Removal struct {
+	tx  *sql.Tx
+	img *Image
+}
+
+// BeginRemoval starts removing an image. The caller must Commit or
+// Rollback the removal; Rollback after Commit does nothing.
+func (db *DB) BeginRemoval(img *Image) (*Removal, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Removal{tx: tx, img: img}, nil
+}
+
+// Trash soft-deletes the image, recording where its file was moved to
+func (r *Removal) Trash(trashPath string) error {
+	query := `UPDATE images SET deleted_at = CURRENT_TIMESTAMP, trash_path = ? WHERE id = ? AND deleted_at IS NULL`
+	return r.execOne(query, trashPath, r.img.ID)
+}
+
+// Delete deletes the image's row
+func (r *Removal) Delete() error {
+	return r.execOne(`DELETE FROM images WHERE id = ?`, r.img.ID)
+}
+
+// Block adds the image to the blocklist
+func (r *Removal) Block(reason string) error {
+	_, err := r.tx.Exec(blockQuery, r.img.Source, r.img.SourceID, r.img.Checksum, reason)
+	return err
+}
+
+// Commit makes the removal permanent
+func (r *Removal) Commit() error {
+	return r.tx.Commit()
+}
+
+// Rollback abandons the removal
+func (r *Removal) Rollback() error {
+	err := r.tx.Rollback()
+	if err == sql.ErrTxDone {
+		return nil
+	}
+	return err
+}
+
+// execOne executes a statement that must affect the image being removed
+func (r *Removal) execOne(query string, args ...interface{}) error {
+	result, err := r.tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("image with ID %d not found", r.img.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// root is a named directory that image paths are stored relative to, so
+// the library survives the directory moving or being mounted elsewhere
+type root struct {
+	name string
+	path string
+	// online is false when the directory does not exist, e.g. a removable
+	// drive that is not mounted
+	online bool
+}
+
+// SetRoots sets the library roots, a map of names to directories. Paths of
+// images under a root are stored relative to it; absolute paths stored
+// before the root was set are converted. Images in roots that are offline
+// or no longer configured are marked Unavailable.
+func (db *DB) SetRoots(roots map[string]string) error {
+	db.roots = nil
+	for name, path := range roots {
+		if name == "" || path == "" {
+			continue
+		}
+		path = filepath.Clean(path)
+		if path == "/" {
+			continue
+		}
+		info, err := os.Stat(path)
+		db.roots = append(db.roots, root{name: name, path: path, online: err == nil && info.IsDir()})
+	}
+	// Innermost roots first, so nested roots take precedence
+	sort.Slice(db.roots, func(i, j int) bool {
+		if len(db.roots[i].path) != len(db.roots[j].path) {
+			return len(db.roots[i].path) > len(db.roots[j].path)
+		}
+		return db.roots[i].name < db.roots[j].name
+	})
+
+	// Paths are converted in Go rather than with substr, which counts
+	// characters where len counts bytes
+	rows, err := db.conn.Query(`SELECT id, local_path FROM images WHERE root = ''`)
+	if err != nil {
+		return err
+	}
+	type converted struct {
+		id         int
+		root, path string
+	}
+	var paths []converted
+	for rows.Next() {
+		var id int
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			rows.Close()
+			return err
+		}
+		if root, rel := db.relative(path); root != "" {
+			paths = append(paths, converted{id: id, root: root, path: rel})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range paths {
+		if _, err := tx.Exec(`UPDATE images SET root = ?, local_path = ? WHERE id = ?`, c.root, c.path, c.id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// relative returns the root and path to store for a file: the path
+// relative to the innermost root containing it, or the path itself and no
+// root if none does
+func (db *DB) relative(path string) (string, string) {
+	for _, r := range db.roots {
+		if rel, ok := under(path, r.path); ok {
+			return r.name, rel
+		}
+	}
+	return "", path
+}
+
+// absolute resolves a stored root and path. It reports false if the root
+// is offline or not configured; the path of an unconfigured root is
+// returned as "name:path".
+func (db *DB) absolute(rootName, path string) (string, bool) {
+	if rootName == "" {
+		return path, true
+	}
+	for _, r := range db.roots {
+		if r.name == rootName {
+			return filepath.Join(r.path, path), r.online
+		}
+	}
+	return rootName + ":" + path, false
+}
+
+// resolve replaces the stored path of a scanned image with its absolute
+// path
+func (db *DB) resolve(img *Image) {
+	var ok bool
+	img.LocalPath, ok = db.absolute(img.Root, img.LocalPath)
+	img.Unavailable = !ok
+}
+
+// underCondition returns an SQL condition matching the images stored
+// under dir, whether their paths are absolute or relative to a root
+func (db *DB) underCondition(dir string) (string, []interface{}) {
+	dir = filepath.Clean(dir)
+	conditions := []string{`(root = '' AND instr(local_path, ?) = 1)`}
+	args := []interface{}{strings.TrimSuffix(dir, "/") + "/"}
+	for _, r := range db.roots {
+		if r.path == dir || strings.HasPrefix(r.path, dir+"/") || dir == "/" {
+			conditions = append(conditions, `root = ?`)
+			args = append(args, r.name)
+		} else if rel, ok := under(dir, r.path); ok {
+			conditions = append(conditions, `(root = ? AND instr(local_path, ?) = 1)`)
+			args = append(args, r.name, rel+"/")
+		}
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// under returns path relative to dir if it is inside it
+func under(path, dir string) (string, bool) {
+	if !strings.HasPrefix(path, dir+"/") {
+		return "", false
+	}
+	return path[len(dir)+1:], true
+}
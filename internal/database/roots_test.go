@@ -0,0 +1,54 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetRootsConvertsNonASCIIPaths(t *testing.T) {
+	dir := t.TempDir()
+	rootDir := filepath.Join(dir, "Bilder", "Hintergründe")
+	if err := os.MkdirAll(rootDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(filepath.Join(dir, "wallpapers.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	path := filepath.Join(rootDir, "ä", "a.jpg")
+	img := &Image{
+		Source:       "local",
+		SourceID:     "a",
+		LocalPath:    path,
+		Checksum:     "abc",
+		DownloadedAt: time.Now(),
+	}
+	if err := db.InsertImage(img); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetRoots(map[string]string{"pictures": rootDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	var root, stored string
+	if err := db.conn.QueryRow(`SELECT root, local_path FROM images WHERE id = ?`, img.ID).Scan(&root, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if root != "pictures" || stored != filepath.Join("ä", "a.jpg") {
+		t.Errorf("stored root %q path %q, want pictures ä/a.jpg", root, stored)
+	}
+
+	got, err := db.GetImageByPath(path)
+	if err != nil {
+		t.Fatalf("image not found by its path after conversion: %v", err)
+	}
+	if got.LocalPath != path || got.Unavailable {
+		t.Errorf("resolved path %q (unavailable %v), want %q", got.LocalPath, got.Unavailable, path)
+	}
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// StatBucket is a named group of images with its count and disk usage
+type StatBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	Size  int64  `json:"size"`
+}
+
+// LibraryStats summarizes the images in the library
+type LibraryStats struct {
+	TotalImages      int          `json:"total_images"`
+	TotalSize        int64        `json:"total_size"`
+	Favorites        int          `json:"favorites"`
+	FavoritesRatio   float64      `json:"favorites_ratio"`
+	Missing          int          `json:"missing"`
+	Unavailable      int          `json:"unavailable"`
+	Trashed          int          `json:"trashed"`
+	TrashedSize      int64        `json:"trashed_size"`
+	BySource         []StatBucket `json:"by_source"`
+	ByCategory       []StatBucket `json:"by_category"`
+	ByPurity         []StatBucket `json:"by_purity"`
+	ByResolution     []StatBucket `json:"by_resolution"`
+	ByAspectRatio    []StatBucket `json:"by_aspect_ratio"`
+	DownloadsPerWeek []StatBucket `json:"downloads_per_week"`
+	TopTags          []StatBucket `json:"top_tags"`
+	Largest          []Image      `json:"largest"`
+}
+
+// GetStats computes library statistics. topN limits the number of tags and
+// largest files returned; weeks limits the downloads-per-week history. Both
+// must be at least 1.
+func (db *DB) GetStats(topN, weeks int) (*LibraryStats, error) {
+	stats := &LibraryStats{}
+
+	images, err := db.ListImages("", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	bySource := newBucketCounter()
+	byCategory := newBucketCounter()
+	byPurity := newBucketCounter()
+	byResolution := newBucketCounter()
+	byAspect := newBucketCounter()
+	tags := newBucketCounter()
+
+	// Files linked together by dedupe --link store their data once
+	linkGroups := make(map[int]bool)
+
+	for _, img := range images {
+		stats.TotalImages++
+		if img.LinkGroup == 0 || !linkGroups[img.LinkGroup] {
+			stats.TotalSize += img.FileSize
+			linkGroups[img.LinkGroup] = true
+		}
+		if img.Favorite {
+			stats.Favorites++
+		}
+		if img.Unavailable {
+			stats.Unavailable++
+		} else if _, err := os.Stat(img.LocalPath); os.IsNotExist(err) {
+			stats.Missing++
+		}
+
+		bySource.add(img.Source, img.FileSize)
+		byCategory.add(orUnknown(img.Category), img.FileSize)
+		byPurity.add(orUnknown(img.Purity), img.FileSize)
+		byResolution.add(ResolutionBucket(img.Width, img.Height), img.FileSize)
+		byAspect.add(AspectRatioBucket(img.Width, img.Height), img.FileSize)
+
+		for _, tag := range strings.Split(img.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags.add(tag, 0)
+			}
+		}
+	}
+
+	if stats.TotalImages > 0 {
+		stats.FavoritesRatio = float64(stats.Favorites) / float64(stats.TotalImages)
+	}
+
+	stats.BySource = bySource.sorted(0)
+	stats.ByCategory = byCategory.sorted(0)
+	stats.ByPurity = byPurity.sorted(0)
+	stats.ByResolution = byResolution.sorted(0)
+	stats.ByAspectRatio = byAspect.sorted(0)
+	stats.TopTags = tags.sorted(topN)
+
+	// Largest files
+	largest, err := db.QueryImages(ImageQuery{SortBy: "file_size", Limit: topN})
+	if err != nil {
+		return nil, err
+	}
+	stats.Largest = largest
+
+	// Trash usage
+	err = db.conn.QueryRow(`SELECT COUNT(*), COALESCE(SUM(file_size), 0) FROM images WHERE deleted_at IS NOT NULL`).
+		Scan(&stats.Trashed, &stats.TrashedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Downloads per week, most recent first
+	query := `
+	SELECT strftime('%Y-W%W', downloaded_at) AS week, COUNT(*), COALESCE(SUM(file_size), 0)
+	FROM images
+	WHERE deleted_at IS NULL
+	GROUP BY week
+	ORDER BY week DESC
+	LIMIT ?
+	`
+	rows, err := db.conn.Query(query, weeks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b StatBucket
+		if err := rows.Scan(&b.Key, &b.Count, &b.Size); err != nil {
+			return nil, err
+		}
+		stats.DownloadsPerWeek = append(stats.DownloadsPerWeek, b)
+	}
+
+	return stats, rows.Err()
+}
+
+// ResolutionBucket groups image dimensions into common resolution classes
+func ResolutionBucket(width, height int) string {
+	if width <= 0 || height <= 0 {
+		return "unknown"
+	}
+
+	// Use the shorter side so portrait images land in the same classes
+	short := height
+	if width < height {
+		short = width
+	}
+
+	switch {
+	case short >= 4320:
+		return "8K+"
+	case short >= 2160:
+		return "4K"
+	case short >= 1440:
+		return "1440p"
+	case short >= 1080:
+		return "1080p"
+	case short >= 720:
+		return "720p"
+	default:
+		return "<720p"
+	}
+}
+
+// AspectRatioBucket groups image dimensions into common aspect ratios
+func AspectRatioBucket(width, height int) string {
+	if width <= 0 || height <= 0 {
+		return "unknown"
+	}
+
+	ratio := float64(width) / float64(height)
+	buckets := []struct {
+		name  string
+		ratio float64
+	}{
+		{"32x9", 32.0 / 9},
+		{"21x9", 21.0 / 9},
+		{"16x9", 16.0 / 9},
+		{"16x10", 16.0 / 10},
+		{"4x3", 4.0 / 3},
+		{"5x4", 5.0 / 4},
+		{"1x1", 1},
+		{"9x16", 9.0 / 16},
+	}
+
+	for _, b := range buckets {
+		if ratio >= b.ratio*0.97 && ratio <= b.ratio*1.03 {
+			return b.name
+		}
+	}
+
+	if ratio < 1 {
+		return "portrait (other)"
+	}
+	return "other"
+}
+
+// orUnknown substitutes "unknown" for empty values
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// bucketCounter accumulates StatBuckets by key
+type bucketCounter map[string]*StatBucket
+
+func newBucketCounter() bucketCounter {
+	return make(bucketCounter)
+}
+
+func (c bucketCounter) add(key string, size int64) {
+	b, ok := c[key]
+	if !ok {
+		b = &StatBucket{Key: key}
+		c[key] = b
+	}
+	b.Count++
+	b.Size += size
+}
+
+// sorted returns the buckets by descending count, limited to n when n > 0
+func (c bucketCounter) sorted(n int) []StatBucket {
+	result := make([]StatBucket, 0, len(c))
+	for _, b := range c {
+		result = append(result, *b)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Key < result[j].Key
+	})
+
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
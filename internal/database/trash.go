@@ -0,0 +1,19 @@
+package database
+
+// RestoreImage moves a trashed image back into the library
+func (db *DB) RestoreImage(id int) error {
+	query := `UPDATE images SET deleted_at = NULL, trash_path = '' WHERE id = ? AND deleted_at IS NOT NULL`
+	return db.execOne(query, id, id)
+}
+
+// ListTrashed lists trashed images, most recently deleted first
+func (db *DB) ListTrashed() ([]Image, error) {
+	query := `SELECT ` + imageColumns + ` FROM images WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return db.scanImages(rows)
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// WallpaperSet records a wallpaper being applied to the desktop
+type WallpaperSet struct {
+	ID      int       `json:"id"`
+	ImageID int       `json:"image_id"` // 0 for files not in the library
+	Path    string    `json:"path"`
+	Backend string    `json:"backend"`
+	Monitor string    `json:"monitor,omitempty"` // empty when applied to every monitor
+	SetAt   time.Time `json:"set_at"`
+}
+
+// wallpaperSetColumns is the column list matching scanWallpaperSets
+const wallpaperSetColumns = `id, image_id, path, backend, monitor, set_at`
+
+// RecordWallpaperSet records that a wallpaper was applied to a monitor, or
+// to every monitor when monitor is empty
+func (db *DB) RecordWallpaperSet(imageID int, path, backend, monitor string) error {
+	query := `INSERT INTO wallpaper_history (image_id, path, backend, monitor) VALUES (?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, imageID, path, backend, monitor)
+	return err
+}
+
+// ListWallpaperHistory lists applied wallpapers, most recent first
+func (db *DB) ListWallpaperHistory(limit int) ([]WallpaperSet, error) {
+	query := `SELECT ` + wallpaperSetColumns + ` FROM wallpaper_history ORDER BY set_at DESC, id DESC`
+	args := []interface{}{}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWallpaperSets(rows)
+}
+
+// CurrentWallpapers returns the most recently applied wallpaper for each
+// monitor name. The "" key holds the last wallpaper applied to every monitor.
+func (db *DB) CurrentWallpapers() (map[string]WallpaperSet, error) {
+	query := `
+	SELECT ` + wallpaperSetColumns + ` FROM wallpaper_history
+	WHERE id IN (SELECT MAX(id) FROM wallpaper_history GROUP BY monitor)
+	`
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets, err := scanWallpaperSets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]WallpaperSet)
+	for _, s := range sets {
+		current[s.Monitor] = s
+	}
+
+	// A wallpaper applied to every monitor replaces older per-monitor ones
+	if all, ok := current[""]; ok {
+		for monitor, s := range current {
+			if monitor != "" && s.ID < all.ID {
+				delete(current, monitor)
+			}
+		}
+	}
+	return current, nil
+}
+
+// RecentWallpaperImageIDs returns the library IDs of the most recently
+// applied wallpapers, most recent first and without duplicates
+func (db *DB) RecentWallpaperImageIDs(limit int) ([]int, error) {
+	query := `
+	SELECT image_id FROM wallpaper_history
+	WHERE image_id > 0
+	GROUP BY image_id
+	ORDER BY MAX(set_at) DESC, MAX(id) DESC
+	LIMIT ?
+	`
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ShownImageIDs returns the library IDs of every wallpaper that has ever
+// been applied
+func (db *DB) ShownImageIDs() (map[int]bool, error) {
+	rows, err := db.conn.Query(`SELECT DISTINCT image_id FROM wallpaper_history WHERE image_id > 0`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shown := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		shown[id] = true
+	}
+
+	return shown, rows.Err()
+}
+
+// scanWallpaperSets scans wallpaper history rows
+func scanWallpaperSets(rows *sql.Rows) ([]WallpaperSet, error) {
+	var sets []WallpaperSet
+	for rows.Next() {
+		var s WallpaperSet
+		if err := rows.Scan(&s.ID, &s.ImageID, &s.Path, &s.Backend, &s.Monitor, &s.SetAt); err != nil {
+			return nil, err
+		}
+		sets = append(sets, s)
+	}
+
+	return sets, rows.Err()
+}
@@ -0,0 +1,256 @@
+package desktop
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Feh sets the wallpaper on X11 with feh
+type Feh struct {
+	runner Runner
+}
+
+func (b *Feh) Name() string    { return "feh" }
+func (b *Feh) Available() bool { return installed(b.runner, "feh") }
+
+func (b *Feh) Set(path string) error {
+	return b.runner.Run("feh", "--no-fehbg", "--bg-fill", path)
+}
+
+// SetPerMonitor passes one image per screen; feh assigns them in Xinerama
+// order, which follows the left-to-right monitor order on most setups
+func (b *Feh) SetPerMonitor(assignments []Assignment) error {
+	args := []string{"--no-fehbg", "--bg-fill"}
+	for _, a := range assignments {
+		args = append(args, a.Path)
+	}
+	return b.runner.Run("feh", args...)
+}
+
+// Xwallpaper sets the wallpaper on X11 with xwallpaper
+type Xwallpaper struct {
+	runner Runner
+}
+
+func (b *Xwallpaper) Name() string    { return "xwallpaper" }
+func (b *Xwallpaper) Available() bool { return installed(b.runner, "xwallpaper") }
+
+func (b *Xwallpaper) Set(path string) error {
+	return b.runner.Run("xwallpaper", "--zoom", path)
+}
+
+func (b *Xwallpaper) SetPerMonitor(assignments []Assignment) error {
+	var args []string
+	for _, a := range assignments {
+		args = append(args, "--output", a.Monitor.Name, "--zoom", a.Path)
+	}
+	return b.runner.Run("xwallpaper", args...)
+}
+
+// Swaybg sets the wallpaper on wlroots compositors with swaybg. swaybg
+// keeps running to draw the background, so any previous instance is
+// replaced.
+type Swaybg struct {
+	runner Runner
+}
+
+func (b *Swaybg) Name() string    { return "swaybg" }
+func (b *Swaybg) Available() bool { return installed(b.runner, "swaybg") }
+
+func (b *Swaybg) Set(path string) error {
+	// pkill exits non-zero when nothing was running
+	_ = b.runner.Run("pkill", "-x", "swaybg")
+	return b.runner.Start("swaybg", "-i", path, "-m", "fill")
+}
+
+func (b *Swaybg) SetPerMonitor(assignments []Assignment) error {
+	var args []string
+	for _, a := range assignments {
+		args = append(args, "-o", a.Monitor.Name, "-i", a.Path, "-m", "fill")
+	}
+	_ = b.runner.Run("pkill", "-x", "swaybg")
+	return b.runner.Start("swaybg", args...)
+}
+
+// Swww sets the wallpaper on Wayland with swww, starting its daemon if needed
+type Swww struct {
+	runner Runner
+}
+
+func (b *Swww) Name() string    { return "swww" }
+func (b *Swww) Available() bool { return installed(b.runner, "swww") }
+
+func (b *Swww) Set(path string) error {
+	if err := b.ensureDaemon(); err != nil {
+		return err
+	}
+	return b.runner.Run("swww", "img", path)
+}
+
+func (b *Swww) SetPerMonitor(assignments []Assignment) error {
+	if err := b.ensureDaemon(); err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		if err := b.runner.Run("swww", "img", "-o", a.Monitor.Name, a.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureDaemon starts swww-daemon unless it is already running
+func (b *Swww) ensureDaemon() error {
+	if err := b.runner.Run("swww", "query"); err != nil {
+		if err := b.runner.Start("swww-daemon"); err != nil {
+			return fmt.Errorf("failed to start swww daemon: %w", err)
+		}
+		// Give the daemon a moment to create its socket
+		time.Sleep(500 * time.Millisecond)
+	}
+	return nil
+}
+
+// Hyprpaper sets the wallpaper on Hyprland through hyprpaper's IPC
+type Hyprpaper struct {
+	runner Runner
+}
+
+func (b *Hyprpaper) Name() string    { return "hyprpaper" }
+func (b *Hyprpaper) Available() bool { return installed(b.runner, "hyprctl") }
+
+func (b *Hyprpaper) Set(path string) error {
+	if err := b.runner.Run("hyprctl", "hyprpaper", "preload", path); err != nil {
+		return err
+	}
+	// An empty monitor name applies the wallpaper to every monitor
+	if err := b.runner.Run("hyprctl", "hyprpaper", "wallpaper", ","+path); err != nil {
+		return err
+	}
+	// Free previously preloaded wallpapers; failure here is harmless
+	_ = b.runner.Run("hyprctl", "hyprpaper", "unload", "unused")
+	return nil
+}
+
+func (b *Hyprpaper) SetPerMonitor(assignments []Assignment) error {
+	for _, a := range assignments {
+		if err := b.runner.Run("hyprctl", "hyprpaper", "preload", a.Path); err != nil {
+			return err
+		}
+		if err := b.runner.Run("hyprctl", "hyprpaper", "wallpaper", a.Monitor.Name+","+a.Path); err != nil {
+			return err
+		}
+	}
+	_ = b.runner.Run("hyprctl", "hyprpaper", "unload", "unused")
+	return nil
+}
+
+// GNOME sets the wallpaper through gsettings
+type GNOME struct {
+	runner Runner
+}
+
+func (b *GNOME) Name() string    { return "gnome" }
+func (b *GNOME) Available() bool { return installed(b.runner, "gsettings") }
+
+func (b *GNOME) Set(path string) error {
+	uri := (&url.URL{Scheme: "file", Path: path}).String()
+	if err := b.runner.Run("gsettings", "set", "org.gnome.desktop.background", "picture-uri", uri); err != nil {
+		return err
+	}
+	// picture-uri-dark only exists on GNOME 42 and later
+	_ = b.runner.Run("gsettings", "set", "org.gnome.desktop.background", "picture-uri-dark", uri)
+	return b.runner.Run("gsettings", "set", "org.gnome.desktop.background", "picture-options", "zoom")
+}
+
+// KDE sets the wallpaper on Plasma with plasma-apply-wallpaperimage
+type KDE struct {
+	runner Runner
+}
+
+func (b *KDE) Name() string    { return "kde" }
+func (b *KDE) Available() bool { return installed(b.runner, "plasma-apply-wallpaperimage") }
+
+func (b *KDE) Set(path string) error {
+	return b.runner.Run("plasma-apply-wallpaperimage", path)
+}
+
+// XFCE sets the wallpaper on every XFCE backdrop with xfconf-query
+type XFCE struct {
+	runner Runner
+}
+
+// xfceDefaultProperty is used when no backdrop has been configured yet
+const xfceDefaultProperty = "/backdrop/screen0/monitor0/workspace0/last-image"
+
+func (b *XFCE) Name() string    { return "xfce" }
+func (b *XFCE) Available() bool { return installed(b.runner, "xfconf-query") }
+
+func (b *XFCE) Set(path string) error {
+	properties, err := b.imageProperties()
+	if err != nil {
+		return err
+	}
+
+	if len(properties) == 0 {
+		return b.runner.Run("xfconf-query", "-c", "xfce4-desktop", "-p", xfceDefaultProperty,
+			"-n", "-t", "string", "-s", path)
+	}
+
+	for _, property := range properties {
+		if err := b.runner.Run("xfconf-query", "-c", "xfce4-desktop", "-p", property, "-s", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPerMonitor sets the backdrops of each monitor on every workspace. XFCE
+// names per-output properties /backdrop/screen0/monitor<NAME>/...
+func (b *XFCE) SetPerMonitor(assignments []Assignment) error {
+	properties, err := b.imageProperties()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range assignments {
+		prefix := "/backdrop/screen0/monitor" + a.Monitor.Name + "/"
+
+		found := false
+		for _, property := range properties {
+			if strings.HasPrefix(property, prefix) {
+				found = true
+				if err := b.runner.Run("xfconf-query", "-c", "xfce4-desktop", "-p", property, "-s", a.Path); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !found {
+			err := b.runner.Run("xfconf-query", "-c", "xfce4-desktop", "-p", prefix+"workspace0/last-image",
+				"-n", "-t", "string", "-s", a.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// imageProperties lists the xfconf properties holding backdrop images
+func (b *XFCE) imageProperties() ([]string, error) {
+	out, err := b.runner.Output("xfconf-query", "-c", "xfce4-desktop", "-l")
+	if err != nil {
+		return nil, err
+	}
+
+	var properties []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); strings.HasSuffix(line, "/last-image") {
+			properties = append(properties, line)
+		}
+	}
+	return properties, nil
+}
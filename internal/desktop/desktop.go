@@ -0,0 +1,205 @@
+package desktop
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Runner executes external commands. Backends only talk to the system
+// through a Runner so they can be exercised with a stub.
+type Runner interface {
+	// LookPath reports whether a command is installed
+	LookPath(name string) (string, error)
+	// Run runs a command and waits for it to finish
+	Run(name string, args ...string) error
+	// Output runs a command and returns its standard output
+	Output(name string, args ...string) ([]byte, error)
+	// Start starts a long-running command without waiting for it
+	Start(name string, args ...string) error
+}
+
+// ExecRunner runs commands with os/exec
+type ExecRunner struct{}
+
+// LookPath implements Runner
+func (ExecRunner) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// Run implements Runner
+func (ExecRunner) Run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Output implements Runner
+func (ExecRunner) Output(name string, args ...string) ([]byte, error) {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
+
+// Start implements Runner
+func (ExecRunner) Start(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	// The process outlives us; release it so it is not left as a zombie
+	return cmd.Process.Release()
+}
+
+// Backend applies a wallpaper using a specific tool or desktop environment
+type Backend interface {
+	// Name returns the backend identifier used in config and flags
+	Name() string
+	// Available reports whether the backend's tools are installed
+	Available() bool
+	// Set applies the image at path as the wallpaper
+	Set(path string) error
+}
+
+// Assignment pairs a monitor with the wallpaper to show on it
+type Assignment struct {
+	Monitor Monitor
+	Path    string
+}
+
+// MultiBackend is implemented by backends that can show a different
+// wallpaper on each monitor
+type MultiBackend interface {
+	Backend
+	// SetPerMonitor applies each assignment to its monitor
+	SetPerMonitor(assignments []Assignment) error
+}
+
+// SupportsPerMonitor reports whether a backend can show a different
+// wallpaper on each monitor
+func SupportsPerMonitor(b Backend) bool {
+	_, ok := b.(MultiBackend)
+	return ok
+}
+
+// Apply shows each assignment on its monitor. A single assignment without a
+// monitor name is applied to every monitor, as are assignments given to a
+// backend without per-monitor support.
+func Apply(b Backend, assignments []Assignment) error {
+	if len(assignments) == 0 {
+		return fmt.Errorf("no wallpaper to apply")
+	}
+
+	if mb, ok := b.(MultiBackend); ok && assignments[0].Monitor.Name != "" {
+		return mb.SetPerMonitor(assignments)
+	}
+	return b.Set(assignments[0].Path)
+}
+
+// Env looks up environment variables; os.Getenv in normal use
+type Env func(key string) string
+
+// Backends returns every supported backend in detection preference order
+func Backends(r Runner) []Backend {
+	return []Backend{
+		&GNOME{runner: r},
+		&KDE{runner: r},
+		&XFCE{runner: r},
+		&Hyprpaper{runner: r},
+		&Swww{runner: r},
+		&Swaybg{runner: r},
+		&Feh{runner: r},
+		&Xwallpaper{runner: r},
+	}
+}
+
+// BackendNames returns the names of all supported backends
+func BackendNames() []string {
+	var names []string
+	for _, b := range Backends(ExecRunner{}) {
+		names = append(names, b.Name())
+	}
+	return names
+}
+
+// Get returns the backend with the given name, or auto-detects one when
+// name is empty or "auto"
+func Get(name string, r Runner, env Env) (Backend, error) {
+	if name == "" || name == "auto" {
+		return Detect(r, env)
+	}
+
+	for _, b := range Backends(r) {
+		if b.Name() == name {
+			if !b.Available() {
+				return nil, fmt.Errorf("backend %s is not available (required tools are not installed)", name)
+			}
+			return b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown backend: %s (valid: %s)", name, strings.Join(BackendNames(), ", "))
+}
+
+// Detect picks a backend for the current session based on
+// XDG_CURRENT_DESKTOP, WAYLAND_DISPLAY and DISPLAY, preferring the desktop
+// environment's own mechanism over generic tools.
+func Detect(r Runner, env Env) (Backend, error) {
+	if env == nil {
+		env = os.Getenv
+	}
+
+	backends := make(map[string]Backend)
+	for _, b := range Backends(r) {
+		backends[b.Name()] = b
+	}
+
+	var candidates []string
+	desktop := strings.ToLower(env("XDG_CURRENT_DESKTOP"))
+	switch {
+	case strings.Contains(desktop, "gnome"), strings.Contains(desktop, "unity"), strings.Contains(desktop, "budgie"):
+		candidates = append(candidates, "gnome")
+	case strings.Contains(desktop, "kde"):
+		candidates = append(candidates, "kde")
+	case strings.Contains(desktop, "xfce"):
+		candidates = append(candidates, "xfce")
+	case strings.Contains(desktop, "hyprland") || env("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		candidates = append(candidates, "hyprpaper", "swww")
+	}
+
+	if env("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, "swww", "swaybg")
+	}
+	if env("DISPLAY") != "" {
+		candidates = append(candidates, "feh", "xwallpaper")
+	}
+
+	for _, name := range candidates {
+		if b := backends[name]; b.Available() {
+			return b, nil
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("could not detect a graphical session; set desktop.backend in the config or use --backend")
+	}
+	return nil, fmt.Errorf("no wallpaper backend available; install one of: %s", strings.Join(candidates, ", "))
+}
+
+// installed reports whether all the given commands are available
+func installed(r Runner, names ...string) bool {
+	for _, name := range names {
+		if _, err := r.LookPath(name); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package desktop
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stubRunner records the commands backends run. Commands in installed are
+// found by LookPath, and Output returns the output set for a command.
+type stubRunner struct {
+	installed map[string]bool
+	output    map[string]string
+	calls     []string
+}
+
+func newStub(installed ...string) *stubRunner {
+	r := &stubRunner{installed: make(map[string]bool), output: make(map[string]string)}
+	for _, name := range installed {
+		r.installed[name] = true
+	}
+	return r
+}
+
+func (r *stubRunner) record(kind, name string, args []string) {
+	r.calls = append(r.calls, strings.TrimSpace(kind+" "+name+" "+strings.Join(args, " ")))
+}
+
+func (r *stubRunner) LookPath(name string) (string, error) {
+	if r.installed[name] {
+		return "/usr/bin/" + name, nil
+	}
+	return "", errors.New("not found")
+}
+
+func (r *stubRunner) Run(name string, args ...string) error {
+	r.record("run", name, args)
+	return nil
+}
+
+func (r *stubRunner) Output(name string, args ...string) ([]byte, error) {
+	r.record("output", name, args)
+	return []byte(r.output[name]), nil
+}
+
+func (r *stubRunner) Start(name string, args ...string) error {
+	r.record("start", name, args)
+	return nil
+}
+
+func env(vars map[string]string) Env {
+	return func(key string) string { return vars[key] }
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		installed []string
+		want      string
+	}{
+		{"gnome", map[string]string{"XDG_CURRENT_DESKTOP": "ubuntu:GNOME", "WAYLAND_DISPLAY": "wayland-0"}, []string{"gsettings", "swww"}, "gnome"},
+		{"kde", map[string]string{"XDG_CURRENT_DESKTOP": "KDE", "DISPLAY": ":0"}, []string{"plasma-apply-wallpaperimage", "feh"}, "kde"},
+		{"xfce", map[string]string{"XDG_CURRENT_DESKTOP": "XFCE", "DISPLAY": ":0"}, []string{"xfconf-query", "feh"}, "xfce"},
+		{"hyprland", map[string]string{"XDG_CURRENT_DESKTOP": "Hyprland", "WAYLAND_DISPLAY": "wayland-1"}, []string{"hyprctl", "swww"}, "hyprpaper"},
+		{"hyprland signature", map[string]string{"HYPRLAND_INSTANCE_SIGNATURE": "abc", "WAYLAND_DISPLAY": "wayland-1"}, []string{"swww"}, "swww"},
+		{"desktop tool missing", map[string]string{"XDG_CURRENT_DESKTOP": "GNOME", "DISPLAY": ":0"}, []string{"feh"}, "feh"},
+		{"wayland", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"swaybg"}, "swaybg"},
+		{"wayland prefers swww", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"swaybg", "swww"}, "swww"},
+		{"x11", map[string]string{"DISPLAY": ":0"}, []string{"xwallpaper"}, "xwallpaper"},
+		{"xwayland", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"feh"}, "feh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Detect(newStub(tt.installed...), env(tt.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Name() != tt.want {
+				t.Errorf("detected %s, want %s", b.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"no session", map[string]string{}, "could not detect a graphical session"},
+		{"nothing installed", map[string]string{"DISPLAY": ":0"}, "install one of: feh, xwallpaper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Detect(newStub(), env(tt.env))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	r := newStub("feh")
+	vars := env(map[string]string{"DISPLAY": ":0"})
+
+	for _, name := range []string{"", "auto", "feh"} {
+		b, err := Get(name, r, vars)
+		if err != nil || b.Name() != "feh" {
+			t.Errorf("Get(%q) = %v, %v, want feh", name, b, err)
+		}
+	}
+	if _, err := Get("swww", r, vars); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("Get of a backend that is not installed: %v", err)
+	}
+	if _, err := Get("nosuch", r, vars); err == nil || !strings.Contains(err.Error(), "unknown backend") {
+		t.Errorf("Get of an unknown backend: %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	const path = "/pics/a b.jpg"
+	tests := []struct {
+		backend string
+		want    []string
+	}{
+		{"feh", []string{"run feh --no-fehbg --bg-fill " + path}},
+		{"xwallpaper", []string{"run xwallpaper --zoom " + path}},
+		{"swaybg", []string{"run pkill -x swaybg", "start swaybg -i " + path + " -m fill"}},
+		{"swww", []string{"run swww query", "run swww img " + path}},
+		{"hyprpaper", []string{
+			"run hyprctl hyprpaper preload " + path,
+			"run hyprctl hyprpaper wallpaper ," + path,
+			"run hyprctl hyprpaper unload unused",
+		}},
+		{"gnome", []string{
+			"run gsettings set org.gnome.desktop.background picture-uri file:///pics/a%20b.jpg",
+			"run gsettings set org.gnome.desktop.background picture-uri-dark file:///pics/a%20b.jpg",
+			"run gsettings set org.gnome.desktop.background picture-options zoom",
+		}},
+		{"kde", []string{"run plasma-apply-wallpaperimage " + path}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			r := newStub()
+			b := backend(t, tt.backend, r)
+			if err := b.Set(path); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r.calls, tt.want) {
+				t.Errorf("ran %q, want %q", r.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestXFCESet(t *testing.T) {
+	list := "output xfconf-query -c xfce4-desktop -l"
+
+	t.Run("existing backdrops", func(t *testing.T) {
+		r := newStub()
+		r.output["xfconf-query"] = "/backdrop/screen0/monitorHDMI-1/workspace0/last-image\n" +
+			"/backdrop/screen0/monitorHDMI-1/workspace0/image-style\n" +
+			"/backdrop/screen0/monitorDP-1/workspace0/last-image\n"
+		if err := backend(t, "xfce", r).Set("/a.jpg"); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			list,
+			"run xfconf-query -c xfce4-desktop -p /backdrop/screen0/monitorHDMI-1/workspace0/last-image -s /a.jpg",
+			"run xfconf-query -c xfce4-desktop -p /backdrop/screen0/monitorDP-1/workspace0/last-image -s /a.jpg",
+		}
+		if !reflect.DeepEqual(r.calls, want) {
+			t.Errorf("ran %q, want %q", r.calls, want)
+		}
+	})
+
+	t.Run("no backdrop yet", func(t *testing.T) {
+		r := newStub()
+		if err := backend(t, "xfce", r).Set("/a.jpg"); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			list,
+			"run xfconf-query -c xfce4-desktop -p " + xfceDefaultProperty + " -n -t string -s /a.jpg",
+		}
+		if !reflect.DeepEqual(r.calls, want) {
+			t.Errorf("ran %q, want %q", r.calls, want)
+		}
+	})
+
+	t.Run("per monitor", func(t *testing.T) {
+		r := newStub()
+		r.output["xfconf-query"] = "/backdrop/screen0/monitorHDMI-1/workspace0/last-image\n" +
+			"/backdrop/screen0/monitorHDMI-1/workspace1/last-image\n"
+		err := Apply(backend(t, "xfce", r), []Assignment{
+			{Monitor: Monitor{Name: "HDMI-1"}, Path: "/a.jpg"},
+			{Monitor: Monitor{Name: "DP-1"}, Path: "/b.jpg"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			list,
+			"run xfconf-query -c xfce4-desktop -p /backdrop/screen0/monitorHDMI-1/workspace0/last-image -s /a.jpg",
+			"run xfconf-query -c xfce4-desktop -p /backdrop/screen0/monitorHDMI-1/workspace1/last-image -s /a.jpg",
+			"run xfconf-query -c xfce4-desktop -p /backdrop/screen0/monitorDP-1/workspace0/last-image -n -t string -s /b.jpg",
+		}
+		if !reflect.DeepEqual(r.calls, want) {
+			t.Errorf("ran %q, want %q", r.calls, want)
+		}
+	})
+}
+
+func TestApply(t *testing.T) {
+	assignments := []Assignment{
+		{Monitor: Monitor{Name: "DP-1"}, Path: "/a.jpg"},
+		{Monitor: Monitor{Name: "HDMI-1"}, Path: "/b.jpg"},
+	}
+	tests := []struct {
+		name        string
+		backend     string
+		assignments []Assignment
+		want        []string
+	}{
+		{"feh per monitor", "feh", assignments, []string{"run feh --no-fehbg --bg-fill /a.jpg /b.jpg"}},
+		{"xwallpaper per monitor", "xwallpaper", assignments, []string{
+			"run xwallpaper --output DP-1 --zoom /a.jpg --output HDMI-1 --zoom /b.jpg",
+		}},
+		{"swaybg per monitor", "swaybg", assignments, []string{
+			"run pkill -x swaybg",
+			"start swaybg -o DP-1 -i /a.jpg -m fill -o HDMI-1 -i /b.jpg -m fill",
+		}},
+		{"swww per monitor", "swww", assignments, []string{
+			"run swww query",
+			"run swww img -o DP-1 /a.jpg",
+			"run swww img -o HDMI-1 /b.jpg",
+		}},
+		{"hyprpaper per monitor", "hyprpaper", assignments, []string{
+			"run hyprctl hyprpaper preload /a.jpg",
+			"run hyprctl hyprpaper wallpaper DP-1,/a.jpg",
+			"run hyprctl hyprpaper preload /b.jpg",
+			"run hyprctl hyprpaper wallpaper HDMI-1,/b.jpg",
+			"run hyprctl hyprpaper unload unused",
+		}},
+		{"single backend uses the first", "kde", assignments, []string{"run plasma-apply-wallpaperimage /a.jpg"}},
+		{"no monitor name sets all", "xwallpaper", []Assignment{{Path: "/a.jpg"}}, []string{"run xwallpaper --zoom /a.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newStub()
+			if err := Apply(backend(t, tt.backend, r), tt.assignments); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r.calls, tt.want) {
+				t.Errorf("ran %q, want %q", r.calls, tt.want)
+			}
+		})
+	}
+
+	if err := Apply(backend(t, "feh", newStub()), nil); err == nil {
+		t.Error("Apply without assignments succeeded")
+	}
+}
+
+// backend returns the backend named name running commands with r
+func backend(t *testing.T, name string, r Runner) Backend {
+	t.Helper()
+	for _, b := range Backends(r) {
+		if b.Name() == name {
+			return b
+		}
+	}
+	t.Fatalf("no backend %s", name)
+	return nil
+}
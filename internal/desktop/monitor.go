@@ -0,0 +1,269 @@
+package desktop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Monitor is a connected output. Width and Height are in pixels with the
+// output's rotation applied, so a vertical 1080p screen is 1080x1920.
+type Monitor struct {
+	Name    string `json:"name"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	Primary bool   `json:"primary"`
+}
+
+// Resolution returns the monitor resolution as "WxH"
+func (m Monitor) Resolution() string {
+	return fmt.Sprintf("%dx%d", m.Width, m.Height)
+}
+
+// Portrait reports whether the monitor is taller than it is wide
+func (m Monitor) Portrait() bool {
+	return m.Height > m.Width
+}
+
+// monitorSource is a tool that can list connected outputs
+type monitorSource struct {
+	command string
+	args    []string
+	parse   func([]byte) ([]Monitor, error)
+}
+
+// DetectMonitors lists connected monitors using hyprctl, swaymsg, wlr-randr
+// or xrandr, whichever matches the session. Monitors are ordered left to
+// right, then top to bottom.
+func DetectMonitors(r Runner, env Env) ([]Monitor, error) {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	if env == nil {
+		env = func(string) string { return "" }
+	}
+
+	var sources []monitorSource
+	if env("HYPRLAND_INSTANCE_SIGNATURE") != "" || strings.Contains(strings.ToLower(env("XDG_CURRENT_DESKTOP")), "hyprland") {
+		sources = append(sources, monitorSource{"hyprctl", []string{"monitors", "-j"}, parseHyprctlMonitors})
+	}
+	if env("SWAYSOCK") != "" {
+		sources = append(sources, monitorSource{"swaymsg", []string{"-t", "get_outputs", "-r"}, parseSwayOutputs})
+	}
+	if env("WAYLAND_DISPLAY") != "" {
+		sources = append(sources, monitorSource{"wlr-randr", nil, parseWlrRandr})
+	}
+	if env("DISPLAY") != "" {
+		sources = append(sources, monitorSource{"xrandr", []string{"--query"}, parseXrandr})
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("could not detect a graphical session")
+	}
+
+	var tried []string
+	for _, source := range sources {
+		if !installed(r, source.command) {
+			continue
+		}
+		tried = append(tried, source.command)
+
+		out, err := r.Output(source.command, source.args...)
+		if err != nil {
+			continue
+		}
+		monitors, err := source.parse(out)
+		if err != nil || len(monitors) == 0 {
+			continue
+		}
+
+		sort.SliceStable(monitors, func(i, j int) bool {
+			if monitors[i].X != monitors[j].X {
+				return monitors[i].X < monitors[j].X
+			}
+			return monitors[i].Y < monitors[j].Y
+		})
+		return monitors, nil
+	}
+
+	if len(tried) == 0 {
+		var names []string
+		for _, source := range sources {
+			names = append(names, source.command)
+		}
+		return nil, fmt.Errorf("no monitor detection tool available; install one of: %s", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("failed to detect monitors with %s", strings.Join(tried, ", "))
+}
+
+// rotated reports whether a Wayland transform turns the output sideways
+func rotated(transform string) bool {
+	return strings.HasSuffix(transform, "90") || strings.HasSuffix(transform, "270")
+}
+
+// parseHyprctlMonitors parses `hyprctl monitors -j`
+func parseHyprctlMonitors(out []byte) ([]Monitor, error) {
+	var outputs []struct {
+		Name      string `json:"name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		X         int    `json:"x"`
+		Y         int    `json:"y"`
+		Transform int    `json:"transform"`
+		Focused   bool   `json:"focused"`
+		Disabled  bool   `json:"disabled"`
+	}
+	if err := json.Unmarshal(out, &outputs); err != nil {
+		return nil, err
+	}
+
+	var monitors []Monitor
+	for _, o := range outputs {
+		if o.Disabled {
+			continue
+		}
+		m := Monitor{Name: o.Name, Width: o.Width, Height: o.Height, X: o.X, Y: o.Y, Primary: o.Focused}
+		// Odd transforms (1, 3, 5, 7) rotate by 90 or 270 degrees
+		if o.Transform%2 == 1 {
+			m.Width, m.Height = m.Height, m.Width
+		}
+		monitors = append(monitors, m)
+	}
+	return monitors, nil
+}
+
+// parseSwayOutputs parses `swaymsg -t get_outputs -r`
+func parseSwayOutputs(out []byte) ([]Monitor, error) {
+	var outputs []struct {
+		Name    string `json:"name"`
+		Active  bool   `json:"active"`
+		Focused bool   `json:"focused"`
+		Rect    struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		} `json:"rect"`
+		CurrentMode struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"current_mode"`
+		Transform string `json:"transform"`
+	}
+	if err := json.Unmarshal(out, &outputs); err != nil {
+		return nil, err
+	}
+
+	var monitors []Monitor
+	for _, o := range outputs {
+		if !o.Active {
+			continue
+		}
+		m := Monitor{
+			Name:    o.Name,
+			Width:   o.CurrentMode.Width,
+			Height:  o.CurrentMode.Height,
+			X:       o.Rect.X,
+			Y:       o.Rect.Y,
+			Primary: o.Focused,
+		}
+		if rotated(o.Transform) {
+			m.Width, m.Height = m.Height, m.Width
+		}
+		monitors = append(monitors, m)
+	}
+	return monitors, nil
+}
+
+// wlrModeRe matches the current mode line of wlr-randr output
+var wlrModeRe = regexp.MustCompile(`^(\d+)x(\d+) px.*current`)
+
+// parseWlrRandr parses the text output of `wlr-randr`
+func parseWlrRandr(out []byte) ([]Monitor, error) {
+	var monitors []Monitor
+	var current *Monitor
+	enabled := true
+	transform := ""
+
+	flush := func() {
+		if current != nil && enabled && current.Width > 0 {
+			if rotated(transform) {
+				current.Width, current.Height = current.Height, current.Width
+			}
+			monitors = append(monitors, *current)
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		// Output headers are the only unindented lines
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			flush()
+			current = &Monitor{Name: strings.Fields(line)[0]}
+			enabled = true
+			transform = ""
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "Enabled:"):
+			enabled = strings.TrimSpace(strings.TrimPrefix(trimmed, "Enabled:")) == "yes"
+		case strings.HasPrefix(trimmed, "Position:"):
+			pos := strings.Split(strings.TrimSpace(strings.TrimPrefix(trimmed, "Position:")), ",")
+			if len(pos) == 2 {
+				current.X, _ = strconv.Atoi(pos[0])
+				current.Y, _ = strconv.Atoi(pos[1])
+			}
+		case strings.HasPrefix(trimmed, "Transform:"):
+			transform = strings.TrimSpace(strings.TrimPrefix(trimmed, "Transform:"))
+		default:
+			if match := wlrModeRe.FindStringSubmatch(trimmed); match != nil {
+				current.Width, _ = strconv.Atoi(match[1])
+				current.Height, _ = strconv.Atoi(match[2])
+			}
+		}
+	}
+	flush()
+
+	return monitors, scanner.Err()
+}
+
+// xrandrRe matches a connected and active output line of `xrandr --query`.
+// The geometry already has the output's rotation applied.
+var xrandrRe = regexp.MustCompile(`^(\S+) connected (primary )?(\d+)x(\d+)\+(\d+)\+(\d+)`)
+
+// parseXrandr parses the text output of `xrandr --query`
+func parseXrandr(out []byte) ([]Monitor, error) {
+	var monitors []Monitor
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		match := xrandrRe.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		m := Monitor{Name: match[1], Primary: match[2] != ""}
+		m.Width, _ = strconv.Atoi(match[3])
+		m.Height, _ = strconv.Atoi(match[4])
+		m.X, _ = strconv.Atoi(match[5])
+		m.Y, _ = strconv.Atoi(match[6])
+		monitors = append(monitors, m)
+	}
+
+	return monitors, scanner.Err()
+}
@@ -2,6 +2,7 @@ package downloader
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,16 +12,38 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AccursedGalaxy/wallfetch/internal/analysis"
 	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/retention"
 	"github.com/AccursedGalaxy/wallfetch/internal/wallhaven"
 )
 
+// SkipLibraryFull is the skip reason of wallpapers that did not fit in
+// the library's size limits
+const SkipLibraryFull = "Library full"
+
+// Evictor removes a wallpaper from the library to make room for a new
+// download
+type Evictor func(img *database.Image) error
+
 // Downloader handles concurrent wallpaper downloading
 type Downloader struct {
 	downloadDir   string
 	maxConcurrent int
 	db            *database.DB
 	httpClient    *http.Client
+
+	// Library size limits; budgetMu serializes reservations so the
+	// evictions planned for one download are settled before the next one
+	// is checked
+	budget   *retention.Budget
+	evict    Evictor
+	budgetMu sync.Mutex
+	full     error
+	// evictable are the wallpapers that may be evicted, in the order they
+	// go; claimed marks those planned or done for a download
+	evictable []database.Image
+	claimed   []bool
 }
 
 // NewDownloader creates a new downloader instance
@@ -35,14 +58,37 @@ func NewDownloader(downloadDir string, maxConcurrent int, db *database.DB) *Down
 	}
 }
 
+// SetBudget makes the downloader keep the library within a budget. Without
+// an evictor, downloading stops at the first wallpaper that does not fit;
+// with one, the evictable wallpapers are evicted in order until it does.
+// Wallpapers are only evicted once the download that needs the room has
+// succeeded and is known to be new, and not at all if evicting every
+// evictable wallpaper would not make enough room.
+func (d *Downloader) SetBudget(budget *retention.Budget, evictable []database.Image, evict Evictor) {
+	d.budget = budget
+	d.evict = evict
+	d.evictable = evictable
+	d.claimed = make([]bool, len(evictable))
+}
+
+// Full returns why the library is full, or nil while downloads still fit
+func (d *Downloader) Full() error {
+	d.budgetMu.Lock()
+	defer d.budgetMu.Unlock()
+	return d.full
+}
+
 // DownloadResult represents the result of a download operation
 type DownloadResult struct {
 	Wallpaper wallhaven.Wallpaper
+	ImageID   int
 	LocalPath string
 	Checksum  string
 	Error     error
 	Skipped   bool
 	Reason    string
+	// Evicted are the wallpapers removed to make room for this one
+	Evicted []database.Image
 }
 
 // DownloadWallpapers downloads multiple wallpapers concurrently
@@ -95,8 +141,8 @@ func (d *Downloader) worker(wg *sync.WaitGroup, workChan <-chan wallhaven.Wallpa
 }
 
 // downloadWallpaper downloads a single wallpaper
-func (d *Downloader) downloadWallpaper(wallpaper wallhaven.Wallpaper, filter *WallpaperFilter) DownloadResult {
-	result := DownloadResult{
+func (d *Downloader) downloadWallpaper(wallpaper wallhaven.Wallpaper, filter *WallpaperFilter) (result DownloadResult) {
+	result = DownloadResult{
 		Wallpaper: wallpaper,
 	}
 
@@ -110,6 +156,18 @@ func (d *Downloader) downloadWallpaper(wallpaper wallhaven.Wallpaper, filter *Wa
 		}
 	}
 
+	// Check the blocklist before spending bandwidth
+	blocked, err := d.db.IsBlocked("wallhaven", wallpaper.ID)
+	if err != nil {
+		result.Error = fmt.Errorf("blocklist check failed: %w", err)
+		return result
+	}
+	if blocked {
+		result.Skipped = true
+		result.Reason = "Blocked"
+		return result
+	}
+
 	// Check if already exists by source ID
 	exists, err := d.db.ExistsBySourceID("wallhaven", wallpaper.ID)
 	if err != nil {
@@ -122,45 +180,37 @@ func (d *Downloader) downloadWallpaper(wallpaper wallhaven.Wallpaper, filter *Wa
 		return result
 	}
 
+	// Make sure the wallpaper fits in the library before spending
+	// bandwidth, using the size reported by the API. The wallpapers to
+	// evict for it are only planned here.
+	reserved := int64(wallpaper.FileSize)
+	var plan []int
+	if d.budget != nil {
+		plan, err = d.reserve("wallhaven", reserved)
+		if err != nil {
+			result.Skipped = true
+			result.Reason = SkipLibraryFull
+			return result
+		}
+		defer func() {
+			if result.ImageID == 0 {
+				d.cancel("wallhaven", reserved, plan, &result)
+			}
+		}()
+	}
+
 	// Generate local filename
 	filename := d.generateFilename(wallpaper)
 	localPath := filepath.Join(d.downloadDir, filename)
 	result.LocalPath = localPath
 
 	// Download the file
-	resp, err := d.httpClient.Get(wallpaper.Path)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to download: %w", err)
-		return result
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		result.Error = fmt.Errorf("download failed with status %d", resp.StatusCode)
-		return result
-	}
-
-	// Create temporary file
-	tempFile, err := os.CreateTemp(d.downloadDir, "wallfetch_*.tmp")
+	tempPath, checksum, err := d.DownloadTemp(wallpaper.Path, d.downloadDir)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to create temp file: %w", err)
+		result.Error = err
 		return result
 	}
-	defer os.Remove(tempFile.Name())
-
-	// Download and compute checksum simultaneously
-	hasher := sha256.New()
-	writer := io.MultiWriter(tempFile, hasher)
-
-	_, err = io.Copy(writer, resp.Body)
-	tempFile.Close()
-	if err != nil {
-		result.Error = fmt.Errorf("failed to write file: %w", err)
-		return result
-	}
-
-	// Compute checksum
-	checksum := fmt.Sprintf("%x", hasher.Sum(nil))
+	defer os.Remove(tempPath)
 	result.Checksum = checksum
 
 	// Check if file with same checksum already exists
@@ -175,8 +225,30 @@ func (d *Downloader) downloadWallpaper(wallpaper wallhaven.Wallpaper, filter *Wa
 		return result
 	}
 
+	// The same image may have been blocked under a different source ID
+	blocked, err = d.db.IsChecksumBlocked(checksum)
+	if err != nil {
+		result.Error = fmt.Errorf("blocklist check failed: %w", err)
+		return result
+	}
+	if blocked {
+		result.Skipped = true
+		result.Reason = "Blocked (same checksum)"
+		return result
+	}
+
+	// The wallpaper is new, so make the room planned for it
+	if len(plan) > 0 {
+		result.Evicted, plan, err = d.evictPlanned(plan)
+		if err != nil {
+			result.Skipped = true
+			result.Reason = SkipLibraryFull
+			return result
+		}
+	}
+
 	// Move temp file to final location
-	if err := os.Rename(tempFile.Name(), localPath); err != nil {
+	if err := os.Rename(tempPath, localPath); err != nil {
 		result.Error = fmt.Errorf("failed to move file: %w", err)
 		return result
 	}
@@ -191,14 +263,31 @@ func (d *Downloader) downloadWallpaper(wallpaper wallhaven.Wallpaper, filter *Wa
 	// Save to database
 	tags := d.extractTags(wallpaper)
 	dbImage := &database.Image{
-		Source:     "wallhaven",
-		SourceID:   wallpaper.ID,
-		URL:        wallpaper.Path,
-		LocalPath:  localPath,
-		Checksum:   checksum,
-		Tags:       tags,
-		Resolution: wallpaper.Resolution,
-		FileSize:   fileInfo.Size(),
+		Source:          "wallhaven",
+		SourceID:        wallpaper.ID,
+		URL:             wallpaper.Path,
+		LocalPath:       localPath,
+		Checksum:        checksum,
+		Tags:            tags,
+		Resolution:      wallpaper.Resolution,
+		FileSize:        fileInfo.Size(),
+		Width:           wallpaper.DimensionX,
+		Height:          wallpaper.DimensionY,
+		Category:        wallpaper.Category,
+		Purity:          wallpaper.Purity,
+		FileType:        wallpaper.FileType,
+		Colors:          strings.Join(wallpaper.Colors, ","),
+		Views:           wallpaper.Views,
+		SourceFavorites: wallpaper.Favorites,
+		PageURL:         wallpaper.URL,
+	}
+	if wallpaper.Uploader != nil {
+		dbImage.Uploader = wallpaper.Uploader.Username
+	}
+
+	// Palette, brightness and hashes are filled in later if the file can't be decoded
+	if analyzed, err := analysis.AnalyzeFile(localPath); err == nil {
+		analyzed.Apply(dbImage)
 	}
 
 	if err := d.db.InsertImage(dbImage); err != nil {
@@ -207,10 +296,140 @@ func (d *Downloader) downloadWallpaper(wallpaper wallhaven.Wallpaper, filter *Wa
 		result.Error = fmt.Errorf("failed to save to database: %w", err)
 		return result
 	}
+	result.ImageID = dbImage.ID
+	if d.budget != nil {
+		d.budget.Resize("wallhaven", reserved, dbImage.FileSize)
+	}
 
 	return result
 }
 
+// DownloadTemp downloads url into a temporary file in dir and returns its
+// path and SHA-256 checksum. The caller moves the file into place or
+// removes it.
+func (d *Downloader) DownloadTemp(url, dir string) (string, string, error) {
+	resp, err := d.httpClient.Get(url)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	tempFile, err := os.CreateTemp(dir, "wallfetch_*.tmp")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	// Download and compute checksum simultaneously
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tempFile, hasher), resp.Body)
+	tempFile.Close()
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return "", "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return tempFile.Name(), fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// reserve claims room in the budget for a download and returns the
+// indexes of the evictable wallpapers that must be evicted to make it. They
+// are taken out of the budget but not evicted yet. If evicting everything
+// allowed would not make room, nothing is planned, the library is full and
+// no further downloads are attempted.
+func (d *Downloader) reserve(source string, size int64) ([]int, error) {
+	d.budgetMu.Lock()
+	defer d.budgetMu.Unlock()
+
+	if d.full != nil {
+		return nil, d.full
+	}
+
+	var plan []int
+	for {
+		err := d.budget.Reserve(source, size)
+		if err == nil {
+			return plan, nil
+		}
+
+		var over *retention.OverError
+		i := -1
+		if errors.As(err, &over) && d.evict != nil {
+			i = d.nextEvictable(over.Source)
+		}
+		if i < 0 {
+			for _, j := range plan {
+				d.budget.Restore(&d.evictable[j], true)
+				d.claimed[j] = false
+			}
+			if over != nil && d.evict != nil {
+				err = fmt.Errorf("%w, even after evicting everything the retention policy allows", err)
+			}
+			d.full = err
+			return nil, err
+		}
+		d.claimed[i] = true
+		d.budget.Removed(&d.evictable[i])
+		plan = append(plan, i)
+	}
+}
+
+// nextEvictable returns the index of the first unclaimed evictable
+// wallpaper, from source unless it is empty, or -1
+func (d *Downloader) nextEvictable(source string) int {
+	for i := range d.evictable {
+		if !d.claimed[i] && (source == "" || d.evictable[i].Source == source) {
+			return i
+		}
+	}
+	return -1
+}
+
+// evictPlanned evicts the planned wallpapers. If one cannot be evicted,
+// the library is full; the evicted wallpapers are returned with the rest of
+// the plan, which is not evicted.
+func (d *Downloader) evictPlanned(plan []int) ([]database.Image, []int, error) {
+	d.budgetMu.Lock()
+	defer d.budgetMu.Unlock()
+
+	var evicted []database.Image
+	for n, i := range plan {
+		img := d.evictable[i]
+		if err := d.evict(&img); err != nil {
+			if d.full == nil {
+				d.full = fmt.Errorf("evicting ID %d failed: %w", img.ID, err)
+			}
+			return evicted, plan[n:], err
+		}
+		evicted = append(evicted, img)
+	}
+	return evicted, nil, nil
+}
+
+// cancel gives back the reservation of a download that was not added and
+// the wallpapers planned for eviction for it. A planned wallpaper whose room
+// was taken by other downloads in the meantime is evicted after all.
+func (d *Downloader) cancel(source string, reserved int64, plan []int, result *DownloadResult) {
+	d.budgetMu.Lock()
+	defer d.budgetMu.Unlock()
+
+	d.budget.Release(source, reserved)
+	for _, i := range plan {
+		img := d.evictable[i]
+		if !d.budget.Restore(&img, false) {
+			if err := d.evict(&img); err == nil {
+				result.Evicted = append(result.Evicted, img)
+				continue
+			}
+			d.budget.Restore(&img, true)
+		}
+		d.claimed[i] = false
+	}
+}
+
 // generateFilename creates a filename for the wallpaper
 func (d *Downloader) generateFilename(wallpaper wallhaven.Wallpaper) string {
 	// Extract file extension from the URL
@@ -0,0 +1,157 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/retention"
+	"github.com/AccursedGalaxy/wallfetch/internal/wallhaven"
+)
+
+// library is a database holding an evictable wallpaper of 60 bytes and a
+// protected one of 10 bytes, with a budget of 100 bytes
+type library struct {
+	db        *database.DB
+	old, kept database.Image
+	d         *Downloader
+	evicted   []int
+}
+
+func newLibrary(t *testing.T) *library {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := database.Open(filepath.Join(dir, "wallpapers.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	l := &library{db: db}
+	add := func(id string, size int) database.Image {
+		path := filepath.Join(dir, id+".jpg")
+		data := []byte(fmt.Sprintf("%-*s", size, id))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		img := database.Image{
+			Source:       "wallhaven",
+			SourceID:     id,
+			LocalPath:    path,
+			Checksum:     fmt.Sprintf("%x", sha256.Sum256(data)),
+			FileSize:     int64(size),
+			DownloadedAt: time.Now(),
+		}
+		if err := db.InsertImage(&img); err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+	l.old = add("old", 60)
+	l.kept = add("kept", 10)
+
+	budget := retention.NewBudget(100, nil, []database.Image{l.old, l.kept})
+	l.d = NewDownloader(dir, 1, db)
+	l.d.SetBudget(budget, []database.Image{l.old}, func(img *database.Image) error {
+		l.evicted = append(l.evicted, img.ID)
+		if _, err := db.DeleteImage(img.ID); err != nil {
+			return err
+		}
+		return os.Remove(img.LocalPath)
+	})
+	return l
+}
+
+// fetch downloads one wallpaper of size bytes whose file has content
+func (l *library) fetch(t *testing.T, size int, content []byte) DownloadResult {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	results, err := l.d.DownloadWallpapers([]wallhaven.Wallpaper{{
+		ID:       "new",
+		Path:     srv.URL + "/new.jpg",
+		FileSize: size,
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results[0]
+}
+
+func TestEvictsOnlyForAddedWallpapers(t *testing.T) {
+	l := newLibrary(t)
+
+	r := l.fetch(t, 50, []byte("new wallpaper"))
+	if r.Error != nil || r.Skipped {
+		t.Fatalf("download failed: %v %s", r.Error, r.Reason)
+	}
+	if len(l.evicted) != 1 || l.evicted[0] != l.old.ID || len(r.Evicted) != 1 {
+		t.Errorf("evicted %v (result %d), want the old wallpaper", l.evicted, len(r.Evicted))
+	}
+}
+
+func TestNoEvictionForDuplicates(t *testing.T) {
+	l := newLibrary(t)
+
+	data, err := os.ReadFile(l.kept.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := l.fetch(t, 50, data)
+	if !r.Skipped {
+		t.Fatalf("duplicate was not skipped: %v", r.Error)
+	}
+	if len(l.evicted) != 0 {
+		t.Errorf("evicted %v for a duplicate", l.evicted)
+	}
+	if l.d.Full() != nil {
+		t.Errorf("library reported full after a duplicate: %v", l.d.Full())
+	}
+
+	// The planned room was given back, so the next download can use it
+	r = l.fetch(t, 50, []byte("new wallpaper"))
+	if r.Error != nil || r.Skipped || len(l.evicted) != 1 {
+		t.Errorf("download after a duplicate: %v %s, evicted %v", r.Error, r.Reason, l.evicted)
+	}
+}
+
+func TestNoEvictionWhenItCannotMakeRoom(t *testing.T) {
+	l := newLibrary(t)
+
+	r := l.fetch(t, 200, []byte("huge wallpaper"))
+	if !r.Skipped || r.Reason != SkipLibraryFull {
+		t.Fatalf("oversized wallpaper not skipped as full: %v %s", r.Error, r.Reason)
+	}
+	if len(l.evicted) != 0 {
+		t.Errorf("evicted %v for a wallpaper that cannot fit", l.evicted)
+	}
+	if _, err := os.Stat(l.old.LocalPath); err != nil {
+		t.Errorf("evictable wallpaper was removed: %v", err)
+	}
+}
+
+func TestNoEvictionWhenDownloadFails(t *testing.T) {
+	l := newLibrary(t)
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	results, err := l.d.DownloadWallpapers([]wallhaven.Wallpaper{{ID: "new", Path: srv.URL + "/new.jpg", FileSize: 50}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Error == nil {
+		t.Fatal("download of a missing file succeeded")
+	}
+	if len(l.evicted) != 0 {
+		t.Errorf("evicted %v for a failed download", l.evicted)
+	}
+}
@@ -33,6 +33,11 @@ func (f *WallpaperFilter) ValidateWallpaper(wallpaper wallhaven.Wallpaper) Filte
 		return FilterResult{false, fmt.Sprintf("invalid resolution format: %s", wallpaper.Resolution)}
 	}
 
+	return f.ValidateDimensions(width, height)
+}
+
+// ValidateDimensions checks if image dimensions meet the configuration requirements
+func (f *WallpaperFilter) ValidateDimensions(width, height int) FilterResult {
 	// Check minimum dimensions
 	if f.config.MinWidth > 0 && width < f.config.MinWidth {
 		return FilterResult{false, fmt.Sprintf("width %d < minimum %d", width, f.config.MinWidth)}
@@ -0,0 +1,93 @@
+// Package filelink replaces duplicate files with links to one copy, so
+// several paths can show the same wallpaper while its data is stored once.
+package filelink
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Link modes
+const (
+	// Auto uses a reflink where the filesystem supports it and a hardlink
+	// otherwise
+	Auto = "auto"
+	// Hardlink makes both paths name the same inode
+	Hardlink = "hardlink"
+	// Reflink makes a copy-on-write clone sharing the data blocks (btrfs,
+	// XFS and other filesystems supporting FICLONE); the files stay
+	// independent if either is edited
+	Reflink = "reflink"
+)
+
+// ErrUnsupported is returned when reflinks are not available on the
+// platform or filesystem
+var ErrUnsupported = errors.New("reflinks are not supported here")
+
+// ParseMode validates a link mode
+func ParseMode(mode string) (string, error) {
+	switch mode {
+	case Auto, Hardlink, Reflink:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid link mode: %s (valid: %s, %s, %s)", mode, Auto, Hardlink, Reflink)
+	}
+}
+
+// Replace atomically replaces the file at path with a link to source and
+// returns the mode used. Both must be on the same filesystem. If they are
+// already hardlinked, nothing is changed.
+func Replace(path, source, mode string) (string, error) {
+	if same, err := SameFile(path, source); err == nil && same {
+		return Hardlink, nil
+	}
+
+	switch mode {
+	case Hardlink:
+		return Hardlink, replace(path, source, os.Link)
+	case Reflink:
+		return Reflink, replace(path, source, reflink)
+	default:
+		if err := replace(path, source, reflink); err == nil {
+			return Reflink, nil
+		}
+		return Hardlink, replace(path, source, os.Link)
+	}
+}
+
+// SameFile reports whether two paths name the same inode
+func SameFile(a, b string) (bool, error) {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	ib, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ia, ib), nil
+}
+
+// replace links source to a temporary name next to path and renames it
+// over path, so path never goes missing
+func replace(path, source string, link func(source, target string) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wallfetch-link-*")
+	if err != nil {
+		return fmt.Errorf("failed to create link: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	os.Remove(tmpPath)
+
+	if err := link(source, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to link %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package filelink
+
+// reflink is only implemented on Linux
+func reflink(source, target string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,83 @@
+package imagehash
+
+// BKTree is a metric tree over hashes that finds all hashes within a
+// Hamming distance without comparing against every entry
+type BKTree struct {
+	root *bkNode
+	size int
+}
+
+type bkNode struct {
+	hash     Hash
+	ids      []int
+	children map[int]*bkNode
+}
+
+// Match is a hash found by Search
+type Match struct {
+	ID       int
+	Distance int
+}
+
+// Add inserts a hash with the ID of the item it belongs to. Items with
+// identical hashes share a node.
+func (t *BKTree) Add(h Hash, id int) {
+	t.size++
+	if t.root == nil {
+		t.root = &bkNode{hash: h, ids: []int{id}}
+		return
+	}
+
+	node := t.root
+	for {
+		d := Distance(h, node.hash)
+		if d == 0 {
+			node.ids = append(node.ids, id)
+			return
+		}
+		child, ok := node.children[d]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[d] = &bkNode{hash: h, ids: []int{id}}
+			return
+		}
+		node = child
+	}
+}
+
+// Len returns the number of items in the tree
+func (t *BKTree) Len() int {
+	return t.size
+}
+
+// Search returns the items whose hash is within maxDistance of h
+func (t *BKTree) Search(h Hash, maxDistance int) []Match {
+	if t.root == nil {
+		return nil
+	}
+
+	var matches []Match
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d := Distance(h, node.hash)
+		if d <= maxDistance {
+			for _, id := range node.ids {
+				matches = append(matches, Match{ID: id, Distance: d})
+			}
+		}
+
+		// By the triangle inequality, only children at a distance within
+		// maxDistance of d can hold matches
+		for cd, child := range node.children {
+			if cd >= d-maxDistance && cd <= d+maxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,139 @@
+// Package imagehash computes perceptual hashes of images, which stay nearly
+// the same when an image is resized, re-encoded or slightly edited, and
+// finds near-duplicates by comparing them.
+package imagehash
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+)
+
+// Hash is a 64-bit perceptual hash
+type Hash uint64
+
+// Bits is the number of bits in a hash, and so the largest distance
+const Bits = 64
+
+// pHashSize is the side of the grayscale image the DCT is computed over
+const pHashSize = 32
+
+// PHash computes the DCT-based perceptual hash of an image: each bit tells
+// whether one of the 64 lowest frequencies is above their median. It
+// tolerates scaling, compression and color adjustments well.
+func PHash(img image.Image) Hash {
+	pixels := grayscale(img, pHashSize, pHashSize)
+	coeffs := dct2D(pixels, pHashSize)
+
+	// The 8x8 lowest frequencies carry the structure of the image
+	low := make([]float64, 0, 64)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			low = append(low, coeffs[y*pHashSize+x])
+		}
+	}
+
+	// The DC term is the average brightness and would skew the median. The
+	// 63 other terms have a middle one, so half of the bits are set.
+	sorted := append([]float64(nil), low[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var h Hash
+	for i, c := range low {
+		if c > median {
+			h |= 1 << uint(i)
+		}
+	}
+	return h
+}
+
+// DHash computes the difference hash of an image: each bit tells whether a
+// pixel of a 9x8 grayscale thumbnail is brighter than its right neighbor.
+// It is cheap and follows gradients, complementing PHash.
+func DHash(img image.Image) Hash {
+	const w, h = 9, 8
+	pixels := grayscale(img, w, h)
+
+	var hash Hash
+	bit := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			if pixels[y*w+x] > pixels[y*w+x+1] {
+				hash |= 1 << uint(bit)
+			}
+			bit++
+		}
+	}
+	return hash
+}
+
+// Distance returns the number of bits that differ between two hashes, from
+// 0 for identical images to 64
+func Distance(a, b Hash) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
+
+// String formats a hash as 16 hex digits, as stored in the database
+func (h Hash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// Parse parses a hash formatted by String
+func Parse(s string) (Hash, error) {
+	v, err := strconv.ParseUint(s, 16, 64)
+	if err != nil || len(s) != 16 {
+		return 0, fmt.Errorf("invalid hash: %q", s)
+	}
+	return Hash(v), nil
+}
+
+// grayscale scales img to w x h and returns the luma of its pixels, row by row
+func grayscale(img image.Image, w, h int) []float64 {
+	small := imaging.Resize(img, w, h)
+	pixels := make([]float64, w*h)
+	for i := range pixels {
+		p := small.Pix[i*4 : i*4+3]
+		pixels[i] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+	}
+	return pixels
+}
+
+// dct2D computes the two-dimensional DCT-II of an n x n matrix, first over
+// the rows and then over the columns
+func dct2D(m []float64, n int) []float64 {
+	cos := make([]float64, n*n)
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			cos[k*n+i] = math.Cos(math.Pi / float64(n) * (float64(i) + 0.5) * float64(k))
+		}
+	}
+
+	rows := make([]float64, n*n)
+	for y := 0; y < n; y++ {
+		for k := 0; k < n; k++ {
+			var sum float64
+			for i := 0; i < n; i++ {
+				sum += m[y*n+i] * cos[k*n+i]
+			}
+			rows[y*n+k] = sum
+		}
+	}
+
+	out := make([]float64, n*n)
+	for x := 0; x < n; x++ {
+		for k := 0; k < n; k++ {
+			var sum float64
+			for i := 0; i < n; i++ {
+				sum += rows[i*n+x] * cos[k*n+i]
+			}
+			out[k*n+x] = sum
+		}
+	}
+	return out
+}
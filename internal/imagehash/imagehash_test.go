@@ -0,0 +1,39 @@
+package imagehash
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// pattern returns an image with structure at several frequencies
+func pattern(w, h int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{uint8((x*x + 3*y + x*y/7) % 256)})
+		}
+	}
+	return img
+}
+
+func TestPHashMedian(t *testing.T) {
+	// Without the DC term, 31 of the 63 frequencies are above the median
+	h := PHash(pattern(256, 256))
+	if n := Distance(h>>1, 0); n != 31 {
+		t.Errorf("%d of the 63 bits set, want 31", n)
+	}
+}
+
+func TestParse(t *testing.T) {
+	h := Hash(0x0123456789abcdef)
+	got, err := Parse(h.String())
+	if err != nil || got != h {
+		t.Errorf("Parse(%s) = %s, %v", h, got, err)
+	}
+	for _, s := range []string{"", "123", "0123456789abcdeg", "0123456789abcdef0"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package imaging provides a small pure-Go image pipeline: decoding,
+// cropping and scaling, and encoding, without external tools.
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// Register decoders for the formats wallpaper sites serve
+	_ "image/gif"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// JPEGQuality is the quality used when encoding JPEG files
+const JPEGQuality = 92
+
+// imageExts are the file extensions of the formats wallfetch handles
+var imageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".webp": true,
+}
+
+// IsImageFile reports whether a path has the extension of an image format
+// wallfetch handles
+func IsImageFile(path string) bool {
+	return imageExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// Open decodes an image file. It returns the image and its format name
+// (jpeg, png, gif, webp or bmp).
+func Open(path string) (image.Image, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return img, format, nil
+}
+
+// Dimensions reads the width, height and format name of an image file from
+// its header, without decoding the pixels
+func Dimensions(path string) (width, height int, format string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to read dimensions of %s: %w", filepath.Base(path), err)
+	}
+	return cfg.Width, cfg.Height, format, nil
+}
+
+// Scale crops the src rectangle out of img and resamples it to a new
+// width x height image
+func Scale(img image.Image, src image.Rectangle, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+// Resize scales the whole image to width x height
+func Resize(img image.Image, width, height int) *image.RGBA {
+	return Scale(img, img.Bounds(), width, height)
+}
+
+// Fit scales img down so its longest side is at most size, keeping the
+// aspect ratio. Images that already fit are returned unchanged.
+func Fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	scale := float64(size) / float64(max(w, h))
+	return Resize(img, max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale)))
+}
+
+// Save encodes img to path as JPEG or PNG, chosen by the file extension.
+// The file is written to a temporary name first so readers never see a
+// partial image.
+func Save(path string, img image.Image) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return fmt.Errorf("unsupported output format: %s", ext)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*"+ext)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if ext == ".png" {
+		err = png.Encode(tmp, img)
+	} else {
+		err = jpeg.Encode(tmp, img, &jpeg.Options{Quality: JPEGQuality})
+	}
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,52 @@
+package palette
+
+import (
+	"image"
+	"image/color"
+)
+
+// Brightness classes
+const (
+	Light = "light"
+	Dark  = "dark"
+)
+
+// lightThreshold is the luminance from which a wallpaper counts as light
+const lightThreshold = 0.5
+
+// Analysis is what is computed from a wallpaper's colors
+type Analysis struct {
+	Palette []color.RGBA
+	// Luminance is the average perceived lightness (CIE L*) from 0 to 1
+	Luminance float64
+	// Brightness is Light or Dark
+	Brightness string
+}
+
+// Analyze computes the palette and brightness of an image
+func Analyze(img image.Image) Analysis {
+	pixels := samplePixels(img)
+
+	var sum float64
+	for _, p := range pixels {
+		sum += ToLab(toRGBA(p)).L
+	}
+	var luminance float64
+	if len(pixels) > 0 {
+		luminance = sum / float64(len(pixels)) / 100
+	}
+
+	return Analysis{
+		Palette:    cluster(pixels, Size),
+		Luminance:  luminance,
+		Brightness: Classify(luminance),
+	}
+}
+
+// Classify returns the brightness class for a luminance
+func Classify(luminance float64) string {
+	if luminance >= lightThreshold {
+		return Light
+	}
+	return Dark
+}
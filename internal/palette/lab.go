@@ -0,0 +1,126 @@
+package palette
+
+import (
+	"image/color"
+	"math"
+)
+
+// Lab is a color in the CIE L*a*b* space (D65 white point)
+type Lab struct {
+	L, A, B float64
+}
+
+// ToLab converts an sRGB color to CIE L*a*b*
+func ToLab(c color.RGBA) Lab {
+	lin := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	r, g, b := lin(c.R), lin(c.G), lin(c.B)
+
+	// sRGB to XYZ, normalized by the D65 reference white
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) / 1.00000
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389.0 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27.0*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// DeltaE2000 returns the CIEDE2000 color difference between two colors.
+// Differences below about 2 are hard to see; above 10 colors look distinct.
+func DeltaE2000(c1, c2 Lab) float64 {
+	const deg = math.Pi / 180
+	// Hues that are exactly opposite can come out a rounding error more
+	// than 180° apart, which would flip the cases below
+	const eps = 1e-9
+
+	cab1 := math.Hypot(c1.A, c1.B)
+	cab2 := math.Hypot(c2.A, c2.B)
+	cabMean7 := math.Pow((cab1+cab2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cabMean7/(cabMean7+math.Pow(25, 7))))
+
+	a1 := (1 + g) * c1.A
+	a2 := (1 + g) * c2.A
+	chroma1 := math.Hypot(a1, c1.B)
+	chroma2 := math.Hypot(a2, c2.B)
+
+	hueAngle := func(b, a float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / deg
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1 := hueAngle(c1.B, a1)
+	h2 := hueAngle(c2.B, a2)
+
+	dL := c2.L - c1.L
+	dC := chroma2 - chroma1
+
+	var dh float64
+	if chroma1*chroma2 != 0 {
+		dh = h2 - h1
+		switch {
+		case dh > 180+eps:
+			dh -= 360
+		case dh < -180-eps:
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(chroma1*chroma2) * math.Sin(dh/2*deg)
+
+	lMean := (c1.L + c2.L) / 2
+	cMean := (chroma1 + chroma2) / 2
+
+	hMean := h1 + h2
+	if chroma1*chroma2 != 0 {
+		switch {
+		case math.Abs(h1-h2) <= 180+eps:
+			hMean /= 2
+		case h1+h2 < 360:
+			hMean = (hMean + 360) / 2
+		default:
+			hMean = (hMean - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((hMean-30)*deg) + 0.24*math.Cos(2*hMean*deg) +
+		0.32*math.Cos((3*hMean+6)*deg) - 0.20*math.Cos((4*hMean-63)*deg)
+
+	lMean50 := (lMean - 50) * (lMean - 50)
+	sl := 1 + 0.015*lMean50/math.Sqrt(20+lMean50)
+	sc := 1 + 0.045*cMean
+	sh := 1 + 0.015*cMean*t
+
+	cMean7 := math.Pow(cMean, 7)
+	rc := 2 * math.Sqrt(cMean7/(cMean7+math.Pow(25, 7)))
+	dTheta := 30 * math.Exp(-math.Pow((hMean-275)/25, 2))
+	rt := -math.Sin(2*dTheta*deg) * rc
+
+	return math.Sqrt(math.Pow(dL/sl, 2) + math.Pow(dC/sc, 2) + math.Pow(dH/sh, 2) + rt*(dC/sc)*(dH/sh))
+}
+
+// Distance returns the smallest CIEDE2000 difference between target and
+// any of the colors, or +Inf if there are none
+func Distance(target color.RGBA, colors []color.RGBA) float64 {
+	t := ToLab(target)
+	best := math.Inf(1)
+	for _, c := range colors {
+		best = math.Min(best, DeltaE2000(t, ToLab(c)))
+	}
+	return best
+}
@@ -0,0 +1,81 @@
+package palette
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// sharma are the CIEDE2000 test pairs of Sharma, Wu and Dalal, "The
+// CIEDE2000 Color-Difference Formula: Implementation Notes, Supplementary
+// Test Data, and Mathematical Observations" (2005), with the differences
+// given there to four decimals
+var sharma = []struct {
+	c1, c2 Lab
+	want   float64
+}{
+	{Lab{50, 2.6772, -79.7751}, Lab{50, 0, -82.7485}, 2.0425},
+	{Lab{50, 3.1571, -77.2803}, Lab{50, 0, -82.7485}, 2.8615},
+	{Lab{50, 2.8361, -74.0200}, Lab{50, 0, -82.7485}, 3.4412},
+	{Lab{50, -1.3802, -84.2814}, Lab{50, 0, -82.7485}, 1.0000},
+	{Lab{50, -1.1848, -84.8006}, Lab{50, 0, -82.7485}, 1.0000},
+	{Lab{50, -0.9009, -85.5211}, Lab{50, 0, -82.7485}, 1.0000},
+	{Lab{50, 0, 0}, Lab{50, -1, 2}, 2.3669},
+	{Lab{50, -1, 2}, Lab{50, 0, 0}, 2.3669},
+	{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0009}, 7.1792},
+	{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0010}, 7.1792},
+	{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0011}, 7.2195},
+	{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0012}, 7.2195},
+	{Lab{50, -0.0010, 2.4900}, Lab{50, 0.0009, -2.4900}, 4.8045},
+	{Lab{50, -0.0010, 2.4900}, Lab{50, 0.0010, -2.4900}, 4.8045},
+	{Lab{50, -0.0010, 2.4900}, Lab{50, 0.0011, -2.4900}, 4.7461},
+	{Lab{50, 2.5, 0}, Lab{50, 0, -2.5}, 4.3065},
+	{Lab{50, 2.5, 0}, Lab{73, 25, -18}, 27.1492},
+	{Lab{50, 2.5, 0}, Lab{61, -5, 29}, 22.8977},
+	{Lab{50, 2.5, 0}, Lab{56, -27, -3}, 31.9030},
+	{Lab{50, 2.5, 0}, Lab{58, 24, 15}, 19.4535},
+	{Lab{50, 2.5, 0}, Lab{50, 3.1736, 0.5854}, 1.0000},
+	{Lab{50, 2.5, 0}, Lab{50, 3.2972, 0}, 1.0000},
+	{Lab{50, 2.5, 0}, Lab{50, 1.8634, 0.5757}, 1.0000},
+	{Lab{50, 2.5, 0}, Lab{50, 3.2592, 0.3350}, 1.0000},
+	{Lab{60.2574, -34.0099, 36.2677}, Lab{60.4626, -34.1751, 39.4387}, 1.2644},
+	{Lab{63.0109, -31.0961, -5.8663}, Lab{62.8187, -29.7946, -4.0864}, 1.2630},
+	{Lab{61.2901, 3.7196, -5.3901}, Lab{61.4292, 2.2480, -4.9620}, 1.8731},
+	{Lab{35.0831, -44.1164, 3.7933}, Lab{35.0232, -40.0716, 1.5901}, 1.8645},
+	{Lab{22.7233, 20.0904, -46.6940}, Lab{23.0331, 14.9730, -42.5619}, 2.0373},
+	{Lab{36.4612, 47.8580, 18.3852}, Lab{36.2715, 50.5065, 21.2231}, 1.4146},
+	{Lab{90.8027, -2.0831, 1.4410}, Lab{91.1528, -1.6435, 0.0447}, 1.4441},
+	{Lab{90.9257, -0.5406, -0.9208}, Lab{88.6381, -0.8985, -0.7239}, 1.5381},
+	{Lab{6.7747, -0.2908, -2.4247}, Lab{5.8714, -0.0985, -2.2286}, 0.6377},
+	{Lab{2.0776, 0.0795, -1.1350}, Lab{0.9033, -0.0636, -0.5514}, 0.9082},
+}
+
+func TestDeltaE2000(t *testing.T) {
+	for i, tt := range sharma {
+		got := DeltaE2000(tt.c1, tt.c2)
+		if math.Abs(got-tt.want) > 0.00005 {
+			t.Errorf("pair %d: DeltaE2000(%v, %v) = %.4f, want %.4f", i+1, tt.c1, tt.c2, got, tt.want)
+		}
+		if back := DeltaE2000(tt.c2, tt.c1); math.Abs(back-got) > 1e-9 {
+			t.Errorf("pair %d: difference is %.6f one way and %.6f the other", i+1, got, back)
+		}
+	}
+}
+
+func TestToLab(t *testing.T) {
+	tests := []struct {
+		c    color.RGBA
+		want Lab
+	}{
+		{color.RGBA{0, 0, 0, 255}, Lab{0, 0, 0}},
+		{color.RGBA{255, 255, 255, 255}, Lab{100, 0, 0}},
+		{color.RGBA{255, 0, 0, 255}, Lab{53.2408, 80.0925, 67.2032}},
+		{color.RGBA{0, 0, 255, 255}, Lab{32.2970, 79.1875, -107.8602}},
+	}
+	for _, tt := range tests {
+		got := ToLab(tt.c)
+		if math.Abs(got.L-tt.want.L) > 0.01 || math.Abs(got.A-tt.want.A) > 0.01 || math.Abs(got.B-tt.want.B) > 0.01 {
+			t.Errorf("ToLab(%v) = %.4f, want %.4f", tt.c, got, tt.want)
+		}
+	}
+}
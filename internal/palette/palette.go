@@ -0,0 +1,209 @@
+// Package palette analyzes the colors of wallpapers: it extracts color
+// palettes, measures brightness and turns palettes into terminal and
+// desktop color themes.
+package palette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+)
+
+// Size is the number of colors in an extracted palette
+const Size = 16
+
+// sampleSize is the longest side images are scaled down to before
+// clustering; palettes barely change above it
+const sampleSize = 128
+
+// maxIterations bounds the k-means refinement
+const maxIterations = 24
+
+// rgb is a color with float channels in 0..255
+type rgb [3]float64
+
+// cluster computes a palette of up to n colors using k-means clustering,
+// most common color first. The result is deterministic for given pixels.
+func cluster(pixels []rgb, n int) []color.RGBA {
+	if len(pixels) == 0 || n <= 0 {
+		return nil
+	}
+
+	centroids := initCentroids(pixels, n)
+	counts := make([]int, len(centroids))
+	assignment := make([]int, len(pixels))
+
+	for iter := 0; iter < maxIterations; iter++ {
+		changed := false
+		for i, p := range pixels {
+			nearest := nearestCentroid(centroids, p)
+			if iter == 0 || nearest != assignment[i] {
+				assignment[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([]rgb, len(centroids))
+		for i := range counts {
+			counts[i] = 0
+		}
+		for i, p := range pixels {
+			c := assignment[i]
+			counts[c]++
+			for ch := range p {
+				sums[c][ch] += p[ch]
+			}
+		}
+		for c := range centroids {
+			// Empty clusters keep their previous centroid
+			if counts[c] == 0 {
+				continue
+			}
+			for ch := range sums[c] {
+				centroids[c][ch] = sums[c][ch] / float64(counts[c])
+			}
+		}
+	}
+
+	order := make([]int, len(centroids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+
+	colors := make([]color.RGBA, 0, len(centroids))
+	for _, c := range order {
+		colors = append(colors, toRGBA(centroids[c]))
+	}
+	return colors
+}
+
+// samplePixels returns the opaque pixels of a scaled-down copy of img
+func samplePixels(img image.Image) []rgb {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil
+	}
+
+	small := imaging.Fit(img, sampleSize)
+	sb := small.Bounds()
+
+	pixels := make([]rgb, 0, sb.Dx()*sb.Dy())
+	for y := sb.Min.Y; y < sb.Max.Y; y++ {
+		for x := sb.Min.X; x < sb.Max.X; x++ {
+			c := color.NRGBAModel.Convert(small.At(x, y)).(color.NRGBA)
+			if c.A < 128 {
+				continue
+			}
+			pixels = append(pixels, rgb{float64(c.R), float64(c.G), float64(c.B)})
+		}
+	}
+	return pixels
+}
+
+// initCentroids picks starting centroids with k-means++ using a fixed seed
+func initCentroids(pixels []rgb, n int) []rgb {
+	rng := rand.New(rand.NewPCG(1, uint64(len(pixels))))
+
+	centroids := []rgb{pixels[rng.IntN(len(pixels))]}
+	dist := make([]float64, len(pixels))
+	for len(centroids) < n {
+		var total float64
+		for i, p := range pixels {
+			dist[i] = distance(p, centroids[nearestCentroid(centroids, p)])
+			total += dist[i]
+		}
+		if total == 0 {
+			// Fewer distinct colors than requested
+			break
+		}
+
+		target := rng.Float64() * total
+		next := len(pixels) - 1
+		for i, d := range dist {
+			if target -= d; target <= 0 {
+				next = i
+				break
+			}
+		}
+		centroids = append(centroids, pixels[next])
+	}
+	return centroids
+}
+
+// nearestCentroid returns the index of the centroid closest to p
+func nearestCentroid(centroids []rgb, p rgb) int {
+	best, bestDist := 0, math.MaxFloat64
+	for i, c := range centroids {
+		if d := distance(p, c); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// distance is the squared euclidean distance between two colors
+func distance(a, b rgb) float64 {
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+// toRGBA rounds a float color to 8-bit channels
+func toRGBA(c rgb) color.RGBA {
+	ch := func(v float64) uint8 { return uint8(math.Round(math.Max(0, math.Min(255, v)))) }
+	return color.RGBA{R: ch(c[0]), G: ch(c[1]), B: ch(c[2]), A: 255}
+}
+
+// Hex formats a color as #rrggbb
+func Hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// ParseHex parses a #rrggbb or rrggbb color
+func ParseHex(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color: %q (use #rrggbb)", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color: %q (use #rrggbb)", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// Format joins colors into the comma-separated form stored in the database
+func Format(colors []color.RGBA) string {
+	hex := make([]string, len(colors))
+	for i, c := range colors {
+		hex[i] = Hex(c)
+	}
+	return strings.Join(hex, ",")
+}
+
+// Parse splits a comma-separated list of hex colors, as stored in the
+// database for both computed palettes and source colors
+func Parse(s string) ([]color.RGBA, error) {
+	var colors []color.RGBA
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		c, err := ParseHex(part)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
@@ -0,0 +1,244 @@
+package palette
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Theme formats
+const (
+	FormatKitty      = "kitty"
+	FormatAlacritty  = "alacritty"
+	FormatXresources = "xresources"
+	FormatJSON       = "json"
+	FormatCSS        = "css"
+)
+
+// Formats returns the supported theme formats
+func Formats() []string {
+	return []string{FormatKitty, FormatAlacritty, FormatXresources, FormatJSON, FormatCSS}
+}
+
+// Filename returns the file name a theme format is written to
+func Filename(format string) string {
+	switch format {
+	case FormatKitty:
+		return "colors-kitty.conf"
+	case FormatAlacritty:
+		return "colors-alacritty.toml"
+	case FormatXresources:
+		return "colors.Xresources"
+	case FormatJSON:
+		return "colors.json"
+	case FormatCSS:
+		return "colors.css"
+	default:
+		return "colors-" + format
+	}
+}
+
+var (
+	black = color.RGBA{A: 255}
+	white = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// Theme is a 16-color terminal scheme derived from a wallpaper palette
+type Theme struct {
+	Wallpaper  string
+	Background color.RGBA
+	Foreground color.RGBA
+	Cursor     color.RGBA
+	Colors     [16]color.RGBA
+}
+
+// NewTheme builds a theme from a palette: the darkest color becomes the
+// background, the lightest the foreground, and the most saturated of the
+// rest, ordered by hue, become the six accent colors. Colors are adjusted
+// so text stays readable on the background.
+func NewTheme(colors []color.RGBA, wallpaper string) (Theme, error) {
+	if len(colors) == 0 {
+		return Theme{}, fmt.Errorf("empty palette")
+	}
+
+	sorted := append([]color.RGBA(nil), colors...)
+	sort.SliceStable(sorted, func(i, j int) bool { return luminance(sorted[i]) < luminance(sorted[j]) })
+
+	bg := sorted[0]
+	for i := 0; i < 10 && luminance(bg) > 0.03; i++ {
+		bg = mix(bg, black, 0.25)
+	}
+	fg := sorted[len(sorted)-1]
+	for i := 0; i < 10 && luminance(fg) < 0.75; i++ {
+		fg = mix(fg, white, 0.25)
+	}
+
+	// Accents: the most saturated remaining colors, in hue order
+	accents := sorted
+	if len(accents) > 2 {
+		accents = accents[1 : len(accents)-1]
+	}
+	accents = append([]color.RGBA(nil), accents...)
+	sort.SliceStable(accents, func(i, j int) bool { return saturation(accents[i]) > saturation(accents[j]) })
+	accents = accents[:min(len(accents), 6)]
+	sort.SliceStable(accents, func(i, j int) bool { return hue(accents[i]) < hue(accents[j]) })
+
+	t := Theme{Wallpaper: wallpaper, Background: bg, Foreground: fg, Cursor: fg}
+	t.Colors[0] = bg
+	t.Colors[7] = mix(fg, bg, 0.2)
+	t.Colors[8] = mix(bg, fg, 0.3)
+	t.Colors[15] = fg
+	for i := 0; i < 6; i++ {
+		c := accents[i%len(accents)]
+		for j := 0; j < 10 && contrast(c, bg) < 3; j++ {
+			c = mix(c, white, 0.15)
+		}
+		t.Colors[1+i] = c
+		t.Colors[9+i] = mix(c, white, 0.25)
+	}
+
+	return t, nil
+}
+
+// Render writes the theme in the given format
+func (t Theme) Render(format string) (string, error) {
+	var b strings.Builder
+
+	switch format {
+	case FormatKitty:
+		fmt.Fprintf(&b, "# Generated by wallfetch from %s\n", t.Wallpaper)
+		fmt.Fprintf(&b, "background %s\nforeground %s\ncursor %s\n", Hex(t.Background), Hex(t.Foreground), Hex(t.Cursor))
+		fmt.Fprintf(&b, "selection_background %s\nselection_foreground %s\n", Hex(t.Foreground), Hex(t.Background))
+		for i, c := range t.Colors {
+			fmt.Fprintf(&b, "color%d %s\n", i, Hex(c))
+		}
+
+	case FormatAlacritty:
+		names := []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+		fmt.Fprintf(&b, "# Generated by wallfetch from %s\n", t.Wallpaper)
+		fmt.Fprintf(&b, "[colors.primary]\nbackground = %q\nforeground = %q\n\n", Hex(t.Background), Hex(t.Foreground))
+		fmt.Fprintf(&b, "[colors.cursor]\ncursor = %q\ntext = %q\n", Hex(t.Cursor), Hex(t.Background))
+		for _, section := range []struct {
+			name   string
+			offset int
+		}{{"normal", 0}, {"bright", 8}} {
+			fmt.Fprintf(&b, "\n[colors.%s]\n", section.name)
+			for i, name := range names {
+				fmt.Fprintf(&b, "%s = %q\n", name, Hex(t.Colors[section.offset+i]))
+			}
+		}
+
+	case FormatXresources:
+		fmt.Fprintf(&b, "! Generated by wallfetch from %s\n", t.Wallpaper)
+		fmt.Fprintf(&b, "*.background: %s\n*.foreground: %s\n*.cursorColor: %s\n", Hex(t.Background), Hex(t.Foreground), Hex(t.Cursor))
+		for i, c := range t.Colors {
+			fmt.Fprintf(&b, "*.color%d: %s\n", i, Hex(c))
+		}
+
+	case FormatJSON:
+		// The same layout as pywal's colors.json, so existing templates work
+		type special struct {
+			Background string `json:"background"`
+			Foreground string `json:"foreground"`
+			Cursor     string `json:"cursor"`
+		}
+		colors := make(map[string]string, len(t.Colors))
+		for i, c := range t.Colors {
+			colors[fmt.Sprintf("color%d", i)] = Hex(c)
+		}
+		data, err := json.MarshalIndent(struct {
+			Wallpaper string            `json:"wallpaper"`
+			Special   special           `json:"special"`
+			Colors    map[string]string `json:"colors"`
+		}{
+			Wallpaper: t.Wallpaper,
+			Special:   special{Hex(t.Background), Hex(t.Foreground), Hex(t.Cursor)},
+			Colors:    colors,
+		}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		b.Write(data)
+		b.WriteString("\n")
+
+	case FormatCSS:
+		fmt.Fprintf(&b, "/* Generated by wallfetch from %s */\n:root {\n", t.Wallpaper)
+		fmt.Fprintf(&b, "  --wallpaper: url(%q);\n", t.Wallpaper)
+		fmt.Fprintf(&b, "  --background: %s;\n  --foreground: %s;\n  --cursor: %s;\n", Hex(t.Background), Hex(t.Foreground), Hex(t.Cursor))
+		for i, c := range t.Colors {
+			fmt.Fprintf(&b, "  --color%d: %s;\n", i, Hex(c))
+		}
+		b.WriteString("}\n")
+
+	default:
+		return "", fmt.Errorf("invalid theme format: %s (valid: %s)", format, strings.Join(Formats(), ", "))
+	}
+
+	return b.String(), nil
+}
+
+// mix blends a toward b by t in 0..1
+func mix(a, b color.RGBA, t float64) color.RGBA {
+	ch := func(x, y uint8) uint8 { return uint8(math.Round(float64(x)*(1-t) + float64(y)*t)) }
+	return color.RGBA{R: ch(a.R, b.R), G: ch(a.G, b.G), B: ch(a.B, b.B), A: 255}
+}
+
+// luminance is the WCAG relative luminance of a color
+func luminance(c color.RGBA) float64 {
+	lin := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(c.R) + 0.7152*lin(c.G) + 0.0722*lin(c.B)
+}
+
+// contrast is the WCAG contrast ratio between two colors
+func contrast(a, b color.RGBA) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// saturation is the HSV saturation of a color
+func saturation(c color.RGBA) float64 {
+	hi := max(c.R, c.G, c.B)
+	lo := min(c.R, c.G, c.B)
+	if hi == 0 {
+		return 0
+	}
+	return float64(hi-lo) / float64(hi)
+}
+
+// hue is the HSV hue of a color in degrees
+func hue(c color.RGBA) float64 {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	d := hi - lo
+	if d == 0 {
+		return 0
+	}
+
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
@@ -0,0 +1,157 @@
+package retention
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+)
+
+// OverError is returned by Budget.Reserve for wallpapers that do not fit
+type OverError struct {
+	// Source is the source whose quota would be exceeded, or "" for the
+	// size limit of the whole library
+	Source string
+	Limit  string
+}
+
+func (e *OverError) Error() string {
+	if e.Source == "" {
+		return "library would exceed " + e.Limit
+	}
+	return fmt.Sprintf("%s would exceed its quota of %s", e.Source, e.Limit)
+}
+
+// Budget keeps the library within its size limits while wallpapers are
+// added. Room is reserved before a download starts so concurrent downloads
+// cannot overshoot together. It is safe for concurrent use.
+type Budget struct {
+	maxSize int64
+	quotas  map[string]Quota
+
+	mu  sync.Mutex
+	lib *usage
+}
+
+// NewBudget creates a budget for a library currently holding images
+func NewBudget(maxSize int64, quotas map[string]Quota, images []database.Image) *Budget {
+	return &Budget{maxSize: maxSize, quotas: quotas, lib: newUsage(images)}
+}
+
+// BudgetFromConfig creates a budget from the library block of the config
+// file, or returns nil if it sets no limits
+func BudgetFromConfig(cfg config.LibraryConfig, images []database.Image) (*Budget, error) {
+	var maxSize int64
+	if cfg.MaxSize != "" {
+		size, err := config.ParseSize(cfg.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid library.max_size: %w", err)
+		}
+		maxSize = size
+	}
+
+	quotas := make(map[string]Quota)
+	for source, value := range cfg.SourceQuotas {
+		q, err := ParseQuota(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid library.source_quotas.%s: %w", source, err)
+		}
+		quotas[source] = q
+	}
+
+	if maxSize == 0 && len(quotas) == 0 {
+		return nil, nil
+	}
+	return NewBudget(maxSize, quotas, images), nil
+}
+
+// String describes the limits in one line
+func (b *Budget) String() string {
+	p := Policy{MaxSize: b.maxSize, SourceQuotas: b.quotas}
+	return p.String()
+}
+
+// Reserve claims room for a new wallpaper from source taking size bytes,
+// or returns an *OverError if it does not fit
+func (b *Budget) Reserve(source string, size int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.fits(source, size); err != nil {
+		return err
+	}
+
+	t := b.lib.source(source)
+	b.lib.count++
+	b.lib.size += size
+	t.count++
+	t.size += size
+	return nil
+}
+
+// fits returns an *OverError if one more wallpaper from source taking size
+// bytes would exceed a limit. The caller holds mu.
+func (b *Budget) fits(source string, size int64) error {
+	t := b.lib.source(source)
+	if q, ok := b.quotas[source]; ok {
+		if (q.Count > 0 && t.count+1 > q.Count) || (q.Size > 0 && t.size+size > q.Size) {
+			return &OverError{Source: source, Limit: q.String()}
+		}
+	}
+	if b.maxSize > 0 && b.lib.size+size > b.maxSize {
+		return &OverError{Limit: config.FormatSize(b.maxSize)}
+	}
+	return nil
+}
+
+// Release gives back a reservation that was not used
+func (b *Budget) Release(source string, size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.lib.source(source)
+	b.lib.count--
+	b.lib.size -= size
+	t.count--
+	t.size -= size
+}
+
+// Resize corrects a reservation once the real size of the wallpaper is
+// known. The wallpaper is kept even if it turns out larger than reserved.
+func (b *Budget) Resize(source string, reserved, actual int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lib.size += actual - reserved
+	b.lib.source(source).size += actual - reserved
+}
+
+// Removed accounts for an image taken out of the library and returns the
+// space it freed
+func (b *Budget) Removed(img *database.Image) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lib.remove(img)
+}
+
+// Restore accounts for an image given back after Removed, when it was not
+// removed after all. Unless force is set, it is only given back if it fits
+// within the limits; Restore reports whether it was.
+func (b *Budget) Restore(img *database.Image, force bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !force {
+		size := img.FileSize
+		if img.LinkGroup != 0 && b.lib.groups[img.LinkGroup] > 0 {
+			size = 0 // its data is still used by a linked copy
+		}
+		if b.fits(img.Source, size) != nil {
+			return false
+		}
+	}
+	b.lib.add(img)
+	return true
+}
@@ -0,0 +1,131 @@
+package retention
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+)
+
+func TestBudgetReserve(t *testing.T) {
+	images := []database.Image{
+		stored(1, "wallhaven", 300, 10),
+		stored(2, "local", 200, 5),
+	}
+	b := NewBudget(1000, map[string]Quota{
+		"wallhaven": {Count: 2},
+		"local":     {Size: 250},
+	}, images)
+
+	if err := b.Reserve("wallhaven", 100); err != nil {
+		t.Fatalf("first reservation: %v", err)
+	}
+
+	var over *OverError
+	if err := b.Reserve("wallhaven", 100); !errors.As(err, &over) || over.Source != "wallhaven" {
+		t.Errorf("reservation over the count quota: %v", err)
+	}
+	if err := b.Reserve("local", 100); !errors.As(err, &over) || over.Source != "local" {
+		t.Errorf("reservation over the size quota: %v", err)
+	}
+	if err := b.Reserve("reddit", 500); !errors.As(err, &over) || over.Source != "" {
+		t.Errorf("reservation over the library size: %v", err)
+	}
+	if err := b.Reserve("reddit", 400); err != nil {
+		t.Errorf("reservation that just fits: %v", err)
+	}
+
+	// Giving back a reservation makes room again
+	b.Release("wallhaven", 100)
+	if err := b.Reserve("wallhaven", 100); err != nil {
+		t.Errorf("reservation after a release: %v", err)
+	}
+}
+
+func TestBudgetResize(t *testing.T) {
+	b := NewBudget(1000, nil, nil)
+	if err := b.Reserve("wallhaven", 100); err != nil {
+		t.Fatal(err)
+	}
+
+	// The wallpaper turned out larger than reserved
+	b.Resize("wallhaven", 100, 800)
+	if err := b.Reserve("wallhaven", 300); err == nil {
+		t.Error("reservation beyond the resized wallpaper succeeded")
+	}
+	if err := b.Reserve("wallhaven", 200); err != nil {
+		t.Errorf("reservation within the limit: %v", err)
+	}
+}
+
+func TestBudgetRemovedAndRestore(t *testing.T) {
+	old := stored(1, "wallhaven", 600, 10)
+	b := NewBudget(1000, nil, []database.Image{old})
+
+	if freed := b.Removed(&old); freed != 600 {
+		t.Errorf("removing freed %d, want 600", freed)
+	}
+	if err := b.Reserve("wallhaven", 500); err != nil {
+		t.Fatalf("reservation in the freed room: %v", err)
+	}
+
+	// The room was taken, so it is only given back when forced
+	if b.Restore(&old, false) {
+		t.Error("restored an image that no longer fits")
+	}
+	if !b.Restore(&old, true) {
+		t.Error("forced restore failed")
+	}
+	if err := b.Reserve("wallhaven", 1); err == nil {
+		t.Error("reservation succeeded over a forced restore")
+	}
+
+	b.Release("wallhaven", 500)
+	if err := b.Reserve("wallhaven", 400); err != nil {
+		t.Errorf("reservation after a release: %v", err)
+	}
+}
+
+func TestBudgetRestoreLinkedCopy(t *testing.T) {
+	// Restoring one of two linked copies takes no room while the other
+	// still holds their data
+	a := stored(1, "wallhaven", 600, 10)
+	a.LinkGroup = 1
+	c := stored(2, "wallhaven", 600, 5)
+	c.LinkGroup = 1
+	b := NewBudget(1000, nil, []database.Image{a, c})
+
+	b.Removed(&a)
+	if err := b.Reserve("wallhaven", 400); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Restore(&a, false) {
+		t.Error("linked copy not restored")
+	}
+}
+
+func TestBudgetFromConfig(t *testing.T) {
+	b, err := BudgetFromConfig(config.LibraryConfig{}, nil)
+	if err != nil || b != nil {
+		t.Errorf("budget without limits = %v, %v, want none", b, err)
+	}
+
+	b, err = BudgetFromConfig(config.LibraryConfig{
+		MaxSize:      "20GB",
+		SourceQuotas: map[string]string{"wallhaven": "500"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "at most 20.0 GB, wallhaven at most 500 wallpapers"; b.String() != want {
+		t.Errorf("budget %q, want %q", b, want)
+	}
+
+	if _, err := BudgetFromConfig(config.LibraryConfig{MaxSize: "lots"}, nil); err == nil {
+		t.Error("invalid max_size accepted")
+	}
+	if _, err := BudgetFromConfig(config.LibraryConfig{SourceQuotas: map[string]string{"local": "x"}}, nil); err == nil {
+		t.Error("invalid quota accepted")
+	}
+}
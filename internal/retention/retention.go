@@ -0,0 +1,396 @@
+// Package retention decides which wallpapers prune removes. A policy
+// combines limits (how many wallpapers or how much disk space to keep,
+// overall and per source), filters (which wallpapers may be removed at all)
+// and protections (which are never removed).
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+)
+
+// Quota limits a source to a number of wallpapers or an amount of disk space
+type Quota struct {
+	Count int
+	Size  int64
+}
+
+// ParseQuota parses a quota given as a count (500) or a size (10GB)
+func ParseQuota(s string) (Quota, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return Quota{Count: n}, nil
+	}
+	size, err := config.ParseSize(s)
+	if err != nil {
+		return Quota{}, fmt.Errorf("invalid quota: %s (use a count like 500 or a size like 10GB)", s)
+	}
+	return Quota{Size: size}, nil
+}
+
+// String formats a quota the way ParseQuota reads it
+func (q Quota) String() string {
+	if q.Size > 0 {
+		return config.FormatSize(q.Size)
+	}
+	if q.Count == 1 {
+		return "1 wallpaper"
+	}
+	return fmt.Sprintf("%d wallpapers", q.Count)
+}
+
+// Policy decides which wallpapers are removed. Limits evict the oldest
+// eligible wallpapers until the library fits; without limits, every
+// eligible wallpaper is removed. A wallpaper is eligible when it matches
+// all filters and no protection.
+type Policy struct {
+	// Limits; zero means no limit
+	Keep         int
+	MaxSize      int64
+	SourceQuotas map[string]Quota
+
+	// Filters
+	OlderThan  time.Duration
+	NeverShown bool
+
+	// Protections
+	KeepFavorites bool
+	MinRating     int
+}
+
+// FromConfig builds a policy from the retention block of the config file
+func FromConfig(cfg config.RetentionConfig) (Policy, error) {
+	p := Policy{
+		Keep:          cfg.Keep,
+		NeverShown:    cfg.NeverShown,
+		KeepFavorites: cfg.KeepFavorites,
+		MinRating:     cfg.MinRating,
+	}
+	if p.Keep < 0 {
+		return p, fmt.Errorf("invalid retention.keep: %d", cfg.Keep)
+	}
+	if p.MinRating < 0 || p.MinRating > 5 {
+		return p, fmt.Errorf("invalid retention.min_rating: %d (use 1-5, or 0 to disable)", cfg.MinRating)
+	}
+
+	var err error
+	if cfg.MaxSize != "" {
+		if p.MaxSize, err = config.ParseSize(cfg.MaxSize); err != nil {
+			return p, fmt.Errorf("invalid retention.max_size: %w", err)
+		}
+	}
+	if cfg.OlderThan != "" {
+		if p.OlderThan, err = config.ParseAge(cfg.OlderThan); err != nil {
+			return p, fmt.Errorf("invalid retention.older_than: %w", err)
+		}
+	}
+	for source, value := range cfg.SourceQuotas {
+		q, err := ParseQuota(value)
+		if err != nil {
+			return p, fmt.Errorf("invalid retention.source_quotas.%s: %w", source, err)
+		}
+		p.SetQuota(source, q)
+	}
+	return p, nil
+}
+
+// SetQuota sets or replaces the quota of a source
+func (p *Policy) SetQuota(source string, q Quota) {
+	if p.SourceQuotas == nil {
+		p.SourceQuotas = make(map[string]Quota)
+	}
+	p.SourceQuotas[source] = q
+}
+
+// HasLimits reports whether the policy limits the size of the library
+func (p Policy) HasLimits() bool {
+	return p.Keep > 0 || p.MaxSize > 0 || len(p.SourceQuotas) > 0
+}
+
+// HasFilters reports whether the policy restricts which wallpapers may go
+func (p Policy) HasFilters() bool {
+	return p.OlderThan > 0 || p.NeverShown
+}
+
+// String describes the policy in one line
+func (p Policy) String() string {
+	var parts []string
+	if p.Keep > 0 {
+		parts = append(parts, fmt.Sprintf("keep %d wallpapers", p.Keep))
+	}
+	if p.MaxSize > 0 {
+		parts = append(parts, "at most "+config.FormatSize(p.MaxSize))
+	}
+	for _, source := range sortedSources(p.SourceQuotas) {
+		parts = append(parts, fmt.Sprintf("%s at most %s", source, p.SourceQuotas[source]))
+	}
+	if p.OlderThan > 0 {
+		parts = append(parts, "older than "+formatAge(p.OlderThan))
+	}
+	if p.NeverShown {
+		parts = append(parts, "never shown")
+	}
+
+	var protected []string
+	if p.KeepFavorites {
+		protected = append(protected, "favorites")
+	}
+	if p.MinRating > 0 {
+		protected = append(protected, fmt.Sprintf("rated %d+", p.MinRating))
+	}
+
+	s := strings.Join(parts, ", ")
+	if len(protected) > 0 {
+		s += "; protecting " + strings.Join(protected, " and ")
+	}
+	return s
+}
+
+// Protects reports whether the policy never removes an image. Images on
+// unavailable library roots are always protected, as their files cannot be
+// reached.
+func (p Policy) Protects(img *database.Image) bool {
+	return img.Unavailable || (p.KeepFavorites && img.Favorite) || (p.MinRating > 0 && img.Rating >= p.MinRating)
+}
+
+// filterReasons returns why an image matches the filters, or ok false if
+// it does not match them all
+func (p Policy) filterReasons(img *database.Image, shown map[int]bool, now time.Time) (reasons []string, ok bool) {
+	if p.OlderThan > 0 {
+		age := now.Sub(img.DownloadedAt)
+		if age <= p.OlderThan {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("downloaded %s ago", formatAge(age)))
+	}
+	if p.NeverShown {
+		if shown[img.ID] {
+			return nil, false
+		}
+		reasons = append(reasons, "never shown")
+	}
+	return reasons, true
+}
+
+// Evictable returns the images the policy may remove, in the order it
+// removes them: oldest first. shown holds the IDs of images rotation has
+// shown.
+func (p Policy) Evictable(images []database.Image, shown map[int]bool, now time.Time) []database.Image {
+	var evictable []database.Image
+	for i := range images {
+		img := &images[i]
+		if p.Protects(img) {
+			continue
+		}
+		if _, ok := p.filterReasons(img, shown, now); ok {
+			evictable = append(evictable, *img)
+		}
+	}
+	sort.SliceStable(evictable, func(i, j int) bool {
+		if !evictable[i].DownloadedAt.Equal(evictable[j].DownloadedAt) {
+			return evictable[i].DownloadedAt.Before(evictable[j].DownloadedAt)
+		}
+		return evictable[i].ID < evictable[j].ID
+	})
+	return evictable
+}
+
+// Candidate is an image selected for removal
+type Candidate struct {
+	Image  database.Image
+	Reason string
+	// Freed is the disk space removing it frees; 0 when its data is still
+	// used by images linked to it that are kept
+	Freed int64
+}
+
+// Plan is the selection made by a policy
+type Plan struct {
+	Candidates []Candidate
+	Freed      int64
+	// Unmet describes limits that cannot be reached without removing
+	// protected or filtered-out wallpapers
+	Unmet []string
+}
+
+// Select chooses the images to remove from the library. It does not touch
+// the database or the files.
+func (p Policy) Select(images []database.Image, shown map[int]bool, now time.Time) Plan {
+	var plan Plan
+	lib := newUsage(images)
+	evictable := p.Evictable(images, shown, now)
+	selected := make(map[int]bool)
+
+	remove := func(img database.Image, reason string) {
+		reasons, _ := p.filterReasons(&img, shown, now)
+		if reason != "" {
+			reasons = append([]string{reason}, reasons...)
+		}
+		freed := lib.remove(&img)
+		selected[img.ID] = true
+		plan.Freed += freed
+		plan.Candidates = append(plan.Candidates, Candidate{
+			Image:  img,
+			Reason: strings.Join(reasons, ", "),
+			Freed:  freed,
+		})
+	}
+
+	if !p.HasLimits() {
+		for _, img := range evictable {
+			remove(img, "")
+		}
+		return plan
+	}
+
+	// Source quotas first, so the overall limits only take what is left
+	for _, source := range sortedSources(p.SourceQuotas) {
+		q := p.SourceQuotas[source]
+		over := func() bool {
+			t := lib.sources[source]
+			return t != nil && ((q.Count > 0 && t.count > q.Count) || (q.Size > 0 && t.size > q.Size))
+		}
+		for _, img := range evictable {
+			if !over() {
+				break
+			}
+			if img.Source == source && !selected[img.ID] {
+				remove(img, fmt.Sprintf("%s over its quota of %s", source, q))
+			}
+		}
+		if over() {
+			t := lib.sources[source]
+			plan.Unmet = append(plan.Unmet, fmt.Sprintf("%s keeps %d wallpapers (%s), over its quota of %s",
+				source, t.count, config.FormatSize(t.size), q))
+		}
+	}
+
+	if p.Keep > 0 {
+		for _, img := range evictable {
+			if lib.count <= p.Keep {
+				break
+			}
+			if !selected[img.ID] {
+				remove(img, fmt.Sprintf("library over %d wallpapers", p.Keep))
+			}
+		}
+		if lib.count > p.Keep {
+			plan.Unmet = append(plan.Unmet, fmt.Sprintf("library keeps %d wallpapers, over the limit of %d",
+				lib.count, p.Keep))
+		}
+	}
+
+	if p.MaxSize > 0 {
+		for _, img := range evictable {
+			if lib.size <= p.MaxSize {
+				break
+			}
+			if !selected[img.ID] {
+				remove(img, "library over "+config.FormatSize(p.MaxSize))
+			}
+		}
+		if lib.size > p.MaxSize {
+			plan.Unmet = append(plan.Unmet, fmt.Sprintf("library keeps %s, over the limit of %s",
+				config.FormatSize(lib.size), config.FormatSize(p.MaxSize)))
+		}
+	}
+
+	return plan
+}
+
+// tally counts wallpapers and their disk space
+type tally struct {
+	count int
+	size  int64
+}
+
+// usage tracks how much of the library is used as images are removed.
+// Images linked by dedupe --link share their data, which is only counted
+// once and only freed with the last of them.
+type usage struct {
+	tally
+	sources map[string]*tally
+	groups  map[int]int
+}
+
+func newUsage(images []database.Image) *usage {
+	u := &usage{sources: make(map[string]*tally), groups: make(map[int]int)}
+	for i := range images {
+		img := &images[i]
+		t := u.source(img.Source)
+		u.count++
+		t.count++
+		if img.LinkGroup == 0 || u.groups[img.LinkGroup] == 0 {
+			u.size += img.FileSize
+			t.size += img.FileSize
+		}
+		if img.LinkGroup != 0 {
+			u.groups[img.LinkGroup]++
+		}
+	}
+	return u
+}
+
+func (u *usage) source(name string) *tally {
+	t, ok := u.sources[name]
+	if !ok {
+		t = &tally{}
+		u.sources[name] = t
+	}
+	return t
+}
+
+// remove takes an image out of the counts and returns the space freed
+func (u *usage) remove(img *database.Image) int64 {
+	t := u.source(img.Source)
+	u.count--
+	t.count--
+	if img.LinkGroup != 0 {
+		u.groups[img.LinkGroup]--
+		if u.groups[img.LinkGroup] > 0 {
+			return 0
+		}
+	}
+	u.size -= img.FileSize
+	t.size = max(t.size-img.FileSize, 0)
+	return img.FileSize
+}
+
+// add puts an image back into the counts, undoing remove
+func (u *usage) add(img *database.Image) {
+	t := u.source(img.Source)
+	u.count++
+	t.count++
+	if img.LinkGroup != 0 {
+		u.groups[img.LinkGroup]++
+		if u.groups[img.LinkGroup] > 1 {
+			return
+		}
+	}
+	u.size += img.FileSize
+	t.size += img.FileSize
+}
+
+// formatAge formats a duration in days, or hours when under two days and
+// not a whole number of days
+func formatAge(d time.Duration) string {
+	if d < 48*time.Hour && d%(24*time.Hour) != 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func sortedSources(quotas map[string]Quota) []string {
+	sources := make([]string, 0, len(quotas))
+	for source := range quotas {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
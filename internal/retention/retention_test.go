@@ -0,0 +1,132 @@
+package retention
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+)
+
+var now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+// stored returns a wallpaper from source of size bytes downloaded days ago
+func stored(id int, source string, size int64, days int) database.Image {
+	return database.Image{
+		ID:           id,
+		Source:       source,
+		FileSize:     size,
+		DownloadedAt: now.AddDate(0, 0, -days),
+	}
+}
+
+func ids(plan Plan) []int {
+	var ids []int
+	for _, c := range plan.Candidates {
+		ids = append(ids, c.Image.ID)
+	}
+	return ids
+}
+
+func TestSelect(t *testing.T) {
+	favorite := stored(2, "wallhaven", 100, 50)
+	favorite.Favorite = true
+	rated := stored(3, "wallhaven", 100, 40)
+	rated.Rating = 4
+	unavailable := stored(7, "local", 100, 60)
+	unavailable.Unavailable = true
+
+	images := []database.Image{
+		stored(1, "wallhaven", 100, 30),
+		favorite,
+		rated,
+		stored(4, "wallhaven", 100, 20),
+		stored(5, "local", 100, 10),
+		stored(6, "local", 100, 1),
+		unavailable,
+	}
+	shown := map[int]bool{4: true}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []int
+		unmet  int
+	}{
+		{"filters only", Policy{OlderThan: 15 * 24 * time.Hour}, []int{2, 3, 1, 4}, 0},
+		{"never shown", Policy{NeverShown: true, KeepFavorites: true}, []int{3, 1, 5, 6}, 0},
+		{"keep oldest first", Policy{Keep: 5}, []int{2, 3}, 0},
+		{"keep with protections", Policy{Keep: 5, KeepFavorites: true, MinRating: 4}, []int{1, 4}, 0},
+		{"keep within the filters", Policy{Keep: 3, OlderThan: 25 * 24 * time.Hour, KeepFavorites: true}, []int{3, 1}, 1},
+		{"max size", Policy{MaxSize: 450}, []int{2, 3, 1}, 0},
+		{"count quota", Policy{SourceQuotas: map[string]Quota{"local": {Count: 2}}}, []int{5}, 0},
+		{"size quota", Policy{SourceQuotas: map[string]Quota{"wallhaven": {Size: 150}}}, []int{2, 3, 1}, 0},
+		{"quota then keep", Policy{Keep: 4, SourceQuotas: map[string]Quota{"local": {Count: 2}}}, []int{5, 2, 3}, 0},
+		{"unavailable always kept", Policy{Keep: 1}, []int{2, 3, 1, 4, 5, 6}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.policy.Select(images, shown, now)
+			if got := ids(plan); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+			if len(plan.Unmet) != tt.unmet {
+				t.Errorf("unmet %q, want %d", plan.Unmet, tt.unmet)
+			}
+		})
+	}
+}
+
+func TestSelectLinkedCopies(t *testing.T) {
+	// 1 and 2 share their data, which is only freed with the second of them
+	a := stored(1, "wallhaven", 100, 30)
+	a.LinkGroup = 1
+	b := stored(2, "wallhaven", 100, 20)
+	b.LinkGroup = 1
+	images := []database.Image{a, b, stored(3, "wallhaven", 100, 10)}
+
+	plan := Policy{MaxSize: 150}.Select(images, nil, now)
+	if got := ids(plan); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("selected %v, want [1 2]", got)
+	}
+	if plan.Candidates[0].Freed != 0 || plan.Candidates[1].Freed != 100 || plan.Freed != 100 {
+		t.Errorf("freed %d and %d (%d in all), want 0 and 100",
+			plan.Candidates[0].Freed, plan.Candidates[1].Freed, plan.Freed)
+	}
+}
+
+func TestSelectReasons(t *testing.T) {
+	images := []database.Image{stored(1, "wallhaven", 100, 30), stored(2, "wallhaven", 100, 1)}
+
+	plan := Policy{Keep: 1, OlderThan: 7 * 24 * time.Hour, NeverShown: true}.Select(images, nil, now)
+	if len(plan.Candidates) != 1 {
+		t.Fatalf("selected %v, want one", ids(plan))
+	}
+	want := "library over 1 wallpapers, downloaded 30d ago, never shown"
+	if got := plan.Candidates[0].Reason; got != want {
+		t.Errorf("reason %q, want %q", got, want)
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Quota
+	}{
+		{"500", Quota{Count: 500}},
+		{" 0 ", Quota{}},
+		{"10GB", Quota{Size: 10 << 30}},
+		{"512MB", Quota{Size: 512 << 20}},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuota(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseQuota(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "-1", "lots"} {
+		if q, err := ParseQuota(in); err == nil {
+			t.Errorf("ParseQuota(%q) = %v, want an error", in, q)
+		}
+	}
+}
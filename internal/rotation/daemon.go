@@ -0,0 +1,363 @@
+package rotation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+)
+
+// Control commands understood by the daemon
+const (
+	CmdNext   = "next"
+	CmdPrev   = "prev"
+	CmdPause  = "pause"
+	CmdResume = "resume"
+	CmdStatus = "status"
+	CmdStop   = "stop"
+)
+
+// configPollInterval is how often the daemon checks the config for changes
+const configPollInterval = 5 * time.Second
+
+// maxSleep bounds each wait so wall-clock schedules stay accurate across
+// suspend and clock changes
+const maxSleep = time.Minute
+
+// RuntimeDir returns the directory holding the daemon's PID and socket files
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "wallfetch")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("wallfetch-%d", os.Getuid()))
+}
+
+// SocketPath returns the path of the daemon's control socket
+func SocketPath() string {
+	return filepath.Join(RuntimeDir(), "daemon.sock")
+}
+
+// PIDPath returns the path of the daemon's PID file
+func PIDPath() string {
+	return filepath.Join(RuntimeDir(), "daemon.pid")
+}
+
+// Send sends a control command to the running daemon and returns its reply
+func Send(command string) (string, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(), 2*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("wallfetch daemon is not running")
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read reply: %w", err)
+	}
+
+	status, message, _ := strings.Cut(strings.TrimSpace(line), " ")
+	if status != "ok" {
+		return "", fmt.Errorf("%s", message)
+	}
+	return message, nil
+}
+
+// Daemon runs a Rotator on a schedule and serves control commands
+type Daemon struct {
+	Rotator *Rotator
+
+	// ConfigPath is watched for changes; Reload is called to rebuild the
+	// options when it changes
+	ConfigPath string
+	Reload     func() (Options, error)
+
+	// DetectMonitors, if set, is called before each change so plugging
+	// monitors in and out is picked up
+	DetectMonitors func() ([]desktop.Monitor, error)
+
+	// Logf reports what the daemon is doing
+	Logf func(format string, args ...interface{})
+}
+
+// request is a control command waiting for the main loop to handle it
+type request struct {
+	command string
+	reply   chan string
+	written chan struct{} // closed once the reply reached the client
+}
+
+// Run rotates wallpapers until ctx is cancelled or a stop command arrives
+func (d *Daemon) Run(ctx context.Context) error {
+	if _, err := Send(CmdStatus); err == nil {
+		return fmt.Errorf("wallfetch daemon is already running")
+	}
+
+	listener, err := d.listen()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		listener.Close()
+		os.Remove(SocketPath())
+		os.Remove(PIDPath())
+	}()
+
+	requests := make(chan request)
+	go d.serve(listener, requests)
+
+	d.rotate(CmdNext)
+
+	paused := false
+	brightness := WantedBrightness(d.Rotator.Options().Daylight, time.Now())
+	next := d.Rotator.Options().Schedule.Next(time.Now())
+	timer := time.NewTimer(sleepUntil(next))
+	defer timer.Stop()
+
+	reloadTicker := time.NewTicker(configPollInterval)
+	defer reloadTicker.Stop()
+	configMod := modTime(d.ConfigPath)
+
+	d.Logf("Next wallpaper at %s", next.Format("15:04:05"))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-timer.C:
+			// Switch wallpapers right away at sunrise and sunset
+			wanted := WantedBrightness(d.Rotator.Options().Daylight, time.Now())
+			switched := wanted != brightness && wanted != ""
+			brightness = wanted
+
+			if !paused && (switched || !time.Now().Before(next)) {
+				if switched {
+					d.Logf("🌓 Switching to %s wallpapers", wanted)
+				}
+				d.rotate(CmdNext)
+				next = d.Rotator.Options().Schedule.Next(time.Now())
+				d.Logf("Next wallpaper at %s", next.Format("15:04:05"))
+			} else if paused && !time.Now().Before(next) {
+				// Skip the rotations missed while paused, or the timer
+				// would fire again right away
+				next = d.Rotator.Options().Schedule.Next(time.Now())
+			}
+			timer.Reset(sleepUntil(next))
+
+		case <-reloadTicker.C:
+			if mod := modTime(d.ConfigPath); !mod.Equal(configMod) {
+				configMod = mod
+				if d.reload() {
+					next = d.Rotator.Options().Schedule.Next(time.Now())
+					timer.Reset(sleepUntil(next))
+					d.Logf("Next wallpaper at %s", next.Format("15:04:05"))
+				}
+			}
+
+		case req := <-requests:
+			switch req.command {
+			case CmdNext, CmdPrev:
+				req.reply <- d.rotate(req.command)
+				// A manual change restarts the interval
+				next = d.Rotator.Options().Schedule.Next(time.Now())
+				timer.Reset(sleepUntil(next))
+			case CmdPause:
+				paused = true
+				d.Logf("⏸️  Paused")
+				req.reply <- "ok paused"
+			case CmdResume:
+				paused = false
+				next = d.Rotator.Options().Schedule.Next(time.Now())
+				timer.Reset(sleepUntil(next))
+				d.Logf("▶️  Resumed, next wallpaper at %s", next.Format("15:04:05"))
+				req.reply <- "ok resumed"
+			case CmdStatus:
+				req.reply <- "ok " + d.status(paused, next)
+			case CmdStop:
+				d.Logf("Stopping")
+				req.reply <- "ok stopped"
+				// Let the client see the reply before the socket goes away
+				select {
+				case <-req.written:
+				case <-time.After(time.Second):
+				}
+				return nil
+			default:
+				req.reply <- "error unknown command: " + req.command
+			}
+		}
+	}
+}
+
+// listen writes the PID file and opens the control socket
+func (d *Daemon) listen() (net.Listener, error) {
+	if err := os.MkdirAll(RuntimeDir(), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create runtime directory: %w", err)
+	}
+
+	if err := os.WriteFile(PIDPath(), []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write PID file: %w", err)
+	}
+
+	// A socket left behind by a crashed daemon would block Listen
+	os.Remove(SocketPath())
+
+	listener, err := net.Listen("unix", SocketPath())
+	if err != nil {
+		os.Remove(PIDPath())
+		return nil, fmt.Errorf("failed to open control socket: %w", err)
+	}
+	_ = os.Chmod(SocketPath(), 0600)
+
+	return listener, nil
+}
+
+// serve accepts control connections and forwards commands to the main loop
+func (d *Daemon) serve(listener net.Listener, requests chan<- request) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			req := request{
+				command: strings.TrimSpace(line),
+				reply:   make(chan string, 1),
+				written: make(chan struct{}),
+			}
+			requests <- req
+			fmt.Fprintln(conn, <-req.reply)
+			close(req.written)
+		}(conn)
+	}
+}
+
+// rotate moves to the next or previous wallpaper and returns a control reply
+func (d *Daemon) rotate(command string) string {
+	if d.DetectMonitors != nil {
+		if monitors, err := d.DetectMonitors(); err == nil && len(monitors) > 0 {
+			opts := d.Rotator.Options()
+			opts.Monitors = monitors
+			d.Rotator.SetOptions(opts)
+		}
+	}
+
+	var images []database.Image
+	var err error
+	if command == CmdPrev {
+		images, err = d.Rotator.Prev()
+	} else {
+		images, err = d.Rotator.Next()
+	}
+
+	if err != nil {
+		d.Logf("❌ %v", err)
+		return "error " + err.Error()
+	}
+
+	var shown []string
+	for _, img := range images {
+		d.Logf("✅ Set wallpaper ID %d: %s", img.ID, filepath.Base(img.LocalPath))
+		shown = append(shown, fmt.Sprintf("ID %d: %s", img.ID, img.LocalPath))
+	}
+	return "ok " + strings.Join(shown, ", ")
+}
+
+// reload rebuilds the rotator options after a config change
+func (d *Daemon) reload() bool {
+	if d.Reload == nil {
+		return false
+	}
+
+	opts, err := d.Reload()
+	if err != nil {
+		d.Logf("⚠️  Config reload failed, keeping previous settings: %v", err)
+		return false
+	}
+
+	d.Rotator.SetOptions(opts)
+	d.Logf("🔄 Reloaded config: pool %s, %s, backend %s", opts.Pool, opts.Schedule, opts.Backend.Name())
+	return true
+}
+
+// status describes the daemon state for the status command
+func (d *Daemon) status(paused bool, next time.Time) string {
+	opts := d.Rotator.Options()
+
+	state := "running"
+	if paused {
+		state = "paused"
+	}
+
+	parts := []string{
+		state,
+		"pool " + opts.Pool.String(),
+		opts.Schedule.String(),
+		"backend " + opts.Backend.Name(),
+	}
+	if !paused {
+		parts = append(parts, "next at "+next.Format("2006-01-02 15:04:05"))
+	}
+	if len(opts.Monitors) > 1 {
+		mode := "same wallpaper on"
+		switch {
+		case opts.spanning():
+			mode = "spanning"
+		case opts.perMonitor():
+			mode = "per-monitor on"
+		}
+		parts = append(parts, fmt.Sprintf("%s %d monitors", mode, len(opts.Monitors)))
+	}
+	if opts.Daylight != nil {
+		parts = append(parts, fmt.Sprintf("%s, preferring %s wallpapers", opts.Daylight, WantedBrightness(opts.Daylight, time.Now())))
+	}
+	for _, img := range d.Rotator.Current() {
+		parts = append(parts, "current "+img.LocalPath)
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// sleepUntil returns how long to wait for t, capped at maxSleep
+func sleepUntil(t time.Time) time.Duration {
+	d := time.Until(t)
+	if d > maxSleep {
+		return maxSleep
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// modTime returns a file's modification time, or the zero time if it
+// cannot be read
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
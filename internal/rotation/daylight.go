@@ -0,0 +1,203 @@
+package rotation
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/palette"
+)
+
+// Time-of-day modes
+const (
+	TimeOfDayOff   = "off"
+	TimeOfDayHours = "hours"
+	TimeOfDaySun   = "sun"
+)
+
+// Daylight decides whether it is day, so light wallpapers can be shown
+// during the day and dark ones at night
+type Daylight interface {
+	IsDay(t time.Time) bool
+	String() string
+}
+
+// ParseDaylight builds the daylight rule from the time-of-day settings. It
+// returns nil when matching wallpapers to the time of day is off.
+func ParseDaylight(cfg config.TimeOfDayConfig) (Daylight, error) {
+	switch cfg.Mode {
+	case "", TimeOfDayOff:
+		return nil, nil
+
+	case TimeOfDayHours:
+		start, err := parseClock(cfg.DayStart)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(cfg.DayEnd)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("day_start and day_end must differ")
+		}
+		return hoursDaylight{start: start, end: end}, nil
+
+	case TimeOfDaySun:
+		if cfg.Latitude < -90 || cfg.Latitude > 90 || cfg.Longitude < -180 || cfg.Longitude > 180 {
+			return nil, fmt.Errorf("invalid location %g,%g (latitude -90..90, longitude -180..180)", cfg.Latitude, cfg.Longitude)
+		}
+		return sunDaylight{lat: cfg.Latitude, lon: cfg.Longitude}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid time_of_day mode: %s (valid: %s, %s, %s)", cfg.Mode, TimeOfDayOff, TimeOfDayHours, TimeOfDaySun)
+	}
+}
+
+// WantedBrightness returns the brightness class suiting time t, or "" if
+// d is nil
+func WantedBrightness(d Daylight, t time.Time) string {
+	switch {
+	case d == nil:
+		return ""
+	case d.IsDay(t):
+		return palette.Light
+	default:
+		return palette.Dark
+	}
+}
+
+// MatchTimeOfDay returns the images whose brightness suits time t: light
+// ones during the day and dark ones at night. If none match, or d is nil,
+// all images are returned.
+func MatchTimeOfDay(images []database.Image, d Daylight, t time.Time) []database.Image {
+	wanted := WantedBrightness(d, t)
+	if wanted == "" {
+		return images
+	}
+
+	var matching []database.Image
+	for _, img := range images {
+		if img.Brightness == wanted {
+			matching = append(matching, img)
+		}
+	}
+	if len(matching) == 0 {
+		return images
+	}
+	return matching
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(s string) (int, error) {
+	clock, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (use HH:MM)", s)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// hoursDaylight treats fixed hours of the local day as daytime
+type hoursDaylight struct {
+	start, end int // minutes since midnight
+}
+
+func (d hoursDaylight) IsDay(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if d.start < d.end {
+		return m >= d.start && m < d.end
+	}
+	// A day that wraps past midnight, e.g. 22:00-06:00 for night shifts
+	return m >= d.start || m < d.end
+}
+
+func (d hoursDaylight) String() string {
+	return fmt.Sprintf("day %02d:%02d-%02d:%02d", d.start/60, d.start%60, d.end/60, d.end%60)
+}
+
+// sunDaylight treats the time between sunrise and sunset at a location as
+// daytime. Sun times are calculated offline.
+type sunDaylight struct {
+	lat, lon float64
+}
+
+func (d sunDaylight) IsDay(t time.Time) bool {
+	utc := t.UTC()
+	// Depending on the longitude, the daylight containing t may belong to
+	// the previous or next UTC date
+	for _, offset := range []int{-1, 0, 1} {
+		rise, set, state := sunTimes(utc.AddDate(0, 0, offset), d.lat, d.lon)
+		switch state {
+		case sunAlwaysUp:
+			if offset == 0 {
+				return true
+			}
+		case sunAlwaysDown:
+			if offset == 0 {
+				return false
+			}
+		default:
+			if !utc.Before(rise) && utc.Before(set) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (d sunDaylight) String() string {
+	rise, set, state := sunTimes(time.Now().UTC(), d.lat, d.lon)
+	switch state {
+	case sunAlwaysUp:
+		return "sun (polar day)"
+	case sunAlwaysDown:
+		return "sun (polar night)"
+	}
+	return fmt.Sprintf("sun %s-%s", rise.Local().Format("15:04"), set.Local().Format("15:04"))
+}
+
+// Sun states for a date at a location
+const (
+	sunRisesAndSets = iota
+	sunAlwaysUp
+	sunAlwaysDown
+)
+
+// sunTimes returns sunrise and sunset in UTC for the UTC date of day at a
+// location, using the NOAA solar position equations. They are accurate to
+// within a few minutes, which is plenty for choosing wallpapers.
+func sunTimes(day time.Time, lat, lon float64) (time.Time, time.Time, int) {
+	const deg = math.Pi / 180
+
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Fractional year in radians, at noon
+	gamma := 2 * math.Pi / 365 * float64(midnight.YearDay()-1)
+
+	// Equation of time in minutes and solar declination in radians
+	eqTime := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+	decl := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+
+	// Hour angle of the sun's upper limb touching the horizon, including
+	// atmospheric refraction
+	cosHA := math.Cos(90.833*deg)/(math.Cos(lat*deg)*math.Cos(decl)) - math.Tan(lat*deg)*math.Tan(decl)
+	switch {
+	case cosHA > 1:
+		return time.Time{}, time.Time{}, sunAlwaysDown
+	case cosHA < -1:
+		return time.Time{}, time.Time{}, sunAlwaysUp
+	}
+	ha := math.Acos(cosHA) / deg
+
+	minutes := func(m float64) time.Time {
+		return midnight.Add(time.Duration(m * float64(time.Minute)))
+	}
+	sunrise := minutes(720 - 4*(lon+ha) - eqTime)
+	sunset := minutes(720 - 4*(lon-ha) - eqTime)
+	return sunrise, sunset, sunRisesAndSets
+}
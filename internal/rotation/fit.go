@@ -0,0 +1,61 @@
+package rotation
+
+import (
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+	"github.com/AccursedGalaxy/wallfetch/internal/downloader"
+)
+
+// fitLevel is one step of relaxing the requirements for a monitor
+type fitLevel func(img *database.Image) bool
+
+// fitLevels returns checks for a monitor from strictest to loosest: at
+// least the monitor's resolution with its aspect ratio, then the aspect
+// ratio alone, then the orientation, then anything.
+func fitLevels(m desktop.Monitor) []fitLevel {
+	anything := func(*database.Image) bool { return true }
+	if m.Width <= 0 || m.Height <= 0 {
+		return []fitLevel{anything}
+	}
+
+	ratio := []string{m.Resolution()}
+	filters := []*downloader.WallpaperFilter{
+		downloader.NewWallpaperFilter(&config.DefaultOptions{
+			MinWidth:     m.Width,
+			MinHeight:    m.Height,
+			AspectRatios: ratio,
+		}),
+		downloader.NewWallpaperFilter(&config.DefaultOptions{
+			AspectRatios: ratio,
+		}),
+	}
+
+	var levels []fitLevel
+	for _, f := range filters {
+		levels = append(levels, func(img *database.Image) bool {
+			return f.ValidateDimensions(img.Width, img.Height).Passed
+		})
+	}
+
+	levels = append(levels, func(img *database.Image) bool {
+		return img.Width > 0 && (img.Height > img.Width) == m.Portrait()
+	}, anything)
+
+	return levels
+}
+
+// PickForMonitor returns the candidate that best fits a monitor, keeping
+// the candidates' order among equally good fits. Candidates for which skip
+// returns true are ignored. It returns nil if every candidate is skipped.
+func PickForMonitor(candidates []database.Image, m desktop.Monitor, skip func(*database.Image) bool) *database.Image {
+	for _, fits := range fitLevels(m) {
+		for i := range candidates {
+			img := &candidates[i]
+			if (skip == nil || !skip(img)) && fits(img) {
+				return img
+			}
+		}
+	}
+	return nil
+}
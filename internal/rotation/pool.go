@@ -0,0 +1,82 @@
+package rotation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+)
+
+// Pool kinds
+const (
+	PoolAll        = "all"
+	PoolFavorites  = "favorites"
+	PoolTag        = "tag"
+	PoolCollection = "collection"
+)
+
+// Pool is the set of library images a rotation picks from
+type Pool struct {
+	Kind string
+	Tags []string // for tag pools
+	Dir  string   // for collection pools
+}
+
+// ParsePool parses a pool specification: all, favorites,
+// tag:<tag>[,<tag>...] or collection:<dir>
+func ParsePool(spec string) (Pool, error) {
+	spec = strings.TrimSpace(spec)
+	kind, arg, _ := strings.Cut(spec, ":")
+
+	switch kind {
+	case "", PoolAll:
+		return Pool{Kind: PoolAll}, nil
+	case PoolFavorites:
+		return Pool{Kind: PoolFavorites}, nil
+	case PoolTag:
+		var tags []string
+		for _, tag := range strings.Split(arg, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == 0 {
+			return Pool{}, fmt.Errorf("tag pool needs at least one tag (e.g. tag:nature)")
+		}
+		return Pool{Kind: PoolTag, Tags: tags}, nil
+	case PoolCollection:
+		if arg == "" {
+			return Pool{}, fmt.Errorf("collection pool needs a directory (e.g. collection:~/Pictures/Wallpapers/space)")
+		}
+		dir, err := filepath.Abs(config.ExpandPath(arg))
+		if err != nil {
+			return Pool{}, err
+		}
+		return Pool{Kind: PoolCollection, Dir: dir}, nil
+	default:
+		return Pool{}, fmt.Errorf("invalid pool %q (use all, favorites, tag:<tag> or collection:<dir>)", spec)
+	}
+}
+
+// Query returns the database query selecting the pool's images
+func (p Pool) Query() database.ImageQuery {
+	return database.ImageQuery{
+		FavoritesOnly: p.Kind == PoolFavorites,
+		Tags:          p.Tags,
+		PathPrefix:    p.Dir,
+		SortBy:        "random",
+	}
+}
+
+func (p Pool) String() string {
+	switch p.Kind {
+	case PoolTag:
+		return "tag:" + strings.Join(p.Tags, ",")
+	case PoolCollection:
+		return "collection:" + p.Dir
+	default:
+		return p.Kind
+	}
+}
@@ -0,0 +1,299 @@
+package rotation
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/analysis"
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+	"github.com/AccursedGalaxy/wallfetch/internal/span"
+)
+
+// maxShown bounds the in-memory history used by Prev
+const maxShown = 100
+
+// Options configures a Rotator
+type Options struct {
+	Pool        Pool
+	Schedule    Schedule
+	AvoidRecent int
+	Backend     desktop.Backend
+
+	// Monitors are the connected outputs. With PerMonitor set and a backend
+	// that supports it, each monitor gets its own fitting wallpaper;
+	// otherwise one wallpaper fitting the first monitor is shown everywhere.
+	Monitors   []desktop.Monitor
+	PerMonitor bool
+
+	// Span, when set, splits one wallpaper fitting the whole layout
+	// across the monitors instead
+	Span *span.Splitter
+
+	// Daylight, when set, prefers light wallpapers during the day and dark
+	// ones at night
+	Daylight Daylight
+
+	// OnApply, when set, is called with the main image of every frame
+	// that was applied, e.g. to export a color theme
+	OnApply func(img database.Image)
+}
+
+// perMonitor reports whether the options call for one wallpaper per monitor
+func (o Options) perMonitor() bool {
+	return o.PerMonitor && len(o.Monitors) > 1 && desktop.SupportsPerMonitor(o.Backend)
+}
+
+// spanning reports whether the options call for one wallpaper spanning
+// every monitor
+func (o Options) spanning() bool {
+	return o.Span != nil && len(o.Monitors) > 1 && desktop.SupportsPerMonitor(o.Backend)
+}
+
+// frame is one wallpaper change: an image per monitor in per-monitor mode,
+// otherwise a single image for every monitor
+type frame []database.Image
+
+// Rotator picks wallpapers from a pool and applies them, avoiding recently
+// shown ones and remembering what it showed so it can step back.
+type Rotator struct {
+	db   *database.DB
+	opts Options
+
+	mu    sync.Mutex
+	shown []frame
+	pos   int
+}
+
+// NewRotator creates a rotator
+func NewRotator(db *database.DB, opts Options) *Rotator {
+	return &Rotator{db: db, opts: opts, pos: -1}
+}
+
+// Options returns the current options
+func (r *Rotator) Options() Options {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.opts
+}
+
+// SetOptions replaces the options, e.g. after a config reload
+func (r *Rotator) SetOptions(opts Options) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opts = opts
+}
+
+// Current returns the wallpapers currently shown by this rotator, one per
+// monitor in per-monitor mode
+func (r *Rotator) Current() []database.Image {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pos < 0 {
+		return nil
+	}
+	return append([]database.Image(nil), r.shown[r.pos]...)
+}
+
+// Next moves forward in the history if the user stepped back, otherwise
+// picks and applies new wallpapers from the pool
+func (r *Rotator) Next() ([]database.Image, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pos < len(r.shown)-1 {
+		f := r.shown[r.pos+1]
+		if err := r.apply(f); err != nil {
+			return nil, err
+		}
+		r.pos++
+		return f, nil
+	}
+
+	f, err := r.pick()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.apply(f); err != nil {
+		return nil, err
+	}
+
+	r.shown = append(r.shown, f)
+	if len(r.shown) > maxShown {
+		r.shown = r.shown[len(r.shown)-maxShown:]
+	}
+	r.pos = len(r.shown) - 1
+
+	return f, nil
+}
+
+// Prev re-applies the previously shown wallpapers
+func (r *Rotator) Prev() ([]database.Image, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pos <= 0 {
+		return nil, fmt.Errorf("no previous wallpaper")
+	}
+
+	f := r.shown[r.pos-1]
+	if err := r.apply(f); err != nil {
+		return nil, err
+	}
+	r.pos--
+
+	return f, nil
+}
+
+// pick chooses a frame of pool images. Each image fits its monitor as well
+// as the pool allows and, where possible, has not been shown recently.
+func (r *Rotator) pick() (frame, error) {
+	images, err := r.db.QueryImages(r.opts.Pool.Query())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pool: %w", err)
+	}
+
+	// Drop images whose files are gone
+	available := images[:0]
+	for _, img := range images {
+		if _, err := os.Stat(img.LocalPath); err == nil {
+			available = append(available, img)
+		}
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("no wallpapers available in pool %s", r.opts.Pool)
+	}
+
+	if r.opts.Daylight != nil {
+		// Images added before brightness was stored are classified once
+		if _, err := analysis.Backfill(r.db, available); err != nil {
+			return nil, fmt.Errorf("failed to store wallpaper brightness: %w", err)
+		}
+		available = MatchTimeOfDay(available, r.opts.Daylight, time.Now())
+	}
+
+	recent := make(map[int]bool)
+	if r.opts.AvoidRecent > 0 {
+		ids, err := r.db.RecentWallpaperImageIDs(r.opts.AvoidRecent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read wallpaper history: %w", err)
+		}
+		for _, id := range ids {
+			recent[id] = true
+		}
+	}
+
+	current := make(map[int]bool)
+	if r.pos >= 0 {
+		for _, img := range r.shown[r.pos] {
+			current[img.ID] = true
+		}
+	}
+
+	monitors := r.opts.Monitors
+	switch {
+	case r.opts.spanning():
+		monitors = []desktop.Monitor{span.Canvas(monitors, r.opts.Span.Bezel)}
+	case !r.opts.perMonitor():
+		// One image for every monitor, fitted to the first one
+		monitors = monitors[:min(len(monitors), 1)]
+		if len(monitors) == 0 {
+			monitors = []desktop.Monitor{{}}
+		}
+	}
+
+	var f frame
+	chosen := make(map[int]bool)
+	for _, m := range monitors {
+		// Relax variety before giving up: avoid recent images, then only
+		// the ones on screen now, then only the ones already in this frame
+		skips := []func(*database.Image) bool{
+			func(img *database.Image) bool { return chosen[img.ID] || recent[img.ID] || current[img.ID] },
+			func(img *database.Image) bool { return chosen[img.ID] || current[img.ID] },
+			func(img *database.Image) bool { return chosen[img.ID] },
+			nil,
+		}
+
+		var img *database.Image
+		for _, skip := range skips {
+			if img = PickForMonitor(available, m, skip); img != nil {
+				break
+			}
+		}
+
+		chosen[img.ID] = true
+		f = append(f, *img)
+	}
+
+	return f, nil
+}
+
+// apply sets the wallpapers of a frame and records them
+func (r *Rotator) apply(f frame) error {
+	if err := r.applyFrame(f); err != nil {
+		return err
+	}
+	if r.opts.OnApply != nil {
+		r.opts.OnApply(f[0])
+	}
+	return nil
+}
+
+// applyFrame sets the wallpapers of a frame with the backend and records them
+func (r *Rotator) applyFrame(f frame) error {
+	backend := r.opts.Backend
+
+	if r.opts.spanning() {
+		return r.applySpan(f[0])
+	}
+
+	if !r.opts.perMonitor() || len(f) == 1 {
+		if err := backend.Set(f[0].LocalPath); err != nil {
+			return fmt.Errorf("failed to set wallpaper with %s: %w", backend.Name(), err)
+		}
+		if err := r.db.RecordWallpaperSet(f[0].ID, f[0].LocalPath, backend.Name(), ""); err != nil {
+			return fmt.Errorf("failed to record wallpaper: %w", err)
+		}
+		return nil
+	}
+
+	// Frames from before a monitor change are spread over the new layout
+	var assignments []desktop.Assignment
+	for i, m := range r.opts.Monitors {
+		assignments = append(assignments, desktop.Assignment{Monitor: m, Path: f[i%len(f)].LocalPath})
+	}
+
+	if err := desktop.Apply(backend, assignments); err != nil {
+		return fmt.Errorf("failed to set wallpapers with %s: %w", backend.Name(), err)
+	}
+
+	for i, a := range assignments {
+		img := f[i%len(f)]
+		if err := r.db.RecordWallpaperSet(img.ID, a.Path, backend.Name(), a.Monitor.Name); err != nil {
+			return fmt.Errorf("failed to record wallpaper: %w", err)
+		}
+	}
+	return nil
+}
+
+// applySpan splits an image across the monitors and records the slices
+func (r *Rotator) applySpan(img database.Image) error {
+	backend := r.opts.Backend
+
+	assignments, err := r.opts.Span.Split(img.LocalPath, r.opts.Monitors)
+	if err != nil {
+		return fmt.Errorf("failed to split wallpaper: %w", err)
+	}
+	if err := desktop.Apply(backend, assignments); err != nil {
+		return fmt.Errorf("failed to set wallpapers with %s: %w", backend.Name(), err)
+	}
+
+	for _, a := range assignments {
+		if err := r.db.RecordWallpaperSet(img.ID, a.Path, backend.Name(), a.Monitor.Name); err != nil {
+			return fmt.Errorf("failed to record wallpaper: %w", err)
+		}
+	}
+	return nil
+}
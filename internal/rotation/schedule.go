@@ -0,0 +1,250 @@
+package rotation
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinInterval is the shortest allowed rotation interval
+const MinInterval = 10 * time.Second
+
+// Schedule decides when the next wallpaper change happens
+type Schedule interface {
+	// Next returns the first rotation time strictly after t
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// ParseSchedule parses a rotation schedule. It accepts an interval
+// ("30m", "2h"), a five-field cron expression ("*/30 * * * *") or a
+// comma-separated list of times of day ("08:00,13:00,20:00").
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	if len(strings.Fields(spec)) == 5 {
+		return parseCron(spec)
+	}
+
+	if strings.Contains(spec, ":") {
+		return parseDaily(spec)
+	}
+
+	d, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: use an interval (30m), a cron expression (*/30 * * * *) or times of day (08:00,20:00)", spec)
+	}
+	if d < MinInterval {
+		return nil, fmt.Errorf("interval %s is too short (minimum %s)", d, MinInterval)
+	}
+	return intervalSchedule{interval: d}, nil
+}
+
+// intervalSchedule rotates at a fixed interval
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+func (s intervalSchedule) String() string {
+	// Print 30m rather than 30m0s
+	d := s.interval.String()
+	if strings.HasSuffix(d, "m0s") {
+		d = strings.TrimSuffix(d, "0s")
+	}
+	if strings.HasSuffix(d, "h0m") {
+		d = strings.TrimSuffix(d, "0m")
+	}
+	return "every " + d
+}
+
+// dailySchedule rotates at fixed times of day, in local time
+type dailySchedule struct {
+	minutes []int // minutes since midnight, sorted
+}
+
+func parseDaily(spec string) (Schedule, error) {
+	var minutes []int
+	for _, part := range strings.Split(spec, ",") {
+		clock, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time of day %q (use HH:MM)", strings.TrimSpace(part))
+		}
+		minutes = append(minutes, clock.Hour()*60+clock.Minute())
+	}
+	sort.Ints(minutes)
+	return dailySchedule{minutes: minutes}, nil
+}
+
+func (s dailySchedule) Next(t time.Time) time.Time {
+	t = t.Local()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	for day := 0; day <= 1; day++ {
+		base := midnight.AddDate(0, 0, day)
+		for _, m := range s.minutes {
+			at := base.Add(time.Duration(m) * time.Minute)
+			if at.After(t) {
+				return at
+			}
+		}
+	}
+
+	// Unreachable: tomorrow always has a later time
+	return midnight.AddDate(0, 0, 1).Add(time.Duration(s.minutes[0]) * time.Minute)
+}
+
+func (s dailySchedule) String() string {
+	var times []string
+	for _, m := range s.minutes {
+		times = append(times, fmt.Sprintf("%02d:%02d", m/60, m%60))
+	}
+	return "daily at " + strings.Join(times, ", ")
+}
+
+// cronSchedule rotates according to a standard five-field cron expression:
+// minute hour day-of-month month day-of-week
+type cronSchedule struct {
+	spec                          string
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func parseCron(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	s := cronSchedule{spec: spec}
+
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid cron minute field: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid cron hour field: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid cron day-of-month field: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid cron month field: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid cron day-of-week field: %w", err)
+	}
+
+	// Both 0 and 7 mean Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+
+	return s, nil
+}
+
+// parseCronField parses a cron field into a bit set. It supports *, single
+// values, ranges (a-b), steps (*/n, a-b/n) and comma-separated lists.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (s cronSchedule) Next(t time.Time) time.Time {
+	t = t.Local().Truncate(time.Minute).Add(time.Minute)
+
+	// Give up after five years; only impossible dates like 30 Feb get here
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return limit
+}
+
+// dayMatches applies cron's rule that a restricted day-of-month and
+// day-of-week match when either does
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dowMatch
+	case s.dowStar:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+func (s cronSchedule) String() string {
+	return "cron " + s.spec
+}
@@ -0,0 +1,99 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"30m", "every 30m"},
+		{" 2h ", "every 2h"},
+		{"1h30m", "every 1h30m"},
+		{"10s", "every 10s"},
+		{"20:00,08:00", "daily at 08:00, 20:00"},
+		{"*/30 * * * *", "cron */30 * * * *"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if s.String() != tt.want {
+			t.Errorf("ParseSchedule(%q) = %s, want %s", tt.spec, s, tt.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"often",
+		"5s",
+		"25:00",
+		"08:00,8pm",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if s, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) = %s, want an error", spec, s)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"30m", "2026-10-18 23:50:00", "2026-10-19 00:20:00"},
+
+		// Times of day, strictly after and across midnight and the year
+		{"08:00,20:00", "2026-10-18 07:59:59", "2026-10-18 08:00:00"},
+		{"08:00,20:00", "2026-10-18 08:00:00", "2026-10-18 20:00:00"},
+		{"08:00,20:00", "2026-10-18 21:00:00", "2026-10-19 08:00:00"},
+		{"08:00", "2026-12-31 23:59:00", "2027-01-01 08:00:00"},
+		{"00:00", "2026-10-18 00:00:00", "2026-10-19 00:00:00"},
+
+		// Cron
+		{"*/15 * * * *", "2026-10-18 10:07:30", "2026-10-18 10:15:00"},
+		{"*/15 * * * *", "2026-10-18 10:45:00", "2026-10-18 11:00:00"},
+		{"0 9 * * 1-5", "2026-10-17 10:00:00", "2026-10-19 09:00:00"},
+		{"30 14 * * 7", "2026-10-17 10:00:00", "2026-10-18 14:30:00"},
+		{"0 0 1 1 *", "2026-10-18 10:00:00", "2027-01-01 00:00:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00:00", "2028-02-29 00:00:00"},
+		{"0 23 * * *", "2026-10-31 23:30:00", "2026-11-01 23:00:00"},
+		{"5,10 8-9/1 * * *", "2026-10-18 08:10:00", "2026-10-18 09:05:00"},
+		// A restricted day of month and day of week match when either does
+		{"0 12 20 * 0", "2026-10-18 13:00:00", "2026-10-20 12:00:00"},
+		{"0 12 1 * 0", "2026-10-18 11:00:00", "2026-10-18 12:00:00"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+		}
+		if got := s.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%s: Next(%s) = %s, want %s", tt.spec, tt.from, got.Format("2006-01-02 15:04:05"), tt.want)
+		}
+	}
+}
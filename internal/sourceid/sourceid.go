@@ -0,0 +1,108 @@
+// Package sourceid recognizes the source and source ID of a wallpaper from
+// the file name other tools and sites save it under, so imported files
+// keep their origin.
+package sourceid
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+)
+
+// pattern recognizes the file names of one source
+type pattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+// builtin are the file names of known sites and tools. Patterns are matched
+// against the file name without its extension; the first group is the ID.
+var builtin = []config.FilenamePattern{
+	// wallhaven-1q3g79, as saved by wallhaven.cc and by fetch
+	{Source: "wallhaven", Pattern: `^wallhaven-([0-9a-z]+)$`},
+	// photo-1506744038136-46273834b3fb, the name on images.unsplash.com
+	{Source: "unsplash", Pattern: `^photo-(\d{13}-[0-9a-f]{12})$`},
+	// jane-doe-Hn5Q0Jk2m9E-unsplash, the name of Unsplash downloads
+	{Source: "unsplash", Pattern: `^[0-9a-z-]+-([0-9A-Za-z_-]{11})-unsplash$`},
+	// reddit-abc123 and reddit_abc123, the post ID saved by downloaders
+	{Source: "reddit", Pattern: `^reddit[-_]([0-9a-z]{5,8})$`},
+	// __tags__<md5>, as Danbooru downloads are named
+	{Source: "booru", Pattern: `^.+__([0-9a-f]{32})$`},
+}
+
+// booruMD5 is a bare MD5, as boorus name their images. Any hex name of the
+// right length matches, so it is only recognized when it is the MD5 of the
+// file itself.
+var booruMD5 = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Matcher recognizes file names. It is safe for concurrent use.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New returns a matcher for the custom patterns followed by the built-in
+// ones, so custom patterns take precedence
+func New(custom []config.FilenamePattern) (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range append(append([]config.FilenamePattern{}, custom...), builtin...) {
+		if p.Source == "" {
+			return nil, fmt.Errorf("filename pattern %s has no source", p.Pattern)
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filename pattern %s: %w", p.Pattern, err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("filename pattern %s has no group capturing the ID", p.Pattern)
+		}
+		m.patterns = append(m.patterns, pattern{source: p.Source, re: re})
+	}
+	return m, nil
+}
+
+// Match returns the source and ID recognized in the name of the file at
+// path, or false if no pattern matches
+func (m *Matcher) Match(path string) (source, id string, ok bool) {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, p := range m.patterns {
+		groups := p.re.FindStringSubmatch(name)
+		if groups == nil {
+			continue
+		}
+		id = groups[1]
+		if i := p.re.SubexpIndex("id"); i > 0 {
+			id = groups[i]
+		}
+		if id != "" {
+			return p.source, id, true
+		}
+	}
+	if booruMD5.MatchString(name) && fileMD5(path) == name {
+		return "booru", name, true
+	}
+	return "", "", false
+}
+
+// fileMD5 returns the hex MD5 of a file's contents, or "" if it cannot be
+// read
+func fileMD5(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package sourceid
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/config"
+)
+
+func TestMatch(t *testing.T) {
+	m, err := New([]config.FilenamePattern{
+		{Source: "artstation", Pattern: `^artstation_(?P<id>\d+)_(\d+)$`},
+		{Source: "mywallhaven", Pattern: `^wallhaven-(special)$`},
+		{Source: "optional", Pattern: `^opt-(\d*)$`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		source string
+		id     string
+	}{
+		{"/pics/wallhaven-1q3g79.jpg", "wallhaven", "1q3g79"},
+		{"wallhaven-1q3g79.png", "wallhaven", "1q3g79"},
+		{"/pics/photo-1506744038136-46273834b3fb.jpg", "unsplash", "1506744038136-46273834b3fb"},
+		{"/pics/jane-doe-Hn5Q0Jk2m9E-unsplash.jpg", "unsplash", "Hn5Q0Jk2m9E"},
+		{"/pics/reddit-abc123.jpg", "reddit", "abc123"},
+		{"/pics/reddit_abc12.png", "reddit", "abc12"},
+		{"/pics/__hatsune_miku__0123456789abcdef0123456789abcdef.png", "booru", "0123456789abcdef0123456789abcdef"},
+
+		// Custom patterns, with a named group and taking precedence
+		{"/pics/artstation_123_456.jpg", "artstation", "123"},
+		{"/pics/wallhaven-special.jpg", "mywallhaven", "special"},
+
+		// Not recognized
+		{"/pics/IMG_1234.jpg", "", ""},
+		{"/pics/wallhaven.jpg", "", ""},
+		{"/pics/reddit-toolongid1.jpg", "", ""},
+		{"/pics/photo-123.jpg", "", ""},
+		{"/pics/wallhaven-1q3g79-edited.jpg", "", ""},
+		// An empty ID falls through to the next pattern
+		{"/pics/opt-.jpg", "", ""},
+	}
+	for _, tt := range tests {
+		source, id, ok := m.Match(tt.path)
+		if source != tt.source || id != tt.id || ok != (tt.source != "") {
+			t.Errorf("Match(%s) = %q, %q, %v, want %q, %q", tt.path, source, id, ok, tt.source, tt.id)
+		}
+	}
+}
+
+func TestMatchMD5(t *testing.T) {
+	m, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	data := []byte("a wallpaper")
+	sum := md5.Sum(data)
+	name := hex.EncodeToString(sum[:])
+
+	// Named with the MD5 of its own contents
+	path := filepath.Join(dir, name+".jpg")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if source, id, ok := m.Match(path); !ok || source != "booru" || id != name {
+		t.Errorf("Match of a file named with its MD5 = %q, %q, %v", source, id, ok)
+	}
+
+	// Any other hex name of the same length
+	other := filepath.Join(dir, "ffffffffffffffffffffffffffffffff.jpg")
+	if err := os.WriteFile(other, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if source, id, ok := m.Match(other); ok {
+		t.Errorf("Match of a hex name that is not the MD5 = %q, %q", source, id)
+	}
+
+	// The file cannot be read
+	if source, id, ok := m.Match(filepath.Join(dir, "00000000000000000000000000000000.jpg")); ok {
+		t.Errorf("Match of a missing file = %q, %q", source, id)
+	}
+}
+
+func TestNewRejectsPatterns(t *testing.T) {
+	for _, p := range []config.FilenamePattern{
+		{Source: "", Pattern: `^x-(\d+)$`},
+		{Source: "x", Pattern: `^x-(\d+$`},
+		{Source: "x", Pattern: `^x-\d+$`},
+	} {
+		if _, err := New([]config.FilenamePattern{p}); err == nil {
+			t.Errorf("New accepted %+v", p)
+		}
+	}
+}
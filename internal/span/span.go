@@ -0,0 +1,243 @@
+// Package span splits one large image across several monitors, so a wide
+// wallpaper continues from one screen to the next.
+package span
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+)
+
+// maxCacheEntries is how many split wallpapers are kept in the cache
+const maxCacheEntries = 20
+
+// unsafeName matches characters not allowed in slice file names
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Splitter cuts images into per-monitor slices and caches them
+type Splitter struct {
+	// CacheDir holds the slices, one directory per image and layout
+	CacheDir string
+	// Bezel is the gap in pixels hidden behind the frames of two adjacent
+	// monitors. That part of the image is skipped so lines stay straight.
+	Bezel int
+}
+
+// DefaultCacheDir returns the directory used for slices by default
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "wallfetch", "span")
+}
+
+// Layout returns the area of the canvas each monitor shows, in monitor
+// order and relative to the top-left corner of the canvas, with bezel
+// gaps added between neighbouring monitors
+func Layout(monitors []desktop.Monitor, bezel int) []image.Rectangle {
+	rects := make([]image.Rectangle, len(monitors))
+	for i, m := range monitors {
+		// Every monitor fully to the left or above adds a gap
+		var dx, dy int
+		for _, o := range monitors {
+			if o.X+o.Width <= m.X {
+				dx += bezel
+			}
+			if o.Y+o.Height <= m.Y {
+				dy += bezel
+			}
+		}
+		rects[i] = image.Rect(m.X+dx, m.Y+dy, m.X+dx+m.Width, m.Y+dy+m.Height)
+	}
+
+	if len(rects) == 0 {
+		return rects
+	}
+	origin := rects[0].Min
+	for _, r := range rects[1:] {
+		origin.X = min(origin.X, r.Min.X)
+		origin.Y = min(origin.Y, r.Min.Y)
+	}
+	for i := range rects {
+		rects[i] = rects[i].Sub(origin)
+	}
+	return rects
+}
+
+// Canvas returns the whole area covered by the monitors, bezels included,
+// as one virtual monitor. It is used to pick images that fit the layout.
+func Canvas(monitors []desktop.Monitor, bezel int) desktop.Monitor {
+	var bounds image.Rectangle
+	for _, r := range Layout(monitors, bezel) {
+		bounds = bounds.Union(r)
+	}
+	return desktop.Monitor{Name: "span", Width: bounds.Dx(), Height: bounds.Dy()}
+}
+
+// Split returns one slice of the image at path for each monitor. The
+// image is scaled to cover the whole canvas, centred, and cut along the
+// monitor layout. Slices are reused from the cache when the image and
+// layout have not changed.
+func (s *Splitter) Split(path string, monitors []desktop.Monitor) ([]desktop.Assignment, error) {
+	if len(monitors) == 0 {
+		return nil, fmt.Errorf("no monitors to span")
+	}
+	for _, m := range monitors {
+		if m.Width <= 0 || m.Height <= 0 {
+			return nil, fmt.Errorf("unknown resolution for monitor %s", m.Name)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("wallpaper file not found: %s", path)
+	}
+
+	rects := Layout(monitors, s.Bezel)
+	dir := filepath.Join(s.CacheDir, s.cacheKey(path, info, monitors, rects))
+
+	ext := ".jpg"
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		ext = ".png"
+	}
+
+	assignments := make([]desktop.Assignment, len(monitors))
+	cached := true
+	for i, m := range monitors {
+		slice := filepath.Join(dir, unsafeName.ReplaceAllString(m.Name, "_")+ext)
+		assignments[i] = desktop.Assignment{Monitor: m, Path: slice}
+		if _, err := os.Stat(slice); err != nil {
+			cached = false
+		}
+	}
+
+	if cached {
+		// Mark the entry as recently used so it survives eviction
+		now := time.Now()
+		_ = os.Chtimes(dir, now, now)
+		return assignments, nil
+	}
+
+	img, _, err := imaging.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.CacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create span cache: %w", err)
+	}
+
+	// The slices are written to a temporary directory that is moved into
+	// place once all of them are complete, so an interrupted run never
+	// leaves slices that look cached
+	tmp, err := os.MkdirTemp(s.CacheDir, ".partial-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span cache: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	sources := sourceRects(img.Bounds(), rects)
+	for i, a := range assignments {
+		slice := imaging.Scale(img, sources[i], a.Monitor.Width, a.Monitor.Height)
+		if err := imaging.Save(filepath.Join(tmp, filepath.Base(a.Path)), slice); err != nil {
+			return nil, fmt.Errorf("failed to write slice for %s: %w", a.Monitor.Name, err)
+		}
+	}
+
+	// Replace an incomplete entry left by an older version
+	os.RemoveAll(dir)
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, fmt.Errorf("failed to store slices: %w", err)
+	}
+	_ = os.Chmod(dir, 0755)
+
+	s.evict(dir)
+	return assignments, nil
+}
+
+// sourceRects maps canvas rectangles to the image, which is scaled to
+// cover the canvas and centred on it
+func sourceRects(bounds image.Rectangle, rects []image.Rectangle) []image.Rectangle {
+	var canvas image.Rectangle
+	for _, r := range rects {
+		canvas = canvas.Union(r)
+	}
+
+	// Canvas pixels per image pixel, large enough to leave no borders
+	scale := math.Max(
+		float64(canvas.Dx())/float64(bounds.Dx()),
+		float64(canvas.Dy())/float64(bounds.Dy()),
+	)
+	offsetX := (float64(bounds.Dx())*scale - float64(canvas.Dx())) / 2
+	offsetY := (float64(bounds.Dy())*scale - float64(canvas.Dy())) / 2
+
+	toImage := func(x, y int) image.Point {
+		return image.Pt(
+			bounds.Min.X+int(math.Round((float64(x)+offsetX)/scale)),
+			bounds.Min.Y+int(math.Round((float64(y)+offsetY)/scale)),
+		)
+	}
+
+	sources := make([]image.Rectangle, len(rects))
+	for i, r := range rects {
+		sources[i] = image.Rectangle{Min: toImage(r.Min.X, r.Min.Y), Max: toImage(r.Max.X, r.Max.Y)}.Intersect(bounds)
+	}
+	return sources
+}
+
+// cacheKey identifies an image file version together with a layout
+func (s *Splitter) cacheKey(path string, info os.FileInfo, monitors []desktop.Monitor, rects []image.Rectangle) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%d|%d", path, info.Size(), info.ModTime().UnixNano(), s.Bezel)
+	for i, m := range monitors {
+		fmt.Fprintf(h, "|%s:%dx%d@%v", m.Name, m.Width, m.Height, rects[i])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// evict removes the least recently used cache entries beyond
+// maxCacheEntries, never the one just written
+func (s *Splitter) evict(keep string) {
+	entries, err := os.ReadDir(s.CacheDir)
+	if err != nil {
+		return
+	}
+
+	type entry struct {
+		path    string
+		modTime time.Time
+	}
+	var dirs []entry
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, entry{filepath.Join(s.CacheDir, e.Name()), info.ModTime()})
+	}
+
+	if len(dirs) <= maxCacheEntries {
+		return
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].modTime.After(dirs[j].modTime) })
+	for _, d := range dirs[maxCacheEntries:] {
+		if d.path != keep {
+			os.RemoveAll(d.path)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package span
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/desktop"
+)
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		monitors []desktop.Monitor
+		bezel    int
+		want     []image.Rectangle
+	}{
+		{
+			"side by side",
+			[]desktop.Monitor{
+				{Name: "DP-1", X: 0, Y: 0, Width: 1920, Height: 1080},
+				{Name: "DP-2", X: 1920, Y: 0, Width: 1920, Height: 1080},
+			},
+			40,
+			[]image.Rectangle{image.Rect(0, 0, 1920, 1080), image.Rect(1960, 0, 3880, 1080)},
+		},
+		{
+			"no bezel",
+			[]desktop.Monitor{
+				{Name: "DP-1", X: 0, Y: 0, Width: 1920, Height: 1080},
+				{Name: "DP-2", X: 1920, Y: 0, Width: 1920, Height: 1080},
+			},
+			0,
+			[]image.Rectangle{image.Rect(0, 0, 1920, 1080), image.Rect(1920, 0, 3840, 1080)},
+		},
+		{
+			"stacked",
+			[]desktop.Monitor{
+				{Name: "top", X: 0, Y: 0, Width: 2560, Height: 1440},
+				{Name: "bottom", X: 320, Y: 1440, Width: 1920, Height: 1080},
+			},
+			30,
+			[]image.Rectangle{image.Rect(0, 0, 2560, 1440), image.Rect(320, 1470, 2240, 2550)},
+		},
+		{
+			"three in a row out of order",
+			[]desktop.Monitor{
+				{Name: "right", X: 3840, Y: 0, Width: 1920, Height: 1080},
+				{Name: "left", X: 0, Y: 0, Width: 1920, Height: 1080},
+				{Name: "middle", X: 1920, Y: 0, Width: 1920, Height: 1080},
+			},
+			10,
+			[]image.Rectangle{image.Rect(3860, 0, 5780, 1080), image.Rect(0, 0, 1920, 1080), image.Rect(1930, 0, 3850, 1080)},
+		},
+		{
+			// A portrait monitor left of the primary one and lower down
+			"negative offsets",
+			[]desktop.Monitor{
+				{Name: "primary", X: 0, Y: 0, Width: 1920, Height: 1080},
+				{Name: "portrait", X: -1080, Y: -420, Width: 1080, Height: 1920},
+			},
+			20,
+			[]image.Rectangle{image.Rect(1100, 420, 3020, 1500), image.Rect(0, 0, 1080, 1920)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Layout(tt.monitors, tt.bezel); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Layout = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanvas(t *testing.T) {
+	c := Canvas([]desktop.Monitor{
+		{Name: "DP-1", X: 0, Y: 0, Width: 1920, Height: 1080},
+		{Name: "DP-2", X: 1920, Y: 0, Width: 1920, Height: 1080},
+	}, 40)
+	if c.Width != 3880 || c.Height != 1080 {
+		t.Errorf("canvas is %dx%d, want 3880x1080", c.Width, c.Height)
+	}
+}
+
+func TestSourceRects(t *testing.T) {
+	// Two monitors with a 40 pixel bezel make a 3880x1080 canvas
+	rects := []image.Rectangle{image.Rect(0, 0, 1920, 1080), image.Rect(1960, 0, 3880, 1080)}
+
+	tests := []struct {
+		name   string
+		bounds image.Rectangle
+		want   []image.Rectangle
+	}{
+		{
+			"same size as the canvas",
+			image.Rect(0, 0, 3880, 1080),
+			rects,
+		},
+		{
+			"twice the canvas",
+			image.Rect(0, 0, 7760, 2160),
+			[]image.Rectangle{image.Rect(0, 0, 3840, 2160), image.Rect(3920, 0, 7760, 2160)},
+		},
+		{
+			// Scaled to the canvas width and cropped top and bottom
+			"taller than the canvas",
+			image.Rect(0, 0, 3880, 2000),
+			[]image.Rectangle{image.Rect(0, 460, 1920, 1540), image.Rect(1960, 460, 3880, 1540)},
+		},
+		{
+			// Scaled to the canvas height and cropped left and right
+			"wider than the canvas",
+			image.Rect(0, 0, 4880, 1080),
+			[]image.Rectangle{image.Rect(500, 0, 2420, 1080), image.Rect(2460, 0, 4380, 1080)},
+		},
+		{
+			"offset bounds",
+			image.Rect(100, 50, 3980, 1130),
+			[]image.Rectangle{image.Rect(100, 50, 2020, 1130), image.Rect(2060, 50, 3980, 1130)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sourceRects(tt.bounds, rects); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sourceRects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wide.png")
+	img := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 100; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s := &Splitter{CacheDir: filepath.Join(dir, "cache"), Bezel: 10}
+	monitors := []desktop.Monitor{
+		{Name: "DP-1", X: 0, Y: 0, Width: 195, Height: 100},
+		{Name: "DP-2", X: 195, Y: 0, Width: 195, Height: 100},
+	}
+	assignments, err := s.Split(path, monitors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range assignments {
+		info, err := os.Stat(a.Path)
+		if err != nil || info.Size() == 0 {
+			t.Errorf("slice for %s not written: %v", a.Monitor.Name, err)
+		}
+	}
+
+	// Only the finished entry is left in the cache
+	entries, err := os.ReadDir(s.CacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || strings.HasPrefix(entries[0].Name(), ".") {
+		t.Errorf("cache holds %v, want one entry", entries)
+	}
+
+	again, err := s.Split(path, monitors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, assignments) {
+		t.Errorf("second split = %v, want the cached %v", again, assignments)
+	}
+}
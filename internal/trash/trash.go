@@ -0,0 +1,172 @@
+package trash
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Trash is a trash can following the freedesktop.org Trash specification
+// layout: trashed files live in files/ and their metadata in info/.
+type Trash struct {
+	root string
+}
+
+// New creates a trash can rooted at the given directory
+func New(root string) *Trash {
+	return &Trash{root: root}
+}
+
+// System returns the user's freedesktop.org home trash
+func System() *Trash {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, _ := os.UserHomeDir()
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	return New(filepath.Join(dataHome, "Trash"))
+}
+
+// Root returns the trash root directory
+func (t *Trash) Root() string {
+	return t.root
+}
+
+// Move moves a file into the trash and returns its new location
+func (t *Trash) Move(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(absPath); err != nil {
+		return "", err
+	}
+
+	filesDir := filepath.Join(t.root, "files")
+	infoDir := filepath.Join(t.root, "info")
+	if err := os.MkdirAll(filesDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := os.MkdirAll(infoDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	// Reserve a unique name by creating its .trashinfo file exclusively
+	name, infoFile, err := t.reserveName(filepath.Base(absPath))
+	if err != nil {
+		return "", err
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		escapePath(absPath), time.Now().Format("2006-01-02T15:04:05"))
+	_, err = infoFile.WriteString(info)
+	infoFile.Close()
+	if err != nil {
+		os.Remove(t.infoPath(name))
+		return "", fmt.Errorf("failed to write trash info: %w", err)
+	}
+
+	trashedPath := filepath.Join(filesDir, name)
+	if err := moveFile(absPath, trashedPath); err != nil {
+		os.Remove(t.infoPath(name))
+		return "", err
+	}
+
+	return trashedPath, nil
+}
+
+// Restore moves a trashed file back to its original location. It refuses
+// to overwrite an existing file.
+func (t *Trash) Restore(trashedPath, originalPath string) error {
+	if _, err := os.Stat(originalPath); err == nil {
+		return fmt.Errorf("a file already exists at %s", originalPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(originalPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := moveFile(trashedPath, originalPath); err != nil {
+		return err
+	}
+
+	os.Remove(t.infoPath(filepath.Base(trashedPath)))
+	return nil
+}
+
+// Remove permanently deletes a trashed file and its metadata
+func (t *Trash) Remove(trashedPath string) error {
+	if err := os.Remove(trashedPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	os.Remove(t.infoPath(filepath.Base(trashedPath)))
+	return nil
+}
+
+// reserveName finds an unused name in the trash and creates its info file
+func (t *Trash) reserveName(base string) (string, *os.File, error) {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	name := base
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(t.infoPath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			if _, err := os.Lstat(filepath.Join(t.root, "files", name)); os.IsNotExist(err) {
+				return name, f, nil
+			}
+			// Stale file without info; leave both alone and keep looking
+			f.Close()
+			os.Remove(t.infoPath(name))
+		} else if !os.IsExist(err) {
+			return "", nil, fmt.Errorf("failed to create trash info: %w", err)
+		}
+		name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+	}
+}
+
+// infoPath returns the .trashinfo path for a trashed file name
+func (t *Trash) infoPath(name string) string {
+	return filepath.Join(t.root, "info", name+".trashinfo")
+}
+
+// escapePath percent-encodes a path as required by the Trash specification
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// moveFile renames a file, falling back to copy and delete across filesystems
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	return os.Remove(src)
+}
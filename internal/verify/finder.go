@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"os"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+)
+
+// Finder locates the files of images that were moved or renamed among a
+// set of candidate files. Candidates are compared by size before they are
+// hashed, and each is hashed at most once.
+type Finder struct {
+	bySize    map[int64][]string
+	checksums map[string]string
+	claimed   map[string]bool
+}
+
+// NewFinder creates a finder searching paths, typically the untracked
+// files under the library directories
+func NewFinder(paths []string) *Finder {
+	f := &Finder{
+		bySize:    make(map[int64][]string),
+		checksums: make(map[string]string),
+		claimed:   make(map[string]bool),
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		f.bySize[info.Size()] = append(f.bySize[info.Size()], path)
+	}
+	return f
+}
+
+// Find returns a candidate with the contents of img, or "" if there is
+// none. A file is only returned once, so two rows are never relinked to
+// the same file.
+func (f *Finder) Find(img *database.Image) string {
+	var candidates []string
+	if img.FileSize > 0 {
+		candidates = f.bySize[img.FileSize]
+	} else {
+		// The size was never recorded, so every candidate has to be hashed
+		for _, paths := range f.bySize {
+			candidates = append(candidates, paths...)
+		}
+	}
+
+	for _, path := range candidates {
+		if f.claimed[path] {
+			continue
+		}
+		checksum, ok := f.checksums[path]
+		if !ok {
+			checksum, _ = FileChecksum(path)
+			f.checksums[path] = checksum
+		}
+		if checksum != "" && checksum == img.Checksum {
+			f.claimed[path] = true
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+// Package verify checks that the files in the library still match what the
+// database recorded when they were added.
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/database"
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+)
+
+// Kinds of problems, from most to least severe
+const (
+	Missing          = "missing"
+	Undecodable      = "undecodable"
+	SizeMismatch     = "size mismatch"
+	ChecksumMismatch = "checksum mismatch"
+)
+
+// Issue is a problem found with an image's file
+type Issue struct {
+	Kind   string
+	Image  database.Image
+	Detail string
+	// Size and Checksum are those of the file on disk, when it was read
+	Size     int64
+	Checksum string
+}
+
+// Check verifies the files of images several at a time: that they exist,
+// have the recorded size and checksum and decode as images. progress, if
+// not nil, is called after each image from the calling goroutine. Issues are
+// returned in the order of images.
+func Check(images []database.Image, progress func(done int)) []Issue {
+	found := make([]*Issue, len(images))
+	jobs := make(chan int)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				found[i] = checkImage(&images[i])
+				done <- struct{}{}
+			}
+		}()
+	}
+
+	go func() {
+		for i := range images {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	checked := 0
+	for range done {
+		checked++
+		if progress != nil {
+			progress(checked)
+		}
+	}
+
+	var issues []Issue
+	for _, issue := range found {
+		if issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+	return issues
+}
+
+// checkImage verifies one image's file, returning nil if it is intact
+func checkImage(img *database.Image) *Issue {
+	info, err := os.Stat(img.LocalPath)
+	if err != nil {
+		detail := "file not found"
+		if !os.IsNotExist(err) {
+			detail = err.Error()
+		}
+		return &Issue{Kind: Missing, Image: *img, Detail: detail}
+	}
+
+	checksum, err := FileChecksum(img.LocalPath)
+	if err != nil {
+		return &Issue{Kind: Missing, Image: *img, Detail: err.Error()}
+	}
+	issue := &Issue{Image: *img, Size: info.Size(), Checksum: checksum}
+
+	if _, _, err := imaging.Open(img.LocalPath); err != nil {
+		issue.Kind = Undecodable
+		issue.Detail = err.Error()
+		return issue
+	}
+
+	switch {
+	case img.FileSize > 0 && info.Size() != img.FileSize:
+		issue.Kind = SizeMismatch
+		issue.Detail = fmt.Sprintf("%d bytes, expected %d", info.Size(), img.FileSize)
+	case checksum != img.Checksum:
+		issue.Kind = ChecksumMismatch
+		issue.Detail = "contents changed since the file was added"
+	default:
+		return nil
+	}
+	return issue
+}
+
+// Untracked returns the image files under dirs that are not in tracked,
+// a set of absolute paths. Directories that do not exist are skipped.
+func Untracked(dirs []string, tracked map[string]bool) ([]string, error) {
+	var untracked []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !imaging.IsImageFile(path) {
+				return nil
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			if !tracked[abs] && !seen[abs] {
+				seen[abs] = true
+				untracked = append(untracked, abs)
+			}
+			return nil
+		})
+		if err != nil {
+			return untracked, err
+		}
+	}
+	return untracked, nil
+}
+
+// FileChecksum returns the SHA-256 checksum of a file as hex, the form
+// stored in images.checksum
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// StateOf returns the state of a file for the import cache, without its
+// checksum
+func StateOf(path string, info os.FileInfo) database.FileState {
+	return database.FileState{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Inode:   inode(info),
+	}
+}
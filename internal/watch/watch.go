@@ -0,0 +1,234 @@
+// Package watch reports image files dropped into directory trees once they
+// finish being written, and paths that disappear from them, using inotify
+// through fsnotify.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AccursedGalaxy/wallfetch/internal/imaging"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Op is what happened to a watched path
+type Op int
+
+const (
+	// Written is an image file that was created or changed and then stayed
+	// unchanged for the quiet period
+	Written Op = iota
+	// Gone is a file or directory that was removed or renamed away. It is
+	// reported after twice the quiet period, so a file renamed within the
+	// watched directories is reported Written under its new name first.
+	// The directories given to New are not reported.
+	Gone
+)
+
+// Event is a change to a watched path
+type Event struct {
+	Op   Op
+	Path string
+}
+
+// Watcher watches directory trees, including directories created in them
+// while it runs
+type Watcher struct {
+	// Quiet is how long a file must stay unchanged before it is reported,
+	// so partially written files are not picked up
+	Quiet time.Duration
+
+	// Handle is called with each event, one at a time
+	Handle func(Event)
+
+	// Logf reports problems such as directories that cannot be watched
+	Logf func(format string, args ...interface{})
+
+	fs   *fsnotify.Watcher
+	dirs map[string]bool
+	// roots are the directories given to New
+	roots map[string]bool
+	// timers holds the pending event of each path; a newer event for the
+	// same path replaces it
+	timers map[string]*time.Timer
+	due    chan pending
+	done   chan struct{}
+}
+
+// pending is an event whose timer fired
+type pending struct {
+	event Event
+	timer *time.Timer
+}
+
+// New creates a watcher for the directory trees under dirs
+func New(dirs []string, quiet time.Duration) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start watching: %w", err)
+	}
+
+	w := &Watcher{
+		Quiet:  quiet,
+		Logf:   func(string, ...interface{}) {},
+		fs:     fs,
+		dirs:   make(map[string]bool),
+		roots:  make(map[string]bool),
+		timers: make(map[string]*time.Timer),
+		due:    make(chan pending),
+		done:   make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		w.roots[filepath.Clean(dir)] = true
+		if err := w.addTree(dir, nil); err != nil {
+			fs.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Dirs returns the number of directories being watched
+func (w *Watcher) Dirs() int {
+	return len(w.dirs)
+}
+
+// Run reports events until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	defer func() {
+		close(w.done)
+		for _, t := range w.timers {
+			t.Stop()
+		}
+		w.fs.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			w.handleFS(ev)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil
+			}
+			// An overflowing event queue loses events; cleanup and verify
+			// catch up with anything missed
+			w.Logf("⚠️  Watch error: %v", err)
+		case p := <-w.due:
+			if w.timers[p.event.Path] != p.timer {
+				continue // replaced by a newer event
+			}
+			delete(w.timers, p.event.Path)
+			w.Handle(p.event)
+		}
+	}
+}
+
+// handleFS turns an inotify event into a pending event
+func (w *Watcher) handleFS(ev fsnotify.Event) {
+	path := filepath.Clean(ev.Name)
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return // hidden and temporary files
+	}
+
+	switch {
+	case ev.Has(fsnotify.Create):
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			// A directory created or moved in; files already in it raise
+			// no events of their own
+			var files []string
+			if err := w.addTree(path, &files); err != nil {
+				w.Logf("⚠️  %v", err)
+			}
+			for _, file := range files {
+				w.schedule(Event{Op: Written, Path: file}, w.Quiet)
+			}
+			return
+		}
+		fallthrough
+	case ev.Has(fsnotify.Write):
+		if imaging.IsImageFile(path) {
+			w.schedule(Event{Op: Written, Path: path}, w.Quiet)
+		}
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		if w.dirs[path] {
+			w.removeTree(path)
+			if w.roots[path] {
+				// A watched directory itself going away, e.g. an unmounted
+				// drive, says nothing about the files in it
+				w.Logf("⚠️  Stopped watching %s: the directory is gone", path)
+				return
+			}
+		} else if !imaging.IsImageFile(path) {
+			return
+		}
+		w.schedule(Event{Op: Gone, Path: path}, 2*w.Quiet)
+	}
+}
+
+// schedule reports an event after delay unless another event for the same
+// path comes first
+func (w *Watcher) schedule(event Event, delay time.Duration) {
+	if t, ok := w.timers[event.Path]; ok {
+		t.Stop()
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		select {
+		case w.due <- pending{event: event, timer: t}:
+		case <-w.done:
+		}
+	})
+	w.timers[event.Path] = t
+}
+
+// addTree watches dir and the directories under it. If files is not nil,
+// the image files found are appended to it.
+func (w *Watcher) addTree(dir string, files *[]string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path != dir && os.IsNotExist(err) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.IsDir() {
+			if files != nil && imaging.IsImageFile(path) {
+				*files = append(*files, path)
+			}
+			return nil
+		}
+		if err := w.fs.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		w.dirs[path] = true
+		return nil
+	})
+}
+
+// removeTree stops watching dir and the directories under it
+func (w *Watcher) removeTree(dir string) {
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			_ = w.fs.Remove(path)
+			delete(w.dirs, path)
+		}
+	}
+}
@@ -17,6 +17,7 @@ DEFAULT_LIMIT=5
 DEFAULT_RESOLUTION="3440x1440"
 DEFAULT_SORT="toplist"
 DEFAULT_PURITY="sfw"
+DEFAULT_PRUNE_KEEP=500
 
 # Colors for output
 RED='\033[0;31m'
@@ -63,6 +64,7 @@ load_config() {
         SORT="$DEFAULT_SORT"
         PURITY="$DEFAULT_PURITY"
     fi
+    PRUNE_KEEP="${PRUNE_KEEP-$DEFAULT_PRUNE_KEEP}"
 }
 
 # Create default configuration file
@@ -84,6 +86,12 @@ SORT="$DEFAULT_SORT"
 
 # Content purity (sfw, sketchy, nsfw)
 PURITY="$DEFAULT_PURITY"
+
+# Wallpapers to keep after fetching; leave empty to only apply the
+# retention policy from wallfetch's config.yaml. Size limits are set with
+# library.max_size and library.source_quotas there and enforced while
+# fetching.
+PRUNE_KEEP=$DEFAULT_PRUNE_KEEP
 EOF
     log "INFO" "Configuration file created. Edit $CONFIG_FILE to customize settings."
 }
@@ -207,13 +215,18 @@ fetch_all_categories() {
 # Cleanup old wallpapers (optional)
 cleanup_old_wallpapers() {
     log "INFO" "Cleaning up old wallpapers..."
-    
-    # Keep only the 500 most recent wallpapers
-    if wallfetch prune --keep 500 --dry-run 2>/dev/null | grep -q "would be deleted"; then
-        log "INFO" "Removing old wallpapers..."
-        wallfetch prune --keep 500
+
+    # prune applies the retention policy from config.yaml (favorites are
+    # never removed); PRUNE_KEEP overrides how many wallpapers it keeps
+    local args=(--yes)
+    if [[ -n "$PRUNE_KEEP" ]]; then
+        args+=(--keep "$PRUNE_KEEP")
+    fi
+
+    if wallfetch prune "${args[@]}" >> "$LOG_FILE" 2>&1; then
+        log "INFO" "Cleanup finished"
     else
-        log "INFO" "No cleanup needed"
+        log "WARN" "Cleanup failed, see $LOG_FILE"
     fi
 }
 
@@ -238,6 +251,8 @@ Configuration:
     - RESOLUTION: Minimum resolution required
     - SORT: Sort method (toplist, date_added, etc.)
     - PURITY: Content purity (sfw, sketchy, nsfw)
+    - PRUNE_KEEP: Wallpapers to keep after fetching (empty for the
+      retention policy in wallfetch's config.yaml)
 
 Examples:
     $0 --config          # Create default config